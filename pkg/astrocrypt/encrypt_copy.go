@@ -0,0 +1,117 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrNotStruct is returned by DecryptStructCopy for values that are not a
+// struct or a pointer to one.
+var ErrNotStruct = errors.New("expected a struct or struct pointer")
+
+// DecryptStructCopy is DecryptStruct on a deep copy of v, which is returned
+// in the same shape as v (a *T for a *T, a T for a T) and left untouched,
+// so the encrypted form can be kept next to the decrypted one:
+//
+//	plain, err := encryptor.DecryptStructCopy(&user)
+//	u := plain.(*User)
+//
+// Pointers, slices, arrays and maps reachable through exported fields are
+// copied; unexported fields, interfaces, channels and functions are shared
+// with v.
+func (s *Service) DecryptStructCopy(v interface{}) (interface{}, error) {
+	return s.DecryptStructCopyCtx(context.Background(), v)
+}
+
+// DecryptStructCopyCtx is DecryptStructCopy that stops between fields once
+// ctx is done and returns ctx.Err().
+func (s *Service) DecryptStructCopyCtx(ctx context.Context, v interface{}) (interface{}, error) {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() || (val.Kind() == reflect.Ptr && val.IsNil()) {
+		return nil, ErrNotStruct
+	}
+	if val.Kind() != reflect.Struct && (val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct) {
+		return nil, ErrNotStruct
+	}
+
+	c := &copier{seen: make(map[uintptr]reflect.Value)}
+	dup := c.copy(val)
+	target := dup
+	if target.Kind() == reflect.Struct {
+		// A struct value isn't settable: decrypt through a pointer to it.
+		ptr := reflect.New(target.Type())
+		ptr.Elem().Set(target)
+		target = ptr
+	}
+	if err := walkStructWith(s.decryptWalker(ctx), target.Interface()); err != nil {
+		return nil, err
+	}
+	if dup.Kind() == reflect.Struct {
+		return target.Elem().Interface(), nil
+	}
+	return target.Interface(), nil
+}
+
+// copier deep-copies values, copying each pointer once so shared and
+// cyclic pointers keep their shape.
+type copier struct {
+	seen map[uintptr]reflect.Value
+}
+
+func (c *copier) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if dup, ok := c.seen[v.Pointer()]; ok && dup.Type() == v.Type() {
+			return dup
+		}
+		dup := reflect.New(v.Type().Elem())
+		c.seen[v.Pointer()] = dup
+		dup.Elem().Set(c.copy(v.Elem()))
+		return dup
+
+	case reflect.Struct:
+		dup := reflect.New(v.Type()).Elem()
+		dup.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if dup.Field(i).CanSet() {
+				dup.Field(i).Set(c.copy(v.Field(i)))
+			}
+		}
+		return dup
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		dup := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			dup.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return dup
+
+	case reflect.Array:
+		dup := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			dup.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return dup
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		dup := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			dup.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return dup
+	}
+	return v
+}
@@ -0,0 +1,197 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
+// Deterministic mode encrypts equal plaintexts to equal ciphertexts, so an
+// encrypted column can still be matched with WHERE email = ?. The price is
+// that anyone who can read the column learns which rows hold the same value:
+// use it only for lookup columns, and keep Encrypt for everything else.
+//
+// It is AES-SIV (RFC 5297) with a 256-bit MAC key and a 256-bit CTR key,
+// both derived from the Service key with HMAC-SHA256, so a deterministic
+// ciphertext never shares a key with the randomized GCM ones.
+
+// DeterministicPrefix starts every EncryptDeterministic output. It is not
+// part of the base64 alphabet, so Decrypt can tell both modes apart.
+const DeterministicPrefix = "det1:"
+
+// Labels for the deterministic sub-keys.
+const (
+	sivMACLabel = "astrocrypt deterministic v1 mac"
+	sivCTRLabel = "astrocrypt deterministic v1 ctr"
+)
+
+// sivCipher holds the two AES-SIV keys.
+type sivCipher struct {
+	mac cipher.Block // S2V / CMAC key
+	ctr cipher.Block // CTR key
+}
+
+func newSIVCipher(key []byte) (*sivCipher, error) {
+	mac, err := aes.NewCipher(deriveKey(key, sivMACLabel))
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(deriveKey(key, sivCTRLabel))
+	if err != nil {
+		return nil, err
+	}
+	return &sivCipher{mac: mac, ctr: ctr}, nil
+}
+
+// deriveKey returns HMAC-SHA256(key, label), a 32-byte sub-key.
+func deriveKey(key []byte, label string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(label))
+	return h.Sum(nil)
+}
+
+// EncryptDeterministic encrypts plaintext so that the same plaintext under
+// the same key always gives the same result, prefixed with
+// DeterministicPrefix. It leaks equality between values: only use it for
+// fields that must be searched by exact match. Decrypt reads the output.
+func (s *Service) EncryptDeterministic(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if err := checkSize("encrypt", s.MaxPlaintextBytes, len(plaintext)); err != nil {
+		return "", err
+	}
+
+	sealed := s.siv.seal([]byte(plaintext))
+	if s.RawEncoding {
+		return DeterministicPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+	}
+	return DeterministicPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptDeterministic opens a value made by EncryptDeterministic, prefix
+// already removed.
+func (s *Service) decryptDeterministic(encoded string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || len(data) < aes.BlockSize {
+		return "", ErrInvalidData
+	}
+	plaintext, ok := s.siv.open(data)
+	if !ok {
+		return "", ErrDecryptionFailed
+	}
+	return string(plaintext), nil
+}
+
+// =============================
+// AES-SIV (RFC 5297)
+// =============================
+
+// seal returns V || C: the synthetic IV followed by the CTR ciphertext,
+// authenticating the associated data ad as well.
+func (c *sivCipher) seal(plaintext []byte, ad ...[]byte) []byte {
+	v := c.s2v(plaintext, ad...)
+	out := make([]byte, aes.BlockSize+len(plaintext))
+	copy(out, v)
+	cipher.NewCTR(c.ctr, sivCounter(v)).XORKeyStream(out[aes.BlockSize:], plaintext)
+	return out
+}
+
+// open reverses seal and reports whether the synthetic IV matched.
+func (c *sivCipher) open(sealed []byte, ad ...[]byte) ([]byte, bool) {
+	if len(sealed) < aes.BlockSize {
+		return nil, false
+	}
+	v, ct := sealed[:aes.BlockSize], sealed[aes.BlockSize:]
+	plaintext := make([]byte, len(ct))
+	cipher.NewCTR(c.ctr, sivCounter(v)).XORKeyStream(plaintext, ct)
+	if subtle.ConstantTimeCompare(c.s2v(plaintext, ad...), v) != 1 {
+		return nil, false
+	}
+	return plaintext, true
+}
+
+// sivCounter clears the two bits RFC 5297 masks out of V before CTR.
+func sivCounter(v []byte) []byte {
+	q := append([]byte(nil), v...)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	return q
+}
+
+// s2v is the S2V construction over the associated data ad and plaintext.
+func (c *sivCipher) s2v(plaintext []byte, ad ...[]byte) []byte {
+	d := cmac(c.mac, make([]byte, aes.BlockSize))
+	for _, a := range ad {
+		d = dbl(d)
+		xorBytes(d, cmac(c.mac, a))
+	}
+
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte(nil), plaintext...)
+		xorBytes(t[len(t)-aes.BlockSize:], d)
+	} else {
+		t = dbl(d)
+		pad := make([]byte, aes.BlockSize)
+		copy(pad, plaintext)
+		pad[len(plaintext)] = 0x80
+		xorBytes(t, pad)
+	}
+	return cmac(c.mac, t)
+}
+
+// cmac is AES-CMAC (RFC 4493).
+func cmac(b cipher.Block, msg []byte) []byte {
+	l := make([]byte, aes.BlockSize)
+	b.Encrypt(l, l)
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	last := make([]byte, aes.BlockSize)
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		copy(last, msg[(n-1)*aes.BlockSize:])
+		xorBytes(last, k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		rest := msg[(n-1)*aes.BlockSize:]
+		copy(last, rest)
+		last[len(rest)] = 0x80
+		xorBytes(last, k2)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		xorBytes(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		b.Encrypt(x, x)
+	}
+	xorBytes(x, last)
+	b.Encrypt(x, x)
+	return x
+}
+
+// dbl multiplies a block by x in GF(2^128).
+func dbl(in []byte) []byte {
+	out := make([]byte, len(in))
+	carry := in[0] >> 7
+	for i := 0; i < len(in)-1; i++ {
+		out[i] = in[i]<<1 | in[i+1]>>7
+	}
+	out[len(in)-1] = in[len(in)-1]<<1 ^ carry*0x87
+	return out
+}
+
+// xorBytes sets dst[i] ^= src[i] over len(dst).
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,213 @@
+package astrocrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// rfcSIV builds a sivCipher from a raw RFC 5297 key, K1 (S2V) then K2 (CTR),
+// without the sub-key derivation Services apply.
+func rfcSIV(t *testing.T, key []byte) *sivCipher {
+	t.Helper()
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sivCipher{mac: mac, ctr: ctr}
+}
+
+// RFC 4493 §4 examples.
+func TestCMACVectors(t *testing.T) {
+	block, err := aes.NewCipher(mustHex(t, "2b7e1516 28aed2a6 abf71588 09cf4f3c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct{ msg, mac string }{
+		{"", "bb1d6929 e9593728 7fa37d12 9b756746"},
+		{"6bc1bee2 2e409f96 e93d7e11 7393172a", "070a16b4 6b4d4144 f79bdd9d d04a287c"},
+		{"6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51 30c81c46 a35ce411",
+			"dfa66747 de9ae630 30ca3261 1497c827"},
+	} {
+		if got := cmac(block, mustHex(t, v.msg)); !bytes.Equal(got, mustHex(t, v.mac)) {
+			t.Errorf("CMAC(%q) = %x, want %s", v.msg, got, v.mac)
+		}
+	}
+}
+
+// RFC 5297 Appendix A.1 (deterministic) and A.2 (nonce-based, the nonce
+// being the last associated data component).
+func TestSIVVectors(t *testing.T) {
+	for _, v := range []struct {
+		name, key string
+		ad        []string
+		plaintext string
+		v, c      string
+	}{
+		{
+			name: "A.1",
+			key: "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 " +
+				"f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff",
+			ad:        []string{"10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627"},
+			plaintext: "11223344 55667788 99aabbcc ddee",
+			v:         "85632d07 c6e8f37f 950acd32 0a2ecc93",
+			c:         "40c02b96 90c4dc04 daef7f6a fe5c",
+		},
+		{
+			name: "A.2",
+			key: "7f7e7d7c 7b7a7978 77767574 73727170 " +
+				"40414243 44454647 48494a4b 4c4d4e4f",
+			ad: []string{
+				"00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+				"10203040 50607080 90a0",
+				"09f91102 9d74e35b d84156c5 635688c0",
+			},
+			plaintext: "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 " +
+				"74207573 696e6720 5349562d 414553",
+			v: "7bdb6e3b 432667eb 06f4d14b ff2fbd0f",
+			c: "cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 " +
+				"ea64ad54 4a272e9c 485b62a3 fd5c0d",
+		},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			c := rfcSIV(t, mustHex(t, v.key))
+			var ad [][]byte
+			for _, a := range v.ad {
+				ad = append(ad, mustHex(t, a))
+			}
+			plaintext := mustHex(t, v.plaintext)
+			want := append(mustHex(t, v.v), mustHex(t, v.c)...)
+
+			if got := c.s2v(plaintext, ad...); !bytes.Equal(got, mustHex(t, v.v)) {
+				t.Errorf("S2V = %x, want %s", got, v.v)
+			}
+			sealed := c.seal(plaintext, ad...)
+			if !bytes.Equal(sealed, want) {
+				t.Fatalf("seal = %x, want %x", sealed, want)
+			}
+			opened, ok := c.open(sealed, ad...)
+			if !ok || !bytes.Equal(opened, plaintext) {
+				t.Errorf("open = %x, %v", opened, ok)
+			}
+			if _, ok := c.open(sealed, ad[1:]...); ok {
+				t.Error("open accepted different associated data")
+			}
+		})
+	}
+}
+
+func TestSIVOpenRejectsTampering(t *testing.T) {
+	c := rfcSIV(t, mustHex(t, "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff"))
+	sealed := c.seal([]byte("4111111111111111"))
+	for i := range sealed {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 0x01
+		if _, ok := c.open(tampered); ok {
+			t.Errorf("open accepted a flipped bit in byte %d", i)
+		}
+	}
+	if _, ok := c.open(sealed[:aes.BlockSize-1]); ok {
+		t.Error("open accepted a value shorter than the synthetic IV")
+	}
+}
+
+func TestEncryptDeterministicIsStable(t *testing.T) {
+	s := newTestService(t)
+	a, err := s.EncryptDeterministic("alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.EncryptDeterministic("alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("equal plaintexts gave %q and %q", a, b)
+	}
+	if c, _ := s.EncryptDeterministic("bob@example.com"); c == a {
+		t.Error("different plaintexts gave the same ciphertext")
+	}
+
+	other, err := NewService([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := other.EncryptDeterministic("alice@example.com"); c == a {
+		t.Error("different keys gave the same ciphertext")
+	}
+}
+
+func TestDecryptRejectsTamperedDeterministic(t *testing.T) {
+	s := newTestService(t)
+	text, err := s.EncryptDeterministic("alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.TrimPrefix(text, DeterministicPrefix), "="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, aes.BlockSize - 1, aes.BlockSize, len(raw) - 1} {
+		tampered := bytes.Clone(raw)
+		tampered[i] ^= 0x80
+		enc := DeterministicPrefix + base64.StdEncoding.EncodeToString(tampered)
+		if _, err := s.Decrypt(enc); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("byte %d flipped: err = %v, want ErrDecryptionFailed", i, err)
+		}
+	}
+}
+
+func TestDecryptRoutesOnDeterministicPrefix(t *testing.T) {
+	s := newTestService(t)
+	det, err := s.EncryptDeterministic("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := s.Encrypt("value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(det, DeterministicPrefix) || strings.HasPrefix(gcm, DeterministicPrefix) {
+		t.Fatalf("prefixes: deterministic %q, randomized %q", det, gcm)
+	}
+	for _, text := range []string{det, gcm} {
+		if got, err := s.Decrypt(text); err != nil || got != "value" {
+			t.Errorf("Decrypt(%q) = %q, %v", text, got, err)
+		}
+	}
+
+	// Each format only opens through its own path.
+	if _, err := s.Decrypt(DeterministicPrefix + gcm); err == nil {
+		t.Error("a randomized value decrypted as deterministic")
+	}
+	if _, err := s.Decrypt(strings.TrimPrefix(det, DeterministicPrefix)); err == nil {
+		t.Error("a deterministic value decrypted without its prefix")
+	}
+	if _, err := s.DecryptWithContext(det, []byte("tenant-1")); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("deterministic value with an aad: err = %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestDeterministicSubKeys(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	mac, ctr := deriveKey(key, sivMACLabel), deriveKey(key, sivCTRLabel)
+	if bytes.Equal(mac, key) || bytes.Equal(ctr, key) {
+		t.Error("a deterministic sub-key equals the GCM key")
+	}
+	if bytes.Equal(mac, ctr) {
+		t.Error("the S2V and CTR sub-keys are equal")
+	}
+	if !bytes.Equal(mac, deriveKey(key, sivMACLabel)) {
+		t.Error("deriveKey is not stable")
+	}
+	if bytes.Equal(mac, deriveKey([]byte("fedcba9876543210fedcba9876543210"), sivMACLabel)) {
+		t.Error("different keys derived the same sub-key")
+	}
+}
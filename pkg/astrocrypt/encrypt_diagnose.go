@@ -0,0 +1,80 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DiagnosisCategory is the likely cause DiagnoseFailures settles on.
+type DiagnosisCategory string
+
+const (
+	DiagnosisHealthy    DiagnosisCategory = "healthy"    // every non-empty sample decrypts
+	DiagnosisNoData     DiagnosisCategory = "no-data"    // no non-empty samples
+	DiagnosisWrongKey   DiagnosisCategory = "wrong-key"  // nothing decrypts and the values are well-formed
+	DiagnosisCorruption DiagnosisCategory = "corruption" // some values decrypt, others are damaged or foreign
+)
+
+// Diagnosis is returned by DiagnoseFailures. The index lists refer to the
+// samples slice.
+type Diagnosis struct {
+	Category DiagnosisCategory
+	Summary  string // one line saying what to check next
+
+	Samples  int
+	Empty    int
+	OK       int
+	TooLarge []int // over MaxCiphertextBytes, not attempted
+	// AuthFailed holds well-formed values whose tag does not verify: written
+	// with another key, or altered in a way that kept the encoding valid.
+	AuthFailed []int
+	// Malformed holds values that are not base64 or too short to be a
+	// ciphertext, e.g. truncated columns.
+	Malformed []int
+}
+
+// DiagnoseFailures decrypts sample ciphertexts, e.g. a few hundred rows of
+// a column after DecryptStruct starts failing, and guesses why they fail:
+// when no sample decrypts but they are all well-formed, s is most likely not
+// the key they were written with; when most decrypt and a few do not, the
+// failing rows are damaged. Plaintexts are discarded.
+func (s *Service) DiagnoseFailures(samples []string) Diagnosis {
+	d := Diagnosis{Samples: len(samples)}
+	for i, c := range samples {
+		if c == "" {
+			d.Empty++
+			continue
+		}
+		_, err := s.Decrypt(c)
+		switch {
+		case err == nil:
+			d.OK++
+		case errors.Is(err, ErrTooLarge):
+			d.TooLarge = append(d.TooLarge, i)
+		case errors.Is(err, ErrDecryptionFailed):
+			d.AuthFailed = append(d.AuthFailed, i)
+		default:
+			d.Malformed = append(d.Malformed, i)
+		}
+	}
+
+	failed := len(d.AuthFailed) + len(d.Malformed)
+	switch {
+	case d.OK == 0 && failed == 0:
+		d.Category = DiagnosisNoData
+		d.Summary = "no non-empty samples could be checked"
+	case failed == 0:
+		d.Category = DiagnosisHealthy
+		d.Summary = fmt.Sprintf("all %d non-empty samples decrypt", d.OK)
+	case d.OK == 0 && len(d.AuthFailed) > 0:
+		d.Category = DiagnosisWrongKey
+		d.Summary = fmt.Sprintf("none of %d samples decrypt and %d are well-formed: check that the key matches the one the data was written with",
+			failed, len(d.AuthFailed))
+	default:
+		d.Category = DiagnosisCorruption
+		d.Summary = fmt.Sprintf("%d of %d samples fail (%d malformed, %d not authentic) while %d decrypt: inspect the failing rows or restore them",
+			failed, d.OK+failed, len(d.Malformed), len(d.AuthFailed), d.OK)
+	}
+	return d
+}
@@ -0,0 +1,99 @@
+package astrocrypt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sealAll encrypts every value with s.
+func sealAll(t *testing.T, s *Service, values ...string) []string {
+	t.Helper()
+	out := make([]string, len(values))
+	for i, v := range values {
+		c, err := s.Encrypt(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func TestDiagnoseWrongKey(t *testing.T) {
+	writer, err := NewService([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := append(sealAll(t, writer, "a", "b", "c"), "")
+
+	d := newTestService(t).DiagnoseFailures(samples)
+	if d.Category != DiagnosisWrongKey {
+		t.Fatalf("category = %s, want %s: %s", d.Category, DiagnosisWrongKey, d.Summary)
+	}
+	if !reflect.DeepEqual(d.AuthFailed, []int{0, 1, 2}) || d.Malformed != nil || d.OK != 0 || d.Empty != 1 || d.Samples != 4 {
+		t.Errorf("diagnosis = %+v", d)
+	}
+	if !strings.Contains(d.Summary, "key") {
+		t.Errorf("summary %q does not point at the key", d.Summary)
+	}
+}
+
+func TestDiagnoseCorruption(t *testing.T) {
+	s := newTestService(t)
+	samples := sealAll(t, s, "a", "b", "c", "d")
+
+	// A flipped byte keeps the encoding valid but breaks the tag.
+	tampered := []byte(samples[1])
+	if tampered[20] == 'A' {
+		tampered[20] = 'B'
+	} else {
+		tampered[20] = 'A'
+	}
+	samples[1] = string(tampered)
+	samples[3] = "not base64!"
+
+	d := s.DiagnoseFailures(samples)
+	if d.Category != DiagnosisCorruption {
+		t.Fatalf("category = %s, want %s: %s", d.Category, DiagnosisCorruption, d.Summary)
+	}
+	if d.OK != 2 || !reflect.DeepEqual(d.AuthFailed, []int{1}) || !reflect.DeepEqual(d.Malformed, []int{3}) {
+		t.Errorf("diagnosis = %+v", d)
+	}
+}
+
+func TestDiagnoseTruncated(t *testing.T) {
+	s := newTestService(t)
+	samples := sealAll(t, s, "a", "b", "c")
+	samples[0] = samples[0][:len(samples[0])-3] // cut mid-quantum: invalid base64
+	samples[2] = samples[2][:8]                 // too short for nonce and tag
+
+	d := s.DiagnoseFailures(samples)
+	if d.Category != DiagnosisCorruption {
+		t.Fatalf("category = %s, want %s: %s", d.Category, DiagnosisCorruption, d.Summary)
+	}
+	if d.OK != 1 || !reflect.DeepEqual(d.Malformed, []int{0, 2}) || d.AuthFailed != nil {
+		t.Errorf("diagnosis = %+v", d)
+	}
+
+	// Truncated everywhere: nothing well-formed, so not blamed on the key.
+	d = s.DiagnoseFailures([]string{samples[0], samples[2]})
+	if d.Category != DiagnosisCorruption {
+		t.Errorf("all truncated: category = %s, want %s", d.Category, DiagnosisCorruption)
+	}
+}
+
+func TestDiagnoseHealthyAndEmpty(t *testing.T) {
+	s := newTestService(t)
+	if d := s.DiagnoseFailures(sealAll(t, s, "a", "b")); d.Category != DiagnosisHealthy || d.OK != 2 {
+		t.Errorf("healthy: %+v", d)
+	}
+	if d := s.DiagnoseFailures([]string{"", ""}); d.Category != DiagnosisNoData || d.Empty != 2 {
+		t.Errorf("empty: %+v", d)
+	}
+
+	s.MaxCiphertextBytes = 10
+	if d := s.DiagnoseFailures(sealAll(t, s, "a")); d.Category != DiagnosisNoData || !reflect.DeepEqual(d.TooLarge, []int{0}) {
+		t.Errorf("too large: %+v", d)
+	}
+}
@@ -0,0 +1,243 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// KMS wraps and unwraps data keys with a master key it never reveals, e.g.
+// a cloud KMS.
+type KMS interface {
+	Wrap(ctx context.Context, dataKey []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+const defaultDataKeyCacheSize = 1024
+
+// ProviderOptions configures a ProviderService.
+type ProviderOptions struct {
+	// RateLimit applies to the Wrap and Unwrap calls; cache hits never
+	// wait for it. Zero → no limit.
+	RateLimit RateLimitOptions
+	// CacheSize is how many unwrapped data keys are kept, the oldest being
+	// dropped first. 0 → 1024.
+	CacheSize int
+}
+
+// ProviderService does envelope encryption: values are encrypted with a
+// random AES-256 data key, stored next to them as wrapped by the KMS:
+//
+//	<wrapped data key, unpadded base64url>.<Service.Encrypt output>
+//
+// Unwrapped data keys are cached, so the KMS only sees the first value of
+// each key; a bulk job decrypting rows written under a few data keys makes
+// a few KMS calls. Concurrent misses for the same wrapped key share one
+// Unwrap call.
+type ProviderService struct {
+	kms     KMS
+	limiter *rateLimiter
+	size    int
+
+	keyMu   sync.Mutex // serializes the creation of current
+	current *dataKey   // encrypts new values; nil until the first one
+
+	mu       sync.Mutex
+	cache    map[string]*Service    // wrapped data key → its Service
+	order    []string               // cache keys, oldest first
+	inflight map[string]*unwrapCall // wrapped data key → its running Unwrap
+}
+
+// unwrapCall is an Unwrap shared by the callers missing the same key.
+type unwrapCall struct {
+	done chan struct{} // closed once svc and err are set
+	svc  *Service
+	err  error
+}
+
+type dataKey struct {
+	wrapped string // encoded
+	svc     *Service
+}
+
+// NewProviderService returns a ProviderService using kms:
+//
+//	svc := astrocrypt.NewProviderService(kms, astrocrypt.ProviderOptions{
+//	    RateLimit: astrocrypt.RateLimitOptions{RPS: 50, Burst: 10, MaxWait: time.Second},
+//	})
+func NewProviderService(kms KMS, opts ProviderOptions) *ProviderService {
+	size := opts.CacheSize
+	if size <= 0 {
+		size = defaultDataKeyCacheSize
+	}
+	return &ProviderService{
+		kms:      kms,
+		limiter:  newRateLimiter(opts.RateLimit),
+		size:     size,
+		cache:    make(map[string]*Service),
+		inflight: make(map[string]*unwrapCall),
+	}
+}
+
+// Encrypt encrypts plaintext under the current data key, creating and
+// wrapping one on first use.
+func (p *ProviderService) Encrypt(ctx context.Context, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	key, err := p.dataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := key.svc.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return key.wrapped + "." + ciphertext, nil
+}
+
+// Decrypt decrypts a value made by Encrypt, unwrapping its data key through
+// the KMS unless it is cached.
+func (p *ProviderService) Decrypt(ctx context.Context, ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	wrapped, value, ok := strings.Cut(ciphertext, ".")
+	if !ok || wrapped == "" {
+		return "", ErrInvalidData
+	}
+	svc, err := p.unwrap(ctx, wrapped)
+	if err != nil {
+		return "", err
+	}
+	return svc.Decrypt(value)
+}
+
+// RotateDataKey makes Encrypt use a new data key from now on. Values
+// encrypted under the previous ones still decrypt.
+func (p *ProviderService) RotateDataKey(ctx context.Context) error {
+	p.keyMu.Lock()
+	defer p.keyMu.Unlock()
+
+	key, err := p.newDataKey(ctx)
+	if err != nil {
+		return err
+	}
+	p.current = key
+	return nil
+}
+
+// dataKey returns the current data key, creating it when there is none.
+func (p *ProviderService) dataKey(ctx context.Context) (*dataKey, error) {
+	p.keyMu.Lock()
+	defer p.keyMu.Unlock()
+
+	if p.current == nil {
+		key, err := p.newDataKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.current = key
+	}
+	return p.current, nil
+}
+
+// newDataKey generates a data key, wraps it through the limiter and caches
+// it so values encrypted with it decrypt without a KMS call.
+func (p *ProviderService) newDataKey(ctx context.Context) (*dataKey, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, ErrEncryptionFailed
+	}
+	svc, err := NewService(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := p.limiter.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := p.kms.Wrap(ctx, raw)
+	release()
+	if err != nil {
+		return nil, fmt.Errorf("wrap data key: %w", err)
+	}
+
+	key := &dataKey{wrapped: base64.RawURLEncoding.EncodeToString(wrapped), svc: svc}
+	p.mu.Lock()
+	p.store(key.wrapped, svc)
+	p.mu.Unlock()
+	return key, nil
+}
+
+// unwrap returns the Service of an encoded wrapped data key. Cached keys
+// are returned without touching the limiter. On a miss the first caller
+// unwraps the key and the others wait for its result, errors included; a
+// waiter whose ctx ends first returns ctx.Err().
+func (p *ProviderService) unwrap(ctx context.Context, wrapped string) (*Service, error) {
+	p.mu.Lock()
+	if svc, ok := p.cache[wrapped]; ok {
+		p.mu.Unlock()
+		return svc, nil
+	}
+	if call, ok := p.inflight[wrapped]; ok {
+		p.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.svc, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &unwrapCall{done: make(chan struct{})}
+	p.inflight[wrapped] = call
+	p.mu.Unlock()
+
+	call.svc, call.err = p.unwrapKMS(ctx, wrapped)
+
+	p.mu.Lock()
+	delete(p.inflight, wrapped)
+	if call.err == nil {
+		p.store(wrapped, call.svc)
+	}
+	p.mu.Unlock()
+	close(call.done)
+	return call.svc, call.err
+}
+
+// unwrapKMS unwraps an encoded wrapped data key through the limiter.
+func (p *ProviderService) unwrapKMS(ctx context.Context, wrapped string) (*Service, error) {
+	blob, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+	release, err := p.limiter.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.kms.Unwrap(ctx, blob)
+	release()
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	return NewService(raw)
+}
+
+// store caches svc under wrapped, dropping the oldest key when full. The
+// caller holds p.mu.
+func (p *ProviderService) store(wrapped string, svc *Service) {
+	if _, ok := p.cache[wrapped]; ok {
+		return
+	}
+	if len(p.order) >= p.size {
+		delete(p.cache, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.cache[wrapped] = svc
+	p.order = append(p.order, wrapped)
+}
@@ -0,0 +1,294 @@
+package astrocrypt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKMS "wraps" data keys by XOR-ing them with a byte and records when
+// each call was made.
+type fakeKMS struct {
+	mu      sync.Mutex
+	wraps   int
+	unwraps int
+	calls   []time.Time
+	hold    chan struct{} // when set, Unwrap blocks until it is closed
+}
+
+func (k *fakeKMS) Wrap(_ context.Context, dataKey []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.wraps++
+	k.calls = append(k.calls, time.Now())
+	return xorKey(dataKey), nil
+}
+
+func (k *fakeKMS) Unwrap(_ context.Context, wrapped []byte) ([]byte, error) {
+	k.mu.Lock()
+	k.unwraps++
+	k.calls = append(k.calls, time.Now())
+	hold := k.hold
+	k.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+	return xorKey(wrapped), nil
+}
+
+func (k *fakeKMS) counts() (wraps, unwraps int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.wraps, k.unwraps
+}
+
+func xorKey(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[i] = c ^ 0x5a
+	}
+	return out
+}
+
+// encryptUnder encrypts n values, each under its own data key.
+func encryptUnder(t *testing.T, p *ProviderService, n int) []string {
+	t.Helper()
+	ctx := context.Background()
+	values := make([]string, n)
+	for i := range values {
+		if err := p.RotateDataKey(ctx); err != nil {
+			t.Fatal(err)
+		}
+		v, err := p.Encrypt(ctx, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		values[i] = v
+	}
+	return values
+}
+
+func TestProviderServiceRoundTrip(t *testing.T) {
+	kms := &fakeKMS{}
+	writer := NewProviderService(kms, ProviderOptions{})
+	ctx := context.Background()
+
+	a, err := writer.Encrypt(ctx, "alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := writer.Encrypt(ctx, "beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wraps, _ := kms.counts(); wraps != 1 {
+		t.Errorf("wraps = %d, want 1 for one data key", wraps)
+	}
+
+	// A fresh instance has to unwrap the data key, once.
+	reader := NewProviderService(kms, ProviderOptions{})
+	for want, text := range map[string]string{"alpha": a, "beta": b} {
+		got, err := reader.Decrypt(ctx, text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if _, unwraps := kms.counts(); unwraps != 1 {
+		t.Errorf("unwraps = %d, want 1", unwraps)
+	}
+
+	if _, err := reader.Decrypt(ctx, "no-separator"); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("err = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestProviderServiceCacheHitsBypassLimiter(t *testing.T) {
+	kms := &fakeKMS{}
+	values := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 1)
+
+	// One token, and none coming back within the test.
+	p := NewProviderService(kms, ProviderOptions{
+		RateLimit: RateLimitOptions{RPS: 0.001, MaxWait: time.Millisecond},
+	})
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		if _, err := p.Decrypt(ctx, values[0]); err != nil {
+			t.Fatalf("decrypt %d: %v", i, err)
+		}
+	}
+	if _, unwraps := kms.counts(); unwraps != 1 {
+		t.Errorf("unwraps = %d, want 1", unwraps)
+	}
+
+	// A second data key is a miss and the bucket is empty.
+	other := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 1)
+	if _, err := p.Decrypt(ctx, other[0]); !errors.Is(err, ErrKMSThrottled) {
+		t.Errorf("err = %v, want ErrKMSThrottled", err)
+	}
+}
+
+func TestProviderServiceCallRate(t *testing.T) {
+	const rps = 50
+	kms := &fakeKMS{}
+	values := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 6)
+	kms.calls = nil
+
+	p := NewProviderService(kms, ProviderOptions{RateLimit: RateLimitOptions{RPS: rps}})
+	for _, v := range values {
+		if _, err := p.Decrypt(context.Background(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first call spends the burst; each later one waits for a token.
+	minGap := time.Second / rps * 9 / 10
+	for i := 1; i < len(kms.calls); i++ {
+		if gap := kms.calls[i].Sub(kms.calls[i-1]); gap < minGap {
+			t.Errorf("call %d came %s after the previous one, want ≥ %s", i, gap, minGap)
+		}
+	}
+}
+
+func TestProviderServiceDeadline(t *testing.T) {
+	kms := &fakeKMS{}
+	values := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 2)
+
+	p := NewProviderService(kms, ProviderOptions{RateLimit: RateLimitOptions{RPS: 1}})
+	if _, err := p.Decrypt(context.Background(), values[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next token is a second away, past the deadline: fail now.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := p.Decrypt(ctx, values[1])
+	if !errors.Is(err, ErrKMSThrottled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want ErrKMSThrottled and DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("failed after %s, want right away", elapsed)
+	}
+	if _, unwraps := kms.counts(); unwraps != 1 {
+		t.Errorf("unwraps = %d, want 1: the throttled call must not reach the KMS", unwraps)
+	}
+}
+
+func TestProviderServiceMaxInFlight(t *testing.T) {
+	kms := &fakeKMS{}
+	values := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 2)
+
+	kms.hold = make(chan struct{})
+	p := NewProviderService(kms, ProviderOptions{
+		RateLimit: RateLimitOptions{MaxInFlight: 1, MaxWait: 20 * time.Millisecond},
+	})
+	done := make(chan error)
+	go func() {
+		_, err := p.Decrypt(context.Background(), values[0])
+		done <- err
+	}()
+	for {
+		if _, unwraps := kms.counts(); unwraps == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := p.Decrypt(context.Background(), values[1]); !errors.Is(err, ErrKMSThrottled) {
+		t.Errorf("err = %v, want ErrKMSThrottled while the slot is taken", err)
+	}
+	close(kms.hold)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Decrypt(context.Background(), values[1]); err != nil {
+		t.Errorf("after the slot freed: %v", err)
+	}
+}
+
+func TestProviderServiceCacheEviction(t *testing.T) {
+	kms := &fakeKMS{}
+	values := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 3)
+
+	p := NewProviderService(kms, ProviderOptions{CacheSize: 2})
+	ctx := context.Background()
+	for _, v := range values {
+		if _, err := p.Decrypt(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// values[0] was the oldest of three with room for two.
+	if _, err := p.Decrypt(ctx, values[2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Decrypt(ctx, values[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, unwraps := kms.counts(); unwraps != 4 {
+		t.Errorf("unwraps = %d, want 4", unwraps)
+	}
+}
+
+func TestProviderServiceConcurrentMissesShareUnwrap(t *testing.T) {
+	kms := &fakeKMS{}
+	values := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 1)
+
+	kms.hold = make(chan struct{})
+	p := NewProviderService(kms, ProviderOptions{})
+	const callers = 10
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			got, err := p.Decrypt(context.Background(), values[0])
+			if err == nil && got != "secret" {
+				err = errors.New("got " + got)
+			}
+			errs <- err
+		}()
+	}
+	for {
+		if _, unwraps := kms.counts(); unwraps >= 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Let the other callers reach the miss while the first Unwrap is held.
+	time.Sleep(20 * time.Millisecond)
+	close(kms.hold)
+
+	for i := 0; i < callers; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if _, unwraps := kms.counts(); unwraps != 1 {
+		t.Errorf("unwraps = %d, want 1 for %d concurrent misses", unwraps, callers)
+	}
+}
+
+func TestProviderServiceWaiterHonoursContext(t *testing.T) {
+	kms := &fakeKMS{}
+	values := encryptUnder(t, NewProviderService(kms, ProviderOptions{}), 1)
+
+	kms.hold = make(chan struct{})
+	defer close(kms.hold)
+	p := NewProviderService(kms, ProviderOptions{})
+	go p.Decrypt(context.Background(), values[0])
+	for {
+		if _, unwraps := kms.counts(); unwraps == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Decrypt(ctx, values[0]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded while waiting on the shared Unwrap", err)
+	}
+}
@@ -7,23 +7,95 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 )
 
 type Service struct {
-	gcm cipher.AEAD
+	gcm      cipher.AEAD
+	siv      *sivCipher // EncryptDeterministic, on keys derived from the main one
+	indexKey []byte     // BlindIndex; derived from the main key unless given
+	salt     []byte     // set by NewServiceFromPassphrase
+
+	// MaxPlaintextBytes caps the input of Encrypt/EncryptBytes.
+	// 0 → unlimited.
+	MaxPlaintextBytes int
+	// MaxCiphertextBytes caps the input of Decrypt/DecryptBytes (base64 text
+	// for Decrypt, raw bytes for DecryptBytes).
+	// 0 → unlimited.
+	MaxCiphertextBytes int
+
+	// RawEncoding makes Encrypt emit unpadded base64 (base64.RawStdEncoding).
+	// Decrypt accepts padded and unpadded input either way.
+	RawEncoding bool
+
+	// IndexLowercase makes BlindIndex case-insensitive by lowercasing
+	// values before hashing. Values are always trimmed.
+	IndexLowercase bool
+	// IndexBase64 makes BlindIndex return unpadded base64 instead of hex.
+	IndexBase64 bool
+
+	// Workers is the number of goroutines EncryptSlice/DecryptSlice use.
+	// 0 → 1 (sequential).
+	Workers int
 }
 
+// Default limits applied by NewService. Set the Service fields to 0 to lift them.
+const (
+	DefaultMaxPlaintextBytes  = 64 << 20  // 64 MB
+	DefaultMaxCiphertextBytes = 128 << 20 // room for base64 + nonce/tag overhead
+)
+
 var (
 	ErrMissingKey       = errors.New("encryption key is missing")
 	ErrInvalidKeyLength = errors.New("key must be 16, 24, or 32 bytes")
 	ErrEncryptionFailed = errors.New("encryption failed")
 	ErrDecryptionFailed = errors.New("decryption failed")
 	ErrInvalidData      = errors.New("invalid encrypted data")
+	ErrUnsupportedField = errors.New("encrypt tag on non-string field")
+	ErrInvalidEnvelope  = errors.New("decrypted value does not match field type")
+	ErrTooLarge         = errors.New("data exceeds size limit")
 )
 
+// SizeLimitError is returned when an input is over the Service limits.
+// It matches ErrTooLarge with errors.Is.
+type SizeLimitError struct {
+	Op    string // "encrypt", "decrypt" or "index" (BlindIndex)
+	Field string // struct field path, empty outside the struct walkers
+	Limit int
+	Size  int
+}
+
+func (e *SizeLimitError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s %s: %d bytes exceeds limit of %d", e.Op, e.Field, e.Size, e.Limit)
+	}
+	return fmt.Sprintf("%s: %d bytes exceeds limit of %d", e.Op, e.Size, e.Limit)
+}
+
+func (e *SizeLimitError) Is(target error) bool {
+	return target == ErrTooLarge
+}
+
+// checkSize returns a *SizeLimitError when size is over a non-zero limit.
+func checkSize(op string, limit, size int) error {
+	if limit > 0 && size > limit {
+		return &SizeLimitError{Op: op, Limit: limit, Size: size}
+	}
+	return nil
+}
+
 // NewService creates a new encryption service
 func NewService(key []byte) (*Service, error) {
+	switch len(key) {
+	case 0:
+		return nil, ErrMissingKey
+	case 16, 24, 32:
+	default:
+		return nil, ErrInvalidKeyLength
+	}
+
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -34,31 +106,75 @@ func NewService(key []byte) (*Service, error) {
 		return nil, err
 	}
 
-	return &Service{gcm: gcm}, nil
+	siv, err := newSIVCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		gcm:                gcm,
+		siv:                siv,
+		indexKey:           deriveKey(key, blindIndexLabel),
+		MaxPlaintextBytes:  DefaultMaxPlaintextBytes,
+		MaxCiphertextBytes: DefaultMaxCiphertextBytes,
+	}, nil
 }
 
 // Encrypt encrypts plaintext and returns base64 encoded string
 func (s *Service) Encrypt(plaintext string) (string, error) {
+	return s.EncryptWithContext(plaintext, nil)
+}
+
+// EncryptWithContext is Encrypt binding the ciphertext to aad, e.g. the ID of
+// the row it is stored in: DecryptWithContext then fails with
+// ErrDecryptionFailed unless given the same aad, so a value copied into
+// another row no longer decrypts. aad is authenticated but not stored; nil
+// gives the same result as Encrypt.
+func (s *Service) EncryptWithContext(plaintext string, aad []byte) (string, error) {
 	if plaintext == "" {
 		return "", nil
 	}
+	if err := checkSize("encrypt", s.MaxPlaintextBytes, len(plaintext)); err != nil {
+		return "", err
+	}
 
 	nonce := make([]byte, s.gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", ErrEncryptionFailed
 	}
 
-	ciphertext := s.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	ciphertext := s.gcm.Seal(nonce, nonce, []byte(plaintext), aad)
+	if s.RawEncoding {
+		return base64.RawStdEncoding.EncodeToString(ciphertext), nil
+	}
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt decrypts base64 encoded ciphertext
+// Decrypt decrypts base64 encoded ciphertext, and EncryptDeterministic
+// output (DeterministicPrefix) with the deterministic sub-keys.
 func (s *Service) Decrypt(ciphertext string) (string, error) {
+	return s.DecryptWithContext(ciphertext, nil)
+}
+
+// DecryptWithContext decrypts a value made by EncryptWithContext with the
+// same aad, and fails with ErrDecryptionFailed otherwise. Deterministic
+// values are never bound to an aad and fail with a non-empty one.
+func (s *Service) DecryptWithContext(ciphertext string, aad []byte) (string, error) {
 	if ciphertext == "" {
 		return "", nil
 	}
+	if err := checkSize("decrypt", s.MaxCiphertextBytes, len(ciphertext)); err != nil {
+		return "", err
+	}
+	if encoded, ok := strings.CutPrefix(ciphertext, DeterministicPrefix); ok {
+		if len(aad) > 0 {
+			return "", ErrDecryptionFailed
+		}
+		return s.decryptDeterministic(encoded)
+	}
 
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	// Padding is optional so values written in either mode stay readable.
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(ciphertext, "="))
 	if err != nil {
 		return "", ErrInvalidData
 	}
@@ -69,7 +185,7 @@ func (s *Service) Decrypt(ciphertext string) (string, error) {
 	}
 
 	nonce, encrypted := data[:nonceSize], data[nonceSize:]
-	plaintext, err := s.gcm.Open(nil, nonce, encrypted, nil)
+	plaintext, err := s.gcm.Open(nil, nonce, encrypted, aad)
 	if err != nil {
 		return "", ErrDecryptionFailed
 	}
@@ -82,6 +198,9 @@ func (s *Service) EncryptBytes(plaintext []byte) ([]byte, error) {
 	if len(plaintext) == 0 {
 		return nil, nil
 	}
+	if err := checkSize("encrypt", s.MaxPlaintextBytes, len(plaintext)); err != nil {
+		return nil, err
+	}
 
 	nonce := make([]byte, s.gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
@@ -96,6 +215,9 @@ func (s *Service) DecryptBytes(ciphertext []byte) ([]byte, error) {
 	if len(ciphertext) == 0 {
 		return nil, nil
 	}
+	if err := checkSize("decrypt", s.MaxCiphertextBytes, len(ciphertext)); err != nil {
+		return nil, err
+	}
 
 	nonceSize := s.gcm.NonceSize()
 	if len(ciphertext) < nonceSize {
@@ -0,0 +1,195 @@
+package astrocrypt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// wantSizeLimit fails unless err is a *SizeLimitError with the given values.
+func wantSizeLimit(t *testing.T, err error, op, field string, limit, size int) {
+	t.Helper()
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+	var sizeErr *SizeLimitError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("err = %T, want *SizeLimitError", err)
+	}
+	if sizeErr.Op != op || sizeErr.Field != field || sizeErr.Limit != limit || sizeErr.Size != size {
+		t.Errorf("err = %+v, want Op=%q Field=%q Limit=%d Size=%d", sizeErr, op, field, limit, size)
+	}
+}
+
+func TestEncryptSizeLimitBoundary(t *testing.T) {
+	s := newTestService(t)
+	s.MaxPlaintextBytes = 16
+
+	if _, err := s.Encrypt(strings.Repeat("a", 16)); err != nil {
+		t.Fatalf("Encrypt at the limit: %v", err)
+	}
+	_, err := s.Encrypt(strings.Repeat("a", 17))
+	wantSizeLimit(t, err, "encrypt", "", 16, 17)
+
+	if _, err := s.EncryptBytes(make([]byte, 16)); err != nil {
+		t.Fatalf("EncryptBytes at the limit: %v", err)
+	}
+	_, err = s.EncryptBytes(make([]byte, 17))
+	wantSizeLimit(t, err, "encrypt", "", 16, 17)
+}
+
+func TestDecryptSizeLimitBoundary(t *testing.T) {
+	s := newTestService(t)
+	text, err := s.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := s.EncryptBytes([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.MaxCiphertextBytes = len(text)
+	if _, err := s.Decrypt(text); err != nil {
+		t.Fatalf("Decrypt at the limit: %v", err)
+	}
+	s.MaxCiphertextBytes = len(text) - 1
+	_, err = s.Decrypt(text)
+	wantSizeLimit(t, err, "decrypt", "", len(text)-1, len(text))
+
+	s.MaxCiphertextBytes = len(raw)
+	if _, err := s.DecryptBytes(raw); err != nil {
+		t.Fatalf("DecryptBytes at the limit: %v", err)
+	}
+	s.MaxCiphertextBytes = len(raw) - 1
+	_, err = s.DecryptBytes(raw)
+	wantSizeLimit(t, err, "decrypt", "", len(raw)-1, len(raw))
+}
+
+func TestSizeLimitZeroIsUnlimited(t *testing.T) {
+	s := newTestService(t)
+	s.MaxPlaintextBytes = 0
+	s.MaxCiphertextBytes = 0
+	big := strings.Repeat("a", DefaultMaxPlaintextBytes+1)
+
+	text, err := s.Encrypt(big)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	got, err := s.Decrypt(text)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if got != big {
+		t.Error("round-trip mismatch")
+	}
+}
+
+func TestNewServiceDefaultLimits(t *testing.T) {
+	s := newTestService(t)
+	if s.MaxPlaintextBytes != DefaultMaxPlaintextBytes || s.MaxCiphertextBytes != DefaultMaxCiphertextBytes {
+		t.Errorf("limits = %d/%d, want the defaults", s.MaxPlaintextBytes, s.MaxCiphertextBytes)
+	}
+}
+
+func TestEncryptStructSizeLimitFieldPath(t *testing.T) {
+	s := newTestService(t)
+	s.MaxPlaintextBytes = 8
+	u := testUser{
+		Email: "a@b.c",
+		Profile: testProfile{
+			Address: testAddress{Card: &testCard{Number: "4111111111111111"}},
+		},
+	}
+
+	err := s.EncryptStruct(&u)
+	wantSizeLimit(t, err, "encrypt", "testUser.Profile.Address.Card.Number", 8, 16)
+	if u.Email != "a@b.c" {
+		t.Error("u was modified by a failed EncryptStruct")
+	}
+}
+
+func TestDecryptStructSizeLimitFieldPath(t *testing.T) {
+	s := newTestService(t)
+	u := testUser{Cards: []testCard{{Number: "1"}, {Number: "2"}}}
+	if err := s.EncryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	s.MaxCiphertextBytes = len(u.Cards[1].Number) - 1
+	err := s.DecryptStruct(&u)
+	wantSizeLimit(t, err, "decrypt", "testUser.Cards[0].Number", s.MaxCiphertextBytes, len(u.Cards[0].Number))
+}
+
+func TestRawEncodingRoundTrip(t *testing.T) {
+	padded := newTestService(t)
+	raw := newTestService(t)
+	raw.RawEncoding = true
+
+	// Lengths 1..3 cover every amount of base64 padding.
+	for _, plain := range []string{"a", "ab", "abc", "abcd", "héllo wörld"} {
+		p, err := padded.Encrypt(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := raw.Encrypt(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(r, "=") {
+			t.Errorf("raw ciphertext %q is padded", r)
+		}
+
+		// Either service reads either encoding, so switching modes is safe.
+		for _, c := range []struct {
+			name string
+			s    *Service
+			text string
+		}{
+			{"padded/padded", padded, p},
+			{"padded/raw", padded, r},
+			{"raw/padded", raw, p},
+			{"raw/raw", raw, r},
+		} {
+			got, err := c.s.Decrypt(c.text)
+			if err != nil {
+				t.Fatalf("%s %q: %v", c.name, plain, err)
+			}
+			if got != plain {
+				t.Errorf("%s: got %q, want %q", c.name, got, plain)
+			}
+		}
+	}
+}
+
+func TestRawEncodingStructRoundTrip(t *testing.T) {
+	padded := newTestService(t)
+	raw := newTestService(t)
+	raw.RawEncoding = true
+
+	u := testUser{Email: "ada@example.com", Profile: testProfile{Phone: "555-0100"}}
+	if err := padded.EncryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	if err := raw.DecryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Email != "ada@example.com" || u.Profile.Phone != "555-0100" {
+		t.Errorf("got %q/%q after padded → raw round-trip", u.Email, u.Profile.Phone)
+	}
+}
+
+func TestDecryptRejectsBadBase64(t *testing.T) {
+	s := newTestService(t)
+	if _, err := s.Decrypt("not base64!"); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("err = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestBlindIndexSizeLimit(t *testing.T) {
+	s := newTestService(t)
+	s.MaxPlaintextBytes = 4
+
+	_, err := s.BlindIndex("12345")
+	wantSizeLimit(t, err, "index", "", 4, 5)
+}
@@ -0,0 +1,62 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// A blind index is a keyed hash of a value stored next to its ciphertext,
+// so the DB can look rows up by exact match (WHERE email_index = ?) while
+// the value itself stays randomly encrypted. Like deterministic encryption
+// it shows which rows share a value; unlike it, it can't be reversed.
+
+// blindIndexLabel derives the index key from the main key when none is given.
+const blindIndexLabel = "astrocrypt blind index v1"
+
+// NewServiceWithIndexKey is NewService with a dedicated BlindIndex key, e.g.
+// so the index survives a rotation of the encryption key. indexKey must be
+// at least 16 bytes.
+func NewServiceWithIndexKey(key, indexKey []byte) (*Service, error) {
+	if len(indexKey) < 16 {
+		return nil, ErrInvalidKeyLength
+	}
+	s, err := NewService(key)
+	if err != nil {
+		return nil, err
+	}
+	s.indexKey = append([]byte(nil), indexKey...)
+	return s, nil
+}
+
+// BlindIndex returns the HMAC-SHA256 of value under the index key, as hex
+// (or unpadded base64 with IndexBase64). value is trimmed first, and
+// lowercased with IndexLowercase, so the same normalisation must be used
+// when indexing and when querying; going through BlindIndex both times
+// guarantees it. Equal values give equal indexes, for the same key only.
+func (s *Service) BlindIndex(value string) (string, error) {
+	if len(s.indexKey) == 0 {
+		return "", ErrMissingKey
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	if err := checkSize("index", s.MaxPlaintextBytes, len(value)); err != nil {
+		return "", err
+	}
+	if s.IndexLowercase {
+		value = strings.ToLower(value)
+	}
+
+	mac := hmac.New(sha256.New, s.indexKey)
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+	if s.IndexBase64 {
+		return base64.RawStdEncoding.EncodeToString(sum), nil
+	}
+	return hex.EncodeToString(sum), nil
+}
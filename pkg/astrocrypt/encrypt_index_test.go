@@ -0,0 +1,106 @@
+package astrocrypt
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// mustIndex returns s.BlindIndex(value), failing the test on error.
+func mustIndex(t *testing.T, s *Service, value string) string {
+	t.Helper()
+	idx, err := s.BlindIndex(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestBlindIndexIsStable(t *testing.T) {
+	s := newTestService(t)
+	first := mustIndex(t, s, "ada@example.com")
+	for i := 0; i < 3; i++ {
+		if got := mustIndex(t, s, "ada@example.com"); got != first {
+			t.Fatalf("call %d: %q, want %q", i, got, first)
+		}
+	}
+	// A second Service on the same key agrees.
+	if got := mustIndex(t, newTestService(t), "ada@example.com"); got != first {
+		t.Errorf("same key, new Service: %q, want %q", got, first)
+	}
+	if raw, err := hex.DecodeString(first); err != nil || len(raw) != 32 {
+		t.Errorf("index %q is not a hex HMAC-SHA256", first)
+	}
+	if mustIndex(t, s, "bob@example.com") == first {
+		t.Error("different values share an index")
+	}
+}
+
+func TestBlindIndexDiffersBetweenKeys(t *testing.T) {
+	s := newTestService(t)
+	other, err := NewService([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mustIndex(t, s, "ada@example.com") == mustIndex(t, other, "ada@example.com") {
+		t.Error("two main keys give the same index")
+	}
+
+	// A dedicated index key decides alone: it survives a change of the
+	// main key, and differs from the derived one.
+	indexKey := []byte("index-key-0123456789")
+	a, err := NewServiceWithIndexKey([]byte("0123456789abcdef0123456789abcdef"), indexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewServiceWithIndexKey([]byte("fedcba9876543210fedcba9876543210"), indexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mustIndex(t, a, "ada@example.com") != mustIndex(t, b, "ada@example.com") {
+		t.Error("same index key, different main keys: indexes differ")
+	}
+	if mustIndex(t, a, "ada@example.com") == mustIndex(t, s, "ada@example.com") {
+		t.Error("a dedicated index key gives the derived key's index")
+	}
+
+	if _, err := NewServiceWithIndexKey([]byte("0123456789abcdef0123456789abcdef"), []byte("short")); !errors.Is(err, ErrInvalidKeyLength) {
+		t.Errorf("short index key: err = %v, want ErrInvalidKeyLength", err)
+	}
+}
+
+func TestBlindIndexNormalisation(t *testing.T) {
+	s := newTestService(t)
+	base := mustIndex(t, s, "Ada@Example.com")
+
+	// Surrounding space is always trimmed; case is kept by default.
+	if got := mustIndex(t, s, "  Ada@Example.com\t\n"); got != base {
+		t.Errorf("trimmed value: %q, want %q", got, base)
+	}
+	if mustIndex(t, s, "ada@example.com") == base {
+		t.Error("case folded without IndexLowercase")
+	}
+	if got := mustIndex(t, s, "   "); got != "" {
+		t.Errorf("blank value: %q, want no index", got)
+	}
+
+	s.IndexLowercase = true
+	lower := mustIndex(t, s, "ada@example.com")
+	for _, v := range []string{"Ada@Example.com", " ADA@EXAMPLE.COM "} {
+		if got := mustIndex(t, s, v); got != lower {
+			t.Errorf("IndexLowercase: %q → %q, want %q", v, got, lower)
+		}
+	}
+
+	// IndexBase64 changes the encoding, not the MAC.
+	s.IndexBase64 = true
+	b64 := mustIndex(t, s, "ada@example.com")
+	raw, err := base64.RawStdEncoding.DecodeString(b64)
+	if err != nil {
+		t.Fatalf("IndexBase64: %q: %v", b64, err)
+	}
+	if hex.EncodeToString(raw) != lower {
+		t.Errorf("IndexBase64: %q decodes to %x, want %s", b64, raw, lower)
+	}
+}
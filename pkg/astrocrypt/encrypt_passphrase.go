@@ -0,0 +1,92 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Default scrypt cost used by NewServiceFromPassphrase (~64 MB of memory).
+const (
+	DefaultScryptN  = 1 << 16
+	DefaultScryptR  = 8
+	DefaultScryptP  = 1
+	DefaultSaltSize = 16
+)
+
+var ErrInvalidKDFParams = errors.New("invalid key derivation parameters")
+
+// PassphraseOptions tunes the scrypt derivation. The same values must be used
+// on the encrypt and decrypt side, so store them with the salt when you
+// change them.
+type PassphraseOptions struct {
+	N int // CPU/memory cost, power of two. 0 → DefaultScryptN
+	R int // block size. 0 → DefaultScryptR
+	P int // parallelism. 0 → DefaultScryptP
+
+	// SaltSize is the length of a generated salt. 0 → DefaultSaltSize.
+	SaltSize int
+}
+
+// NewServiceFromPassphrase derives an AES-256 key from passphrase with scrypt
+// and the default cost. When salt is nil a random one is generated; read it
+// back with Salt and persist it, the decrypt side needs the same salt.
+func NewServiceFromPassphrase(passphrase string, salt []byte) (*Service, error) {
+	return NewServiceFromPassphraseWithOptions(passphrase, salt, PassphraseOptions{})
+}
+
+// NewServiceFromPassphraseWithOptions is NewServiceFromPassphrase with a
+// custom scrypt cost.
+func NewServiceFromPassphraseWithOptions(passphrase string, salt []byte, opts PassphraseOptions) (*Service, error) {
+	if passphrase == "" {
+		return nil, ErrMissingKey
+	}
+
+	n, r, p := opts.N, opts.R, opts.P
+	if n == 0 {
+		n = DefaultScryptN
+	}
+	if r == 0 {
+		r = DefaultScryptR
+	}
+	if p == 0 {
+		p = DefaultScryptP
+	}
+	if n <= 1 || n&(n-1) != 0 || r < 0 || p < 0 || uint64(r)*uint64(p) >= 1<<30 || r > (1<<31-1)/128/n {
+		return nil, ErrInvalidKDFParams
+	}
+
+	if salt == nil {
+		size := opts.SaltSize
+		if size <= 0 {
+			size = DefaultSaltSize
+		}
+		salt = make([]byte, size)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+	} else {
+		salt = append([]byte(nil), salt...)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKDFParams, err)
+	}
+	s, err := NewService(key)
+	if err != nil {
+		return nil, err
+	}
+	s.salt = salt
+	return s, nil
+}
+
+// Salt returns the salt the key was derived with, or nil when the Service
+// was built from a raw key.
+func (s *Service) Salt() []byte {
+	return append([]byte(nil), s.salt...)
+}
@@ -0,0 +1,140 @@
+package astrocrypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fastKDF keeps the tests quick; the cost is not what is tested.
+var fastKDF = PassphraseOptions{N: 1 << 10, R: 8, P: 1}
+
+// mustHex decodes s, ignoring spaces.
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// RFC 7914 §12 test vectors. Services use the first 32 bytes of the 64-byte
+// outputs, PBKDF2 output blocks not depending on the length asked for.
+func TestPassphraseScryptVectors(t *testing.T) {
+	for _, v := range []struct {
+		passphrase, salt string
+		opts             PassphraseOptions
+		dk               string
+	}{
+		{"password", "NaCl", PassphraseOptions{N: 1024, R: 8, P: 16},
+			"fd ba be 1c 9d 34 72 00 78 56 e7 19 0d 01 e9 fe 7c 6a d7 cb c8 23 78 30 e7 73 76 63 4b 37 31 62"},
+		{"pleaseletmein", "SodiumChloride", PassphraseOptions{N: 16384, R: 8, P: 1},
+			"70 23 bd cb 3a fd 73 48 46 1c 06 cd 81 fd 38 eb fd a8 fb ba 90 4f 8e 3e a9 b5 43 f6 54 5d a1 f2"},
+	} {
+		derived, err := NewServiceFromPassphraseWithOptions(v.passphrase, []byte(v.salt), v.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := NewService(mustHex(t, v.dk))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Same key ⇔ each decrypts what the other encrypts.
+		text, err := raw.Encrypt("vector")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := derived.Decrypt(text); err != nil || got != "vector" {
+			t.Errorf("%s/%s: derived key differs from the RFC 7914 vector (%v)", v.passphrase, v.salt, err)
+		}
+	}
+}
+
+func TestPassphraseGeneratedSaltRoundTrip(t *testing.T) {
+	enc, err := NewServiceFromPassphraseWithOptions("correct horse", nil, fastKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := enc.Salt()
+	if len(salt) != DefaultSaltSize {
+		t.Fatalf("salt is %d bytes, want %d", len(salt), DefaultSaltSize)
+	}
+	text, err := enc.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Salt returns a copy: changing it must not change the Service.
+	salt[0] ^= 0xff
+	if bytes.Equal(salt, enc.Salt()) {
+		t.Error("Salt returned the Service's own slice")
+	}
+	salt[0] ^= 0xff
+
+	dec, err := NewServiceFromPassphraseWithOptions("correct horse", salt, fastKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dec.Decrypt(text); err != nil || got != "secret" {
+		t.Errorf("Decrypt with the persisted salt = %q, %v", got, err)
+	}
+
+	other, err := NewServiceFromPassphraseWithOptions("correct horse", nil, fastKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other.Salt(), enc.Salt()) {
+		t.Error("two generated salts are equal")
+	}
+	if _, err := other.Decrypt(text); err == nil {
+		t.Error("a different salt decrypted the value")
+	}
+}
+
+func TestPassphraseSaltSize(t *testing.T) {
+	opts := fastKDF
+	opts.SaltSize = 32
+	s, err := NewServiceFromPassphraseWithOptions("pw", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Salt()) != 32 {
+		t.Errorf("salt is %d bytes, want 32", len(s.Salt()))
+	}
+}
+
+func TestPassphraseErrors(t *testing.T) {
+	if _, err := NewServiceFromPassphraseWithOptions("", nil, fastKDF); !errors.Is(err, ErrMissingKey) {
+		t.Errorf("empty passphrase: err = %v, want ErrMissingKey", err)
+	}
+	for _, opts := range []PassphraseOptions{{N: 1000}, {N: 1}, {N: 1024, R: -1}} {
+		if _, err := NewServiceFromPassphraseWithOptions("pw", nil, opts); !errors.Is(err, ErrInvalidKDFParams) {
+			t.Errorf("%+v: err = %v, want ErrInvalidKDFParams", opts, err)
+		}
+	}
+}
+
+func TestNewServiceKeyLength(t *testing.T) {
+	if _, err := NewService(nil); !errors.Is(err, ErrMissingKey) {
+		t.Errorf("no key: err = %v, want ErrMissingKey", err)
+	}
+	for _, n := range []int{1, 15, 31, 33, 64} {
+		if _, err := NewService(make([]byte, n)); !errors.Is(err, ErrInvalidKeyLength) {
+			t.Errorf("%d-byte key: err = %v, want ErrInvalidKeyLength", n, err)
+		}
+	}
+	for _, n := range []int{16, 24, 32} {
+		s, err := NewService(make([]byte, n))
+		if err != nil {
+			t.Errorf("%d-byte key: %v", n, err)
+			continue
+		}
+		if s.Salt() != nil {
+			t.Errorf("%d-byte key: Salt() = %x, want nil", n, s.Salt())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyProvider yields an encryption key, e.g. from an env var, a file or a
+// KMS. An unavailable key is reported with an error wrapping ErrMissingKey,
+// other errors mean the source itself failed.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyProviderFunc adapts a function to KeyProvider.
+type KeyProviderFunc func() ([]byte, error)
+
+func (f KeyProviderFunc) Key() ([]byte, error) { return f() }
+
+// EnvKey reads the key from the environment variable name, as raw bytes.
+func EnvKey(name string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		val := os.Getenv(name)
+		if val == "" {
+			return nil, fmt.Errorf("%w: env %s is not set", ErrMissingKey, name)
+		}
+		return []byte(val), nil
+	})
+}
+
+// FileKey reads the key from the file at path, e.g. a mounted secret.
+// Trailing newlines are removed.
+func FileKey(path string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: file %s does not exist", ErrMissingKey, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		key := strings.TrimRight(string(data), "\r\n")
+		if key == "" {
+			return nil, fmt.Errorf("%w: file %s is empty", ErrMissingKey, path)
+		}
+		return []byte(key), nil
+	})
+}
+
+// NewServiceFromProviders builds a Service from the first provider that
+// yields a key of a valid length, trying them in order:
+//
+//	svc, err := NewServiceFromProviders(EnvKey("ENCRYPTION_KEY"), FileKey("/run/secrets/key"), kms)
+//
+// When none does, the error wraps ErrMissingKey and lists why each provider
+// failed; key material is never included.
+func NewServiceFromProviders(providers ...KeyProvider) (*Service, error) {
+	var errs []error
+	for i, p := range providers {
+		key, err := p.Key()
+		if err == nil {
+			var s *Service
+			if s, err = NewService(key); err == nil {
+				return s, nil
+			}
+		}
+		errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+	}
+	if len(errs) == 0 {
+		return nil, ErrMissingKey
+	}
+	return nil, fmt.Errorf("%w: %w", ErrMissingKey, errors.Join(errs...))
+}
@@ -0,0 +1,198 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrKMSThrottled is returned by a RateLimitedProvider or a rate limited
+// ProviderService when a call would queue longer than its wait budget or its
+// context deadline allows.
+var ErrKMSThrottled = errors.New("key provider throttled")
+
+// RateLimitOptions caps the calls a RateLimitedProvider makes to the
+// provider it wraps, or a ProviderService to its KMS, e.g. to stay under a
+// cloud KMS quota during bulk jobs.
+type RateLimitOptions struct {
+	RPS   float64 // sustained calls per second. 0 → no rate limit
+	Burst int     // calls allowed at once after an idle period. 0 → 1
+
+	// MaxInFlight caps the calls running at the same time. 0 → no cap.
+	MaxInFlight int
+
+	// MaxWait is the longest a call may queue for a token and a slot before
+	// failing with ErrKMSThrottled. 0 → only the context deadline applies.
+	MaxWait time.Duration
+}
+
+// RateLimitedProvider is a KeyProvider calling another one through a token
+// bucket and a cap on concurrent calls. Callers queue in arrival order.
+type RateLimitedProvider struct {
+	inner   KeyProvider
+	limiter *rateLimiter
+}
+
+// RateLimited wraps p so that its Key calls follow opts:
+//
+//	kms := astrocrypt.RateLimited(kmsProvider, astrocrypt.RateLimitOptions{RPS: 50, Burst: 10, MaxInFlight: 4, MaxWait: time.Second})
+func RateLimited(p KeyProvider, opts RateLimitOptions) *RateLimitedProvider {
+	return &RateLimitedProvider{inner: p, limiter: newRateLimiter(opts)}
+}
+
+// Key is KeyContext without a deadline.
+func (r *RateLimitedProvider) Key() ([]byte, error) {
+	return r.KeyContext(context.Background())
+}
+
+// KeyContext waits for a token and an in-flight slot, then calls the wrapped
+// provider. A call that could not start within MaxWait, or before ctx's
+// deadline, fails right away with ErrKMSThrottled (also matching
+// context.DeadlineExceeded in the latter case) without using a token. A ctx
+// cancelled while queued returns ctx.Err().
+func (r *RateLimitedProvider) KeyContext(ctx context.Context) ([]byte, error) {
+	release, err := r.limiter.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return r.inner.Key()
+}
+
+// rateLimiter is the token bucket and in-flight cap shared by
+// RateLimitedProvider and ProviderService.
+type rateLimiter struct {
+	opts RateLimitOptions
+	sem  chan struct{} // nil → no in-flight cap
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time // nil → time.Now
+}
+
+func newRateLimiter(opts RateLimitOptions) *rateLimiter {
+	if opts.Burst <= 0 {
+		opts.Burst = 1
+	}
+	r := &rateLimiter{opts: opts, tokens: float64(opts.Burst)}
+	if opts.MaxInFlight > 0 {
+		r.sem = make(chan struct{}, opts.MaxInFlight)
+	}
+	return r
+}
+
+// wait blocks until a call may start, see KeyContext. The call must invoke
+// release once done.
+func (r *rateLimiter) wait(ctx context.Context) (release func(), err error) {
+	start := r.clock()
+	budget := time.Time{}
+	if r.opts.MaxWait > 0 {
+		budget = start.Add(r.opts.MaxWait)
+	}
+
+	wait, err := r.reserve(ctx, start, budget)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			r.cancel()
+			return nil, ctx.Err()
+		}
+	}
+
+	if r.sem == nil {
+		return func() {}, nil
+	}
+	if err := r.acquire(ctx, budget); err != nil {
+		return nil, err
+	}
+	return func() { <-r.sem }, nil
+}
+
+// reserve takes a token and returns how long to wait before it is valid,
+// or ErrKMSThrottled when that is past the budget or ctx's deadline.
+func (r *rateLimiter) reserve(ctx context.Context, now, budget time.Time) (time.Duration, error) {
+	if r.opts.RPS <= 0 {
+		return 0, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.last.IsZero() {
+		r.tokens += now.Sub(r.last).Seconds() * r.opts.RPS
+		if burst := float64(r.opts.Burst); r.tokens > burst {
+			r.tokens = burst
+		}
+	}
+	r.last = now
+
+	var wait time.Duration
+	if r.tokens < 1 {
+		wait = time.Duration((1 - r.tokens) / r.opts.RPS * float64(time.Second))
+	}
+	ready := now.Add(wait)
+	if !budget.IsZero() && ready.After(budget) {
+		return 0, fmt.Errorf("%w: would wait %s, budget is %s", ErrKMSThrottled, wait, r.opts.MaxWait)
+	}
+	if deadline, ok := ctx.Deadline(); ok && ready.After(deadline) {
+		return 0, fmt.Errorf("%w: would wait %s: %w", ErrKMSThrottled, wait, context.DeadlineExceeded)
+	}
+	r.tokens--
+	return wait, nil
+}
+
+// cancel gives back the token of a call abandoned while waiting for it.
+func (r *rateLimiter) cancel() {
+	if r.opts.RPS <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens++; r.tokens > float64(r.opts.Burst) {
+		r.tokens = float64(r.opts.Burst)
+	}
+}
+
+// acquire takes an in-flight slot, waiting at most until budget.
+func (r *rateLimiter) acquire(ctx context.Context, budget time.Time) error {
+	select {
+	case r.sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	var expired <-chan time.Time
+	if !budget.IsZero() {
+		t := time.NewTimer(budget.Sub(r.clock()))
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case r.sem <- struct{}{}:
+		return nil
+	case <-expired:
+		return fmt.Errorf("%w: %d calls in flight for %s", ErrKMSThrottled, r.opts.MaxInFlight, r.opts.MaxWait)
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %d calls in flight: %w", ErrKMSThrottled, r.opts.MaxInFlight, ctx.Err())
+		}
+		return ctx.Err()
+	}
+}
+
+func (r *rateLimiter) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,155 @@
+package astrocrypt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider returns a fixed key and records when each call was made.
+type fakeProvider struct {
+	mu    sync.Mutex
+	calls []time.Time
+	hold  chan struct{} // when set, Key blocks until it is closed
+}
+
+func (p *fakeProvider) Key() ([]byte, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, time.Now())
+	hold := p.hold
+	p.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+	return []byte("0123456789abcdef0123456789abcdef"), nil
+}
+
+func (p *fakeProvider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.calls)
+}
+
+func TestRateLimitedCallRate(t *testing.T) {
+	const rps = 50
+	inner := &fakeProvider{}
+	r := RateLimited(inner, RateLimitOptions{RPS: rps, Burst: 2})
+	for i := 0; i < 6; i++ {
+		if _, err := r.KeyContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The burst goes through at once; each later call waits for a token.
+	if gap := inner.calls[1].Sub(inner.calls[0]); gap > 10*time.Millisecond {
+		t.Errorf("second call of the burst came after %s", gap)
+	}
+	minGap := time.Second / rps * 9 / 10
+	for i := 2; i < len(inner.calls); i++ {
+		if gap := inner.calls[i].Sub(inner.calls[i-1]); gap < minGap {
+			t.Errorf("call %d came %s after the previous one, want ≥ %s", i, gap, minGap)
+		}
+	}
+}
+
+func TestRateLimitedDeadline(t *testing.T) {
+	inner := &fakeProvider{}
+	r := RateLimited(inner, RateLimitOptions{RPS: 1})
+	if _, err := r.Key(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next token is a second away, past the deadline: fail now.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := r.KeyContext(ctx)
+	if !errors.Is(err, ErrKMSThrottled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want ErrKMSThrottled and DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("failed after %s, want right away", elapsed)
+	}
+	if n := inner.count(); n != 1 {
+		t.Errorf("calls = %d, want 1: the throttled call must not reach the provider", n)
+	}
+}
+
+func TestRateLimitedMaxWait(t *testing.T) {
+	inner := &fakeProvider{}
+	r := RateLimited(inner, RateLimitOptions{RPS: 1, MaxWait: 100 * time.Millisecond})
+	if _, err := r.Key(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := r.Key()
+	if !errors.Is(err, ErrKMSThrottled) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want ErrKMSThrottled from the wait budget", err)
+	}
+}
+
+func TestRateLimitedCancelReturnsToken(t *testing.T) {
+	inner := &fakeProvider{}
+	r := RateLimited(inner, RateLimitOptions{RPS: 20})
+	if _, err := r.Key(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := r.KeyContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	// The abandoned token is given back: the next call waits about one
+	// interval from the first, not two.
+	start := time.Now()
+	if _, err := r.Key(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 60*time.Millisecond {
+		t.Errorf("waited %s after a cancelled call, want < 60ms", elapsed)
+	}
+}
+
+func TestRateLimitedMaxInFlight(t *testing.T) {
+	inner := &fakeProvider{hold: make(chan struct{})}
+	r := RateLimited(inner, RateLimitOptions{MaxInFlight: 1, MaxWait: 20 * time.Millisecond})
+	done := make(chan error)
+	go func() {
+		_, err := r.Key()
+		done <- err
+	}()
+	for inner.count() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := r.Key(); !errors.Is(err, ErrKMSThrottled) {
+		t.Errorf("err = %v, want ErrKMSThrottled while the slot is taken", err)
+	}
+	close(inner.hold)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Key(); err != nil {
+		t.Errorf("after the slot freed: %v", err)
+	}
+}
+
+func TestRateLimitedNoLimits(t *testing.T) {
+	inner := &fakeProvider{}
+	r := RateLimited(inner, RateLimitOptions{})
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if _, err := r.Key(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("100 unlimited calls took %s", elapsed)
+	}
+}
@@ -0,0 +1,250 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Re-keying moves data encrypted under an old key to the key of the
+// receiving Service. Every entry point takes the old Service explicitly.
+
+// ReencryptOptions configures ReencryptStruct and ReencryptSlice.
+type ReencryptOptions struct {
+	// DryRun decrypts and re-encrypts as usual, which proves the old key
+	// works, but leaves v untouched. The report lists what would change.
+	DryRun bool
+	// Progress is called after each slice element with the number of
+	// elements done and the total. It may run on several goroutines at once.
+	// nil → no progress reporting.
+	Progress func(done, total int64)
+}
+
+// ReencryptStruct re-encrypts every tagged field of v (a pointer to a
+// struct) from old to s and returns the paths of the rewritten string
+// fields; `into=` fields are reported by their sibling. Like EncryptStruct
+// it is all-or-nothing.
+func (s *Service) ReencryptStruct(old *Service, v interface{}, opts ReencryptOptions) ([]string, error) {
+	w := s.rekeyWalker(old, opts)
+	if err := walkStructWith(w, v); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), w.changed...), nil
+}
+
+// ReencryptSlice is ReencryptStruct over a slice, spread over s.Workers
+// goroutines like EncryptSlice. Failed elements are left untouched and
+// reported in a *SliceError; the returned paths cover the others, in index
+// order.
+func (s *Service) ReencryptSlice(old *Service, v interface{}, opts ReencryptOptions) ([]string, error) {
+	n := -1
+	if val := reflect.Indirect(reflect.ValueOf(v)); val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		n = val.Len()
+	}
+
+	var (
+		mu      sync.Mutex
+		changed = make(map[int][]string)
+		done    atomic.Int64
+	)
+
+	err := s.walkSlice(v, func() *structWalker {
+		return s.rekeyWalker(old, opts)
+	}, func(i int, w *structWalker, err error) {
+		if err == nil && len(w.changed) > 0 {
+			mu.Lock()
+			changed[i] = append([]string(nil), w.changed...)
+			mu.Unlock()
+		}
+		if opts.Progress != nil {
+			opts.Progress(done.Add(1), int64(n))
+		}
+	})
+
+	var paths []string
+	for i := range max(n, 0) {
+		paths = append(paths, changed[i]...)
+	}
+	return paths, err
+}
+
+// rekeyWalker returns a walker that decrypts with old and encrypts with s,
+// keeping deterministic values deterministic and recomputing blind indexes
+// with s's index key.
+func (s *Service) rekeyWalker(old *Service, opts ReencryptOptions) *structWalker {
+	w := newStructWalker(context.Background(), func(ciphertext string) (string, error) {
+		plain, err := old.Decrypt(ciphertext)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(ciphertext, DeterministicPrefix) {
+			return s.EncryptDeterministic(plain)
+		}
+		return s.Encrypt(plain)
+	}, false)
+	w.index = func(ciphertext string) (string, error) {
+		plain, err := old.Decrypt(ciphertext)
+		if err != nil {
+			return "", err
+		}
+		return s.BlindIndex(plain)
+	}
+	w.aead = func(ciphertext string, aad []byte) (string, error) {
+		plain, err := old.DecryptWithContext(ciphertext, aad)
+		if err != nil {
+			return "", err
+		}
+		return s.EncryptWithContext(plain, aad)
+	}
+	w.aadIdx = func(ciphertext string, aad []byte) (string, error) {
+		plain, err := old.DecryptWithContext(ciphertext, aad)
+		if err != nil {
+			return "", err
+		}
+		return s.BlindIndex(plain)
+	}
+	w.rekey, w.dryRun = true, opts.DryRun
+	return w
+}
+
+// ReencryptStream reads a stream written by old.EncryptStream from src and
+// writes it to dst encrypted under s, in one pass and in constant memory.
+// progress, when not nil, is called after every chunk with the encrypted
+// bytes read so far and the size of src (-1 when unknown). On error dst
+// holds a partial stream and must be discarded.
+func (s *Service) ReencryptStream(old *Service, dst io.Writer, src io.Reader, progress func(done, total int64)) error {
+	if progress != nil {
+		src = &progressReader{r: src, total: readerSize(src), fn: progress}
+	}
+
+	pr, pw := io.Pipe()
+	decErr := make(chan error, 1)
+	go func() {
+		err := old.DecryptStream(pw, src)
+		_ = pw.CloseWithError(err)
+		decErr <- err
+	}()
+
+	err := s.EncryptStream(dst, pr)
+	_ = pr.Close() // unblocks the decrypter if EncryptStream stopped early
+	if dErr := <-decErr; dErr != nil && dErr != io.ErrClosedPipe {
+		return dErr
+	}
+	return err
+}
+
+// progressReader reports how many bytes have been read through it.
+type progressReader struct {
+	r     io.Reader
+	n     int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.n += int64(n)
+		p.fn(p.n, p.total)
+	}
+	return n, err
+}
+
+// readerSize returns the size of files and in-memory readers, -1 otherwise.
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case *os.File:
+		if info, err := v.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+// =============================
+// Cost estimate
+// =============================
+
+// CiphertextFormat classifies a stored ciphertext for EstimateReencryptCost.
+type CiphertextFormat string
+
+const (
+	FormatEmpty   CiphertextFormat = "empty"   // "", nothing to re-encrypt
+	FormatPadded  CiphertextFormat = "padded"  // base64 with padding (default Encrypt output)
+	FormatRaw     CiphertextFormat = "raw"     // unpadded base64 (RawEncoding)
+	FormatInvalid CiphertextFormat = "invalid" // not base64, or too short to be a ciphertext
+
+	FormatDeterministic CiphertextFormat = "deterministic" // EncryptDeterministic output
+)
+
+// gcmMinSealed is the size of an empty plaintext sealed by Encrypt:
+// 12-byte nonce plus 16-byte tag.
+const gcmMinSealed = 12 + 16
+
+// sivMinSealed is the size of the synthetic IV EncryptDeterministic
+// prepends.
+const sivMinSealed = 16
+
+// FormatStats summarises the samples of one format.
+type FormatStats struct {
+	Count    int
+	AvgBytes float64 // average length of the stored (base64) value
+}
+
+// ReencryptEstimate is returned by EstimateReencryptCost.
+type ReencryptEstimate struct {
+	Samples int
+	Formats map[CiphertextFormat]FormatStats
+}
+
+// EstimateReencryptCost classifies sample ciphertexts, e.g. a few thousand
+// rows of a column, so a re-keying job can be sized before it runs. No key
+// is needed and nothing is decrypted.
+func EstimateReencryptCost(sampleCiphertexts []string) ReencryptEstimate {
+	est := ReencryptEstimate{
+		Samples: len(sampleCiphertexts),
+		Formats: make(map[CiphertextFormat]FormatStats),
+	}
+	sums := make(map[CiphertextFormat]int)
+	for _, c := range sampleCiphertexts {
+		format := classifyCiphertext(c)
+		stats := est.Formats[format]
+		stats.Count++
+		est.Formats[format] = stats
+		sums[format] += len(c)
+	}
+	for format, stats := range est.Formats {
+		stats.AvgBytes = float64(sums[format]) / float64(stats.Count)
+		est.Formats[format] = stats
+	}
+	return est
+}
+
+func classifyCiphertext(c string) CiphertextFormat {
+	if c == "" {
+		return FormatEmpty
+	}
+	if encoded, ok := strings.CutPrefix(c, DeterministicPrefix); ok {
+		data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil || len(data) < sivMinSealed {
+			return FormatInvalid
+		}
+		return FormatDeterministic
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(c, "="))
+	if err != nil || len(data) < gcmMinSealed {
+		return FormatInvalid
+	}
+	if strings.HasSuffix(c, "=") || len(c)%4 == 0 {
+		return FormatPadded
+	}
+	return FormatRaw
+}
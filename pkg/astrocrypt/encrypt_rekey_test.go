@@ -0,0 +1,163 @@
+package astrocrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// newRekeyServices returns the old and new Service of a key rotation.
+func newRekeyServices(t *testing.T) (old, cur *Service) {
+	t.Helper()
+	old = newTestService(t)
+	cur, err := NewService([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return old, cur
+}
+
+func TestReencryptStreamRoundTrip(t *testing.T) {
+	old, cur := newRekeyServices(t)
+	plain := make([]byte, 3*streamChunkSize+17)
+	if _, err := rand.Read(plain); err != nil {
+		t.Fatal(err)
+	}
+	var sealed bytes.Buffer
+	if err := old.EncryptStream(&sealed, bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		rekeyed        bytes.Buffer
+		calls          int
+		lastDone, size int64
+	)
+	err := cur.ReencryptStream(old, &rekeyed, bytes.NewReader(sealed.Bytes()), func(done, total int64) {
+		calls++
+		lastDone, size = done, total
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || lastDone != int64(sealed.Len()) || size != int64(sealed.Len()) {
+		t.Errorf("progress: %d calls, last %d/%d; want %d/%d", calls, lastDone, size, sealed.Len(), sealed.Len())
+	}
+
+	var out bytes.Buffer
+	if err := cur.DecryptStream(&out, bytes.NewReader(rekeyed.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), plain) {
+		t.Fatal("re-encrypted stream does not decrypt to the original")
+	}
+	if err := old.DecryptStream(&bytes.Buffer{}, bytes.NewReader(rekeyed.Bytes())); err == nil {
+		t.Error("the old key still decrypts the re-encrypted stream")
+	}
+
+	// A stream not written by old fails instead of producing garbage.
+	if err := cur.ReencryptStream(cur, &bytes.Buffer{}, bytes.NewReader(sealed.Bytes()), nil); err == nil {
+		t.Error("ReencryptStream with the wrong old key succeeded")
+	}
+}
+
+type rekeyRecord struct {
+	Email      string `encrypt:"true,index=EmailIndex"`
+	EmailIndex string
+	SSN        string `encrypt:"deterministic"`
+	Card       *testCard
+	Cards      []testCard
+	Note       string
+}
+
+func newRekeyRecord(t *testing.T, old *Service) rekeyRecord {
+	t.Helper()
+	r := rekeyRecord{
+		Email: "ada@example.com",
+		SSN:   "078-05-1120",
+		Card:  &testCard{Number: "4111", Label: "visa"},
+		Cards: []testCard{{Number: "5500"}},
+		Note:  "plain",
+	}
+	if err := old.EncryptStruct(&r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// clone deep-copies r so a walk writing through its pointers can be seen.
+func (r rekeyRecord) clone() rekeyRecord {
+	card := *r.Card
+	r.Card = &card
+	r.Cards = append([]testCard(nil), r.Cards...)
+	return r
+}
+
+func TestReencryptStructDryRun(t *testing.T) {
+	old, cur := newRekeyServices(t)
+	r := newRekeyRecord(t, old)
+	before := r.clone()
+
+	paths, err := cur.ReencryptStruct(old, &r, ReencryptOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, before) {
+		t.Errorf("dry run modified its input:\n got %+v\nwant %+v", r, before)
+	}
+	want := []string{"rekeyRecord.EmailIndex", "rekeyRecord.Email", "rekeyRecord.SSN", "rekeyRecord.Card.Number", "rekeyRecord.Cards[0].Number"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("dry run paths = %q, want %q", paths, want)
+	}
+
+	// The same call without DryRun rewrites those fields for the new key.
+	if _, err := cur.ReencryptStruct(old, &r, ReencryptOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.Email == before.Email || r.Card.Number == before.Card.Number {
+		t.Fatal("re-encryption left the ciphertexts unchanged")
+	}
+	wantIndex, _ := cur.BlindIndex("ada@example.com")
+	if r.EmailIndex != wantIndex {
+		t.Errorf("EmailIndex = %q, want the new key's index %q", r.EmailIndex, wantIndex)
+	}
+	wantSSN, _ := cur.EncryptDeterministic("078-05-1120")
+	if r.SSN != wantSSN {
+		t.Errorf("SSN = %q, want the new key's deterministic ciphertext", r.SSN)
+	}
+	if err := cur.DecryptStruct(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Email != "ada@example.com" || r.Card.Number != "4111" || r.Cards[0].Number != "5500" || r.Note != "plain" {
+		t.Errorf("decrypted under the new key: %+v", r)
+	}
+}
+
+func TestReencryptSliceDryRun(t *testing.T) {
+	old, cur := newRekeyServices(t)
+	records := []rekeyRecord{newRekeyRecord(t, old), newRekeyRecord(t, old), newRekeyRecord(t, old)}
+	before := make([]rekeyRecord, len(records))
+	for i, r := range records {
+		before[i] = r.clone()
+	}
+
+	var progress atomic.Int64
+	paths, err := cur.ReencryptSlice(old, records, ReencryptOptions{
+		DryRun:   true,
+		Progress: func(done, total int64) { progress.Add(1) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records, before) {
+		t.Error("dry run modified the slice")
+	}
+	if len(paths) != 15 || paths[5] != "rekeyRecord[1].EmailIndex" {
+		t.Errorf("dry run paths = %q", paths)
+	}
+	if progress.Load() != 3 {
+		t.Errorf("progress called %d times, want 3", progress.Load())
+	}
+}
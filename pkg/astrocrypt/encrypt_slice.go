@@ -0,0 +1,152 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotSlice is returned by EncryptSlice/DecryptSlice for other inputs.
+var ErrNotSlice = errors.New("expected a slice of structs or struct pointers")
+
+// ElementError is the failure of one slice element.
+type ElementError struct {
+	Index int
+	Err   error
+}
+
+func (e ElementError) Error() string {
+	return fmt.Sprintf("[%d]: %v", e.Index, e.Err)
+}
+
+func (e ElementError) Unwrap() error {
+	return e.Err
+}
+
+// SliceError lists every element EncryptSlice or DecryptSlice could not
+// process, sorted by index. errors.Is/As look through all of them.
+type SliceError struct {
+	Elements []ElementError
+}
+
+func (e *SliceError) Error() string {
+	const shown = 3
+	parts := make([]string, 0, shown)
+	for i, el := range e.Elements {
+		if i == shown {
+			break
+		}
+		parts = append(parts, el.Error())
+	}
+	msg := fmt.Sprintf("%d element(s) failed: %s", len(e.Elements), strings.Join(parts, "; "))
+	if len(e.Elements) > shown {
+		msg += "; ..."
+	}
+	return msg
+}
+
+func (e *SliceError) Unwrap() []error {
+	errs := make([]error, len(e.Elements))
+	for i, el := range e.Elements {
+		errs[i] = el
+	}
+	return errs
+}
+
+// EncryptSlice runs EncryptStruct on every element of a slice (or pointer to
+// a slice) of structs or struct pointers; nil elements are skipped. Field
+// metadata is computed once per type and elements are spread over
+// s.Workers goroutines. Each element is all-or-nothing: failed elements are
+// left untouched and reported in a *SliceError, the others are encrypted.
+//
+// Elements are processed independently, so they must not share pointers.
+func (s *Service) EncryptSlice(v interface{}) error {
+	return s.walkSlice(v, func() *structWalker {
+		return s.encryptWalker(context.Background())
+	}, nil)
+}
+
+// DecryptSlice is the DecryptStruct counterpart of EncryptSlice.
+func (s *Service) DecryptSlice(v interface{}) error {
+	return s.walkSlice(v, func() *structWalker {
+		return s.decryptWalker(context.Background())
+	}, nil)
+}
+
+// walkSlice runs a walker from newWalker on every element of v. When set,
+// after is called from the worker goroutines once per element, with the
+// walker that processed it and its error.
+func (s *Service) walkSlice(v interface{}, newWalker func() *structWalker, after func(i int, w *structWalker, err error)) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return fmt.Errorf("%w, got %T", ErrNotSlice, v)
+	}
+	elemType := val.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("%w, got %T", ErrNotSlice, v)
+	}
+	name := elemType.Name()
+	structFields(elemType) // warm the cache before the workers start
+
+	walkElem := func(w *structWalker, i int) error {
+		elem := val.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				w.changed = w.changed[:0]
+				return nil
+			}
+			elem = elem.Elem()
+		}
+		return w.run(elem, pathElem{name: name}, pathElem{index: i})
+	}
+
+	var (
+		mu     sync.Mutex
+		failed []ElementError
+	)
+	record := func(i int, err error) {
+		mu.Lock()
+		failed = append(failed, ElementError{Index: i, Err: err})
+		mu.Unlock()
+	}
+
+	n := val.Len()
+	workers := min(max(s.Workers, 1), n)
+	// Each worker takes a contiguous share of the indexes and reuses one
+	// walker for all of them.
+	var wg sync.WaitGroup
+	for k := range workers {
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			w := newWalker()
+			for i := from; i < to; i++ {
+				err := walkElem(w, i)
+				if err != nil {
+					record(i, err)
+				}
+				if after != nil {
+					after(i, w, err)
+				}
+			}
+		}(k*n/workers, (k+1)*n/workers)
+	}
+	wg.Wait()
+
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Index < failed[j].Index })
+	return &SliceError{Elements: failed}
+}
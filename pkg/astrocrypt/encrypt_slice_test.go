@@ -0,0 +1,141 @@
+package astrocrypt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEncryptSliceNilPointerElements(t *testing.T) {
+	s := newTestService(t)
+	s.Workers = 4
+	users := []*testUser{
+		{Email: "a@example.com"},
+		nil,
+		{Email: "c@example.com", Backup: &testProfile{Phone: "555-0102"}},
+		nil,
+	}
+
+	if err := s.EncryptSlice(&users); err != nil {
+		t.Fatal(err)
+	}
+	if users[1] != nil || users[3] != nil {
+		t.Fatal("nil elements were allocated")
+	}
+	if users[0].Email == "a@example.com" || users[2].Backup.Phone == "555-0102" {
+		t.Fatal("elements left in plaintext")
+	}
+
+	if err := s.DecryptSlice(users); err != nil {
+		t.Fatal(err)
+	}
+	if users[0].Email != "a@example.com" || users[2].Email != "c@example.com" || users[2].Backup.Phone != "555-0102" {
+		t.Errorf("round-trip mismatch: %+v %+v", users[0], users[2])
+	}
+}
+
+func TestEncryptSliceMatchesEncryptStruct(t *testing.T) {
+	s := newTestService(t)
+	users := make([]testUser, 50)
+	for i := range users {
+		users[i] = testUser{Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+
+	if err := s.EncryptSlice(users); err != nil {
+		t.Fatal(err)
+	}
+	for i := range users {
+		u := users[i]
+		if err := s.DecryptStruct(&u); err != nil {
+			t.Fatalf("[%d]: %v", i, err)
+		}
+		if want := fmt.Sprintf("user%d@example.com", i); u.Email != want {
+			t.Fatalf("[%d] = %q, want %q", i, u.Email, want)
+		}
+	}
+}
+
+func TestEncryptSliceReportsFailedElements(t *testing.T) {
+	s := newTestService(t)
+	s.MaxPlaintextBytes = 8
+	users := []testUser{{Email: "a@b.c"}, {Email: strings.Repeat("x", 9)}, {Email: "d@e.f"}}
+
+	err := s.EncryptSlice(users)
+	var sliceErr *SliceError
+	if !errors.As(err, &sliceErr) {
+		t.Fatalf("err = %v, want *SliceError", err)
+	}
+	if len(sliceErr.Elements) != 1 || sliceErr.Elements[0].Index != 1 {
+		t.Fatalf("failed elements = %v, want only [1]", sliceErr.Elements)
+	}
+	if !errors.Is(err, ErrTooLarge) {
+		t.Error("errors.Is does not see the element error")
+	}
+	if users[1].Email != strings.Repeat("x", 9) {
+		t.Error("the failed element was modified")
+	}
+	if users[0].Email == "a@b.c" || users[2].Email == "d@e.f" {
+		t.Error("the other elements were not encrypted")
+	}
+}
+
+func TestEncryptSliceRejectsNonSlice(t *testing.T) {
+	s := newTestService(t)
+	for _, v := range []interface{}{testUser{}, &testUser{}, []string{"a"}} {
+		if err := s.EncryptSlice(v); !errors.Is(err, ErrNotSlice) {
+			t.Errorf("EncryptSlice(%T) = %v, want ErrNotSlice", v, err)
+		}
+	}
+}
+
+// benchUsers returns n users with a few encrypted fields each.
+func benchUsers(n int) []testUser {
+	users := make([]testUser, n)
+	for i := range users {
+		users[i] = testUser{
+			Name:  "user",
+			Email: fmt.Sprintf("user%d@example.com", i),
+			Profile: testProfile{
+				Phone:   "555-0100",
+				Address: testAddress{Street: "1 Main St"},
+			},
+		}
+	}
+	return users
+}
+
+func BenchmarkEncryptStructLoop(b *testing.B) {
+	s := newTestService(b)
+	users := benchUsers(1000)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		batch := append([]testUser(nil), users...)
+		b.StartTimer()
+		for i := range batch {
+			if err := s.EncryptStruct(&batch[i]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkEncryptSlice(b *testing.B) {
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			s := newTestService(b)
+			s.Workers = workers
+			users := benchUsers(1000)
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				batch := append([]testUser(nil), users...)
+				b.StartTimer()
+				if err := s.EncryptSlice(batch); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// ================ Version : V1.1.0 ===========
+package astrocrypt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Stream format
+//
+//	header : 8-byte random nonce prefix
+//	chunk  : 4-byte big-endian sealed length | sealed chunk
+//
+// Each chunk holds up to streamChunkSize bytes of plaintext and is sealed with
+// nonce = prefix || 4-byte chunk counter. The last chunk is sealed with a
+// "final" additional-data byte, so truncated or reordered streams fail to
+// decrypt.
+const (
+	streamChunkSize   = 64 * 1024
+	streamPrefixSize  = 8
+	streamCounterSize = 4
+)
+
+var (
+	ErrStreamTruncated = errors.New("encrypted stream is truncated")
+	ErrDigestMismatch  = errors.New("plaintext digest mismatch")
+)
+
+var (
+	streamAADChunk = []byte{0}
+	streamAADFinal = []byte{1}
+)
+
+// Digest describes both sides of a streaming operation.
+type Digest struct {
+	PlainSHA256  string // hex
+	CipherSHA256 string // hex, over every byte of the encrypted stream
+	PlainBytes   int64
+	CipherBytes  int64
+}
+
+// EncryptStream encrypts src into dst in fixed-size chunks.
+func (s *Service) EncryptStream(dst io.Writer, src io.Reader) error {
+	_, err := s.EncryptStreamWithDigest(dst, src)
+	return err
+}
+
+// DecryptStream decrypts a stream produced by EncryptStream.
+func (s *Service) DecryptStream(dst io.Writer, src io.Reader) error {
+	_, err := s.DecryptStreamWithDigest(dst, src, "")
+	return err
+}
+
+// EncryptStreamWithDigest encrypts src into dst and returns the SHA-256 of both
+// the plaintext and the ciphertext, computed in the same single pass.
+func (s *Service) EncryptStreamWithDigest(dst io.Writer, src io.Reader) (Digest, error) {
+	if s.gcm.NonceSize() != streamPrefixSize+streamCounterSize {
+		return Digest{}, ErrEncryptionFailed
+	}
+
+	plainHash := sha256.New()
+	out := newDigestWriter(dst)
+
+	nonce := make([]byte, s.gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce[:streamPrefixSize]); err != nil {
+		return Digest{}, ErrEncryptionFailed
+	}
+	if _, err := out.Write(nonce[:streamPrefixSize]); err != nil {
+		return Digest{}, err
+	}
+
+	// Read one chunk ahead so the last chunk can be sealed as final.
+	buf := make([]byte, streamChunkSize)
+	next := make([]byte, streamChunkSize)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Digest{}, err
+	}
+
+	var plainBytes int64
+	var counter uint32
+	for {
+		final := n < streamChunkSize
+		var m int
+		if !final {
+			m, err = io.ReadFull(src, next)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return Digest{}, err
+			}
+			final = m == 0
+		}
+
+		plainHash.Write(buf[:n])
+		plainBytes += int64(n)
+
+		aad := streamAADChunk
+		if final {
+			aad = streamAADFinal
+		}
+		binary.BigEndian.PutUint32(nonce[streamPrefixSize:], counter)
+		sealed := s.gcm.Seal(nil, nonce, buf[:n], aad)
+
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+		if _, err := out.Write(length[:]); err != nil {
+			return Digest{}, err
+		}
+		if _, err := out.Write(sealed); err != nil {
+			return Digest{}, err
+		}
+
+		if final {
+			break
+		}
+		counter++
+		buf, next = next, buf
+		n = m
+	}
+
+	return Digest{
+		PlainSHA256:  hex.EncodeToString(plainHash.Sum(nil)),
+		CipherSHA256: out.sum(),
+		PlainBytes:   plainBytes,
+		CipherBytes:  out.n,
+	}, nil
+}
+
+// DecryptStreamWithDigest decrypts src into dst and returns the digests of
+// both sides. When wantPlainSHA256 (hex) is set, the plaintext digest is
+// checked and ErrDigestMismatch returned on mismatch; dst has already
+// received the data by then, so callers should discard it on error.
+func (s *Service) DecryptStreamWithDigest(dst io.Writer, src io.Reader, wantPlainSHA256 string) (Digest, error) {
+	if s.gcm.NonceSize() != streamPrefixSize+streamCounterSize {
+		return Digest{}, ErrDecryptionFailed
+	}
+
+	in := newDigestReader(src)
+	plainHash := sha256.New()
+
+	nonce := make([]byte, s.gcm.NonceSize())
+	if _, err := io.ReadFull(in, nonce[:streamPrefixSize]); err != nil {
+		return Digest{}, ErrInvalidData
+	}
+
+	maxSealed := streamChunkSize + s.gcm.Overhead()
+	sealed := make([]byte, maxSealed)
+
+	var plainBytes int64
+	var counter uint32
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(in, length[:]); err != nil {
+			if err == io.EOF {
+				return Digest{}, ErrStreamTruncated
+			}
+			return Digest{}, ErrInvalidData
+		}
+		size := int(binary.BigEndian.Uint32(length[:]))
+		if size < s.gcm.Overhead() || size > maxSealed {
+			return Digest{}, ErrInvalidData
+		}
+		if _, err := io.ReadFull(in, sealed[:size]); err != nil {
+			return Digest{}, ErrStreamTruncated
+		}
+
+		binary.BigEndian.PutUint32(nonce[streamPrefixSize:], counter)
+		final := false
+		plain, err := s.gcm.Open(nil, nonce, sealed[:size], streamAADChunk)
+		if err != nil {
+			plain, err = s.gcm.Open(nil, nonce, sealed[:size], streamAADFinal)
+			if err != nil {
+				return Digest{}, ErrDecryptionFailed
+			}
+			final = true
+		}
+
+		plainHash.Write(plain)
+		plainBytes += int64(len(plain))
+		if _, err := dst.Write(plain); err != nil {
+			return Digest{}, err
+		}
+
+		if final {
+			break
+		}
+		counter++
+	}
+
+	// Nothing may follow the final chunk.
+	var extra [1]byte
+	if n, _ := in.Read(extra[:]); n > 0 {
+		return Digest{}, ErrInvalidData
+	}
+
+	digest := Digest{
+		PlainSHA256:  hex.EncodeToString(plainHash.Sum(nil)),
+		CipherSHA256: in.sum(),
+		PlainBytes:   plainBytes,
+		CipherBytes:  in.n,
+	}
+	if wantPlainSHA256 != "" && wantPlainSHA256 != digest.PlainSHA256 {
+		return digest, ErrDigestMismatch
+	}
+	return digest, nil
+}
+
+// digestWriter hashes and counts everything written through it.
+type digestWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+func newDigestWriter(w io.Writer) *digestWriter {
+	return &digestWriter{w: w, h: sha256.New()}
+}
+
+func (d *digestWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.h.Write(p[:n])
+	d.n += int64(n)
+	return n, err
+}
+
+func (d *digestWriter) sum() string {
+	return hex.EncodeToString(d.h.Sum(nil))
+}
+
+// digestReader hashes and counts everything read through it.
+type digestReader struct {
+	r io.Reader
+	h hash.Hash
+	n int64
+}
+
+func newDigestReader(r io.Reader) *digestReader {
+	return &digestReader{r: r, h: sha256.New()}
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.h.Write(p[:n])
+	d.n += int64(n)
+	return n, err
+}
+
+func (d *digestReader) sum() string {
+	return hex.EncodeToString(d.h.Sum(nil))
+}
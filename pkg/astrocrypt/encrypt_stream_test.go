@@ -0,0 +1,89 @@
+package astrocrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// sha256Hex is the reference digest the stream digests are checked against.
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestStreamDigestsMatchIndependentHashes(t *testing.T) {
+	s := newTestService(t)
+	// Empty, sub-chunk, exact chunk and multi-chunk inputs.
+	for _, size := range []int{0, 1, streamChunkSize - 1, streamChunkSize, streamChunkSize + 1, 3*streamChunkSize + 17} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			plain := make([]byte, size)
+			if _, err := rand.Read(plain); err != nil {
+				t.Fatal(err)
+			}
+
+			var cipher bytes.Buffer
+			enc, err := s.EncryptStreamWithDigest(&cipher, bytes.NewReader(plain))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if enc.PlainSHA256 != sha256Hex(plain) || enc.PlainBytes != int64(size) {
+				t.Errorf("plain digest %s/%d, want %s/%d", enc.PlainSHA256, enc.PlainBytes, sha256Hex(plain), size)
+			}
+			if enc.CipherSHA256 != sha256Hex(cipher.Bytes()) || enc.CipherBytes != int64(cipher.Len()) {
+				t.Errorf("cipher digest %s/%d, want %s/%d", enc.CipherSHA256, enc.CipherBytes, sha256Hex(cipher.Bytes()), cipher.Len())
+			}
+
+			var out bytes.Buffer
+			dec, err := s.DecryptStreamWithDigest(&out, bytes.NewReader(cipher.Bytes()), enc.PlainSHA256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), plain) {
+				t.Fatal("round-trip mismatch")
+			}
+			if dec != enc {
+				t.Errorf("decrypt digest %+v, want %+v", dec, enc)
+			}
+		})
+	}
+}
+
+func TestDecryptStreamDigestMismatch(t *testing.T) {
+	s := newTestService(t)
+	plain := make([]byte, 1000)
+	if _, err := rand.Read(plain); err != nil {
+		t.Fatal(err)
+	}
+	var cipher bytes.Buffer
+	if _, err := s.EncryptStreamWithDigest(&cipher, bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	_, err := s.DecryptStreamWithDigest(&out, &cipher, sha256Hex([]byte("something else")))
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("err = %v, want ErrDigestMismatch", err)
+	}
+}
+
+func TestDecryptStreamTruncated(t *testing.T) {
+	s := newTestService(t)
+	plain := make([]byte, 2*streamChunkSize+5)
+	var cipher bytes.Buffer
+	if err := s.EncryptStream(&cipher, bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Drop the final chunk: the remaining ones still authenticate.
+	firstTwo := streamPrefixSize + 2*(4+streamChunkSize+s.gcm.Overhead())
+	var out bytes.Buffer
+	err := s.DecryptStream(&out, bytes.NewReader(cipher.Bytes()[:firstTwo]))
+	if !errors.Is(err, ErrStreamTruncated) {
+		t.Fatalf("err = %v, want ErrStreamTruncated", err)
+	}
+}
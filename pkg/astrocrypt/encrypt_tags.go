@@ -2,11 +2,147 @@
 package astrocrypt
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-// EncryptStruct encrypts all fields with `encrypt:"true"` tag
+// EncryptStruct encrypts all fields with `encrypt:"true"` tag.
+// Fields tagged `encrypt:"deterministic"` go through EncryptDeterministic
+// instead, so they stay searchable by exact match; that leaks which rows
+// share a value, so keep it to lookup columns.
+// Nested structs, pointers to structs, slices/arrays and maps of structs
+// are walked recursively.
+//
+// Add index=Sibling to also store the BlindIndex of the plaintext in a
+// sibling string field, to query on:
+//
+//	Email      string `encrypt:"true,index=EmailIndex"`
+//	EmailIndex string
+//
+// Non-string fields are encrypted into a sibling string field named by the
+// tag, and zeroed:
+//
+//	DOB    time.Time `encrypt:"into=DOBEnc"`
+//	DOBEnc string
+//
+// Supported types are []byte (base64), time.Time (RFC 3339), bool, ints,
+// uints and floats.
+//
+// Add aad=Sibling to bind the ciphertext to the value of another field, e.g.
+// the row ID, through EncryptWithContext; DecryptStruct then fails with
+// ErrDecryptionFailed for a value copied into a row with another ID:
+//
+//	ID  int64
+//	SSN string `encrypt:"true,aad=ID"`
+//
+// The sibling can be of any type into= supports, or a string, and must be
+// set before encrypting. Deterministic fields can't take aad=.
+//
+// EncryptStruct is all-or-nothing: when any field fails, v is left untouched.
 func (s *Service) EncryptStruct(v interface{}) error {
+	return walkStructWith(s.encryptWalker(context.Background()), v)
+}
+
+// EncryptStructCtx is EncryptStruct that stops between fields once ctx is
+// done and returns ctx.Err(), leaving v untouched.
+func (s *Service) EncryptStructCtx(ctx context.Context, v interface{}) error {
+	return walkStructWith(s.encryptWalker(ctx), v)
+}
+
+// DecryptStruct decrypts all fields with `encrypt:"true"` or
+// `encrypt:"deterministic"` tag.
+// Nested values are walked the same way as EncryptStruct. Fields tagged
+// `encrypt:"into=..."` are parsed back from their sibling; the sibling keeps
+// its ciphertext. Like EncryptStruct, it is all-or-nothing.
+func (s *Service) DecryptStruct(v interface{}) error {
+	return walkStructWith(s.decryptWalker(context.Background()), v)
+}
+
+// DecryptStructCtx is DecryptStruct that stops between fields once ctx is
+// done and returns ctx.Err(), leaving v untouched.
+func (s *Service) DecryptStructCtx(ctx context.Context, v interface{}) error {
+	return walkStructWith(s.decryptWalker(ctx), v)
+}
+
+// transformFunc encrypts or decrypts a single tagged string value.
+type transformFunc func(string) (string, error)
+
+// aadFunc is a transformFunc taking the associated data of an `aad=` field.
+type aadFunc func(value string, aad []byte) (string, error)
+
+// bind fixes the associated data of f.
+func (f aadFunc) bind(aad []byte) transformFunc {
+	return func(value string) (string, error) { return f(value, aad) }
+}
+
+// structWalker applies fn to every tagged string reachable from a struct.
+// Writes are queued in pending and only applied once the whole walk has
+// succeeded.
+type structWalker struct {
+	ctx     context.Context
+	fn      transformFunc
+	det     transformFunc    // `encrypt:"deterministic"` fields; nil → fn
+	index   transformFunc    // value → blind index for `index=` siblings; nil → left alone
+	aead    aadFunc          // fn for `aad=` fields; nil → aad= is an error
+	aadIdx  aadFunc          // index for `aad=` fields; nil → index
+	decrypt bool             // direction, for `into=` fields
+	rekey   bool             // fn re-encrypts ciphertexts, `into=` siblings included
+	dryRun  bool             // run walks and records changed but writes nothing
+	visited map[uintptr]bool // pointers already walked, guards against cycles
+	pending []pendingWrite
+	path    []pathElem // current position, only rendered for errors
+	changed []string   // rekey: paths of the fields rewritten by the last run
+}
+
+// pendingWrite is one deferred assignment: dst.SetMapIndex(key, val) when key
+// is set, dst.Set(val) when val is set, dst.SetString(str) otherwise.
+type pendingWrite struct {
+	dst reflect.Value
+	key reflect.Value
+	val reflect.Value
+	str string
+}
+
+// pathElem is one step of a field path: a field name, a map key or an index.
+type pathElem struct {
+	name  string
+	key   reflect.Value
+	index int
+}
+
+func newStructWalker(ctx context.Context, fn transformFunc, decrypt bool) *structWalker {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &structWalker{ctx: ctx, fn: fn, decrypt: decrypt}
+}
+
+// encryptWalker returns the EncryptStruct walker.
+func (s *Service) encryptWalker(ctx context.Context) *structWalker {
+	w := newStructWalker(ctx, s.Encrypt, false)
+	w.det = s.EncryptDeterministic
+	w.index = s.BlindIndex
+	w.aead = s.EncryptWithContext
+	return w
+}
+
+// decryptWalker returns the DecryptStruct walker.
+func (s *Service) decryptWalker(ctx context.Context) *structWalker {
+	w := newStructWalker(ctx, s.Decrypt, true)
+	w.aead = s.DecryptWithContext
+	return w
+}
+
+// walkStructWith runs w on v, a struct or pointer to a struct; other values
+// are ignored.
+func walkStructWith(w *structWalker, v interface{}) error {
 	val := reflect.ValueOf(v)
 	if val.Kind() == reflect.Ptr {
 		val = val.Elem()
@@ -16,79 +152,491 @@ func (s *Service) EncryptStruct(v interface{}) error {
 		return nil
 	}
 
-	typ := val.Type()
+	return w.run(val, pathElem{name: val.Type().Name()})
+}
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		typeField := typ.Field(i)
+// run walks root and applies the queued writes if nothing failed. The walker
+// can be reused for another root afterwards.
+func (w *structWalker) run(root reflect.Value, at ...pathElem) error {
+	w.pending = w.pending[:0]
+	w.path = append(w.path[:0], at...)
+	w.changed = w.changed[:0]
+	clear(w.visited)
 
-		tag := typeField.Tag.Get("encrypt")
-		if tag != "true" {
-			continue
+	if err := w.walk(root); err != nil {
+		return err
+	}
+	if w.dryRun {
+		return nil
+	}
+	for _, p := range w.pending {
+		switch {
+		case p.key.IsValid():
+			p.dst.SetMapIndex(p.key, p.val)
+		case p.val.IsValid():
+			p.dst.Set(p.val)
+		default:
+			p.dst.SetString(p.str)
+		}
+	}
+	return nil
+}
+
+// fieldPath renders the current position, e.g. "User.Addresses[2].Street".
+func (w *structWalker) fieldPath() string {
+	var b strings.Builder
+	for i, p := range w.path {
+		switch {
+		case p.key.IsValid():
+			fmt.Fprintf(&b, "[%v]", p.key)
+		case p.name != "":
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(p.name)
+		default:
+			fmt.Fprintf(&b, "[%d]", p.index)
 		}
+	}
+	return b.String()
+}
 
-		if field.Kind() != reflect.String || !field.CanSet() {
-			continue
+// walk descends into val.
+func (w *structWalker) walk(val reflect.Value) error {
+	switch val.Kind() {
+
+	case reflect.Ptr:
+		if val.IsNil() || w.visited[val.Pointer()] {
+			return nil
+		}
+		if w.visited == nil {
+			w.visited = make(map[uintptr]bool)
 		}
+		w.visited[val.Pointer()] = true
+		return w.walk(val.Elem())
 
-		plaintext := field.String()
-		if plaintext == "" {
+	case reflect.Struct:
+		return w.walkFields(val)
+
+	case reflect.Slice, reflect.Array:
+		if !mayHoldTagged(val.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < val.Len(); i++ {
+			w.path = append(w.path, pathElem{index: i})
+			err := w.walk(val.Index(i))
+			w.path = w.path[:len(w.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if val.IsNil() || !mayHoldTagged(val.Type().Elem()) {
+			return nil
+		}
+		iter := val.MapRange()
+		for iter.Next() {
+			// Map values are not addressable: work on a copy and store it back.
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			w.path = append(w.path, pathElem{key: iter.Key()})
+			err := w.walk(elem)
+			w.path = w.path[:len(w.path)-1]
+			if err != nil {
+				return err
+			}
+			w.pending = append(w.pending, pendingWrite{dst: val, key: iter.Key(), val: elem})
+		}
+	}
+
+	return nil
+}
+
+func (w *structWalker) walkFields(val reflect.Value) error {
+	for _, meta := range structFields(val.Type()) {
+		field := val.Field(meta.index)
+		if !field.CanSet() {
 			continue
 		}
 
-		encrypted, err := s.Encrypt(plaintext)
+		w.path = append(w.path, pathElem{name: meta.name})
+		err := w.walkField(val, field, meta)
+		w.path = w.path[:len(w.path)-1]
 		if err != nil {
 			return err
 		}
-
-		field.SetString(encrypted)
 	}
 
 	return nil
 }
 
-// DecryptStruct decrypts all fields with `encrypt:"true"` tag
-func (s *Service) DecryptStruct(v interface{}) error {
-	val := reflect.ValueOf(v)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+func (w *structWalker) walkField(parent, field reflect.Value, meta fieldMeta) error {
+	if meta.tag == tagNone {
+		return w.walk(field)
 	}
 
-	if val.Kind() != reflect.Struct {
+	fn, index := w.fn, w.index
+	if meta.aad != "" {
+		aad, err := w.aadOf(parent, meta)
+		if err != nil {
+			return err
+		}
+		fn = w.aead.bind(aad)
+		if w.aadIdx != nil {
+			index = w.aadIdx.bind(aad)
+		}
+	}
+
+	if meta.tag == tagInto {
+		return w.transformInto(parent, field, meta.into, fn)
+	}
+	if meta.tag == tagDeterministic {
+		if field.Kind() != reflect.String {
+			return fmt.Errorf("%w: %s is %s, deterministic encryption (equality-leaking, for lookup columns) only supports strings", ErrUnsupportedField, w.fieldPath(), field.Kind())
+		}
+		if w.det != nil {
+			fn = w.det
+		}
+	}
+	if field.Kind() != reflect.String {
+		return fmt.Errorf("%w: %s is %s, use `encrypt:\"into=<string field>\"`", ErrUnsupportedField, w.fieldPath(), field.Kind())
+	}
+
+	if meta.blind != "" && index != nil {
+		if err := w.setIndex(parent, meta.blind, field.String(), index); err != nil {
+			return err
+		}
+	}
+
+	value := field.String()
+	if value == "" {
 		return nil
 	}
 
-	typ := val.Type()
+	result, err := w.apply(fn, value)
+	if err != nil {
+		return err
+	}
+
+	w.pending = append(w.pending, pendingWrite{dst: field, str: result})
+	if w.rekey {
+		w.changed = append(w.changed, w.fieldPath())
+	}
+	return nil
+}
+
+// =============================
+// Field metadata
+// =============================
+
+type fieldTag int
+
+const (
+	tagNone          fieldTag = iota // untagged, may hold tagged values deeper down
+	tagTrue                          // `encrypt:"true"`
+	tagInto                          // `encrypt:"into=Sibling"`
+	tagDeterministic                 // `encrypt:"deterministic"`
+)
+
+// fieldMeta is what the walkers need to know about one struct field.
+type fieldMeta struct {
+	index int
+	name  string
+	tag   fieldTag
+	into  string // sibling name for tagInto
+	blind string // sibling receiving the blind index, `index=` option
+	aad   string // sibling whose value is the associated data, `aad=` option
+}
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		typeField := typ.Field(i)
+// fieldCache maps a struct reflect.Type to its []fieldMeta, so tags are
+// parsed once per type rather than once per value.
+var fieldCache sync.Map
 
-		tag := typeField.Tag.Get("encrypt")
-		if tag != "true" {
+// structFields returns the fields of t the walkers must visit: tagged
+// fields, and untagged fields that can contain tagged ones.
+func structFields(t reflect.Type) []fieldMeta {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]fieldMeta)
+	}
+
+	var fields []fieldMeta
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
 			continue
 		}
-
-		if field.Kind() != reflect.String || !field.CanSet() {
+		meta := fieldMeta{index: i, name: sf.Name}
+		tag, opts, _ := strings.Cut(sf.Tag.Get("encrypt"), ",")
+		for _, opt := range strings.Split(opts, ",") {
+			if index, ok := strings.CutPrefix(opt, "index="); ok {
+				meta.blind = index
+			}
+			if aad, ok := strings.CutPrefix(opt, "aad="); ok {
+				meta.aad = aad
+			}
+		}
+		if into, ok := strings.CutPrefix(tag, "into="); ok {
+			meta.tag, meta.into = tagInto, into
+		} else if tag == "true" {
+			meta.tag = tagTrue
+		} else if tag == "deterministic" {
+			meta.tag = tagDeterministic
+		} else if !mayHoldTagged(sf.Type) {
 			continue
 		}
+		fields = append(fields, meta)
+	}
 
-		ciphertext := field.String()
+	cached, _ := fieldCache.LoadOrStore(t, fields)
+	return cached.([]fieldMeta)
+}
+
+// apply runs fn on value and names the current field in size errors. It fails with
+// ctx.Err() once the context is done.
+func (w *structWalker) apply(fn transformFunc, value string) (string, error) {
+	if err := w.ctx.Err(); err != nil {
+		return "", err
+	}
+	result, err := fn(value)
+	if err != nil {
+		var sizeErr *SizeLimitError
+		if errors.As(err, &sizeErr) {
+			sizeErr.Field = w.fieldPath()
+		}
+		return "", err
+	}
+	return result, nil
+}
+
+// setIndex queues the blind index of value, computed by index, into the
+// sibling field name. value is the field as found: plaintext on encrypt,
+// ciphertext when re-keying, matching how index was built.
+func (w *structWalker) setIndex(parent reflect.Value, name, value string, index transformFunc) error {
+	target := parent.FieldByName(name)
+	if !target.IsValid() || !target.CanSet() || target.Kind() != reflect.String {
+		return fmt.Errorf("%w: %s: index=%s must name an exported string field", ErrUnsupportedField, w.fieldPath(), name)
+	}
+
+	idx := ""
+	if value != "" {
+		var err error
+		if idx, err = w.apply(index, value); err != nil {
+			return err
+		}
+	}
+	if idx == target.String() {
+		return nil
+	}
+	w.pending = append(w.pending, pendingWrite{dst: target, str: idx})
+	if w.rekey {
+		last := w.path[len(w.path)-1]
+		w.path[len(w.path)-1] = pathElem{name: name}
+		w.changed = append(w.changed, w.fieldPath())
+		w.path[len(w.path)-1] = last
+	}
+	return nil
+}
+
+// transformInto handles a field tagged `encrypt:"into=name"`: on encrypt the
+// field is serialised, encrypted into the sibling string field with fn and
+// zeroed; on decrypt the sibling is decrypted and parsed back into the field.
+func (w *structWalker) transformInto(parent, field reflect.Value, into string, fn transformFunc) error {
+	target := parent.FieldByName(into)
+	if !target.IsValid() || !target.CanSet() || target.Kind() != reflect.String {
+		return fmt.Errorf("%w: %s: into=%s must name an exported string field", ErrUnsupportedField, w.fieldPath(), into)
+	}
+
+	// Re-keying only swaps the sibling's ciphertext; the typed field stays
+	// zero as EncryptStruct left it.
+	if w.rekey {
+		ciphertext := target.String()
 		if ciphertext == "" {
-			continue
+			return nil
+		}
+		result, err := w.apply(fn, ciphertext)
+		if err != nil {
+			return err
 		}
+		w.pending = append(w.pending, pendingWrite{dst: target, str: result})
+		// Report the sibling, which is the field that actually changes.
+		last := w.path[len(w.path)-1]
+		w.path[len(w.path)-1] = pathElem{name: into}
+		w.changed = append(w.changed, w.fieldPath())
+		w.path[len(w.path)-1] = last
+		return nil
+	}
 
-		decrypted, err := s.Decrypt(ciphertext)
+	if w.decrypt {
+		ciphertext := target.String()
+		if ciphertext == "" {
+			return nil
+		}
+		plain, err := w.apply(fn, ciphertext)
 		if err != nil {
 			return err
 		}
+		parsed := reflect.New(field.Type()).Elem()
+		if err := parseEnvelope(parsed, w.fieldPath(), plain); err != nil {
+			return err
+		}
+		w.pending = append(w.pending, pendingWrite{dst: field, val: parsed})
+		return nil
+	}
 
-		field.SetString(decrypted)
+	plain, err := formatEnvelope(field, w.fieldPath())
+	if err != nil {
+		return err
+	}
+	// Empty strings, nil slices and zero times have nothing to protect;
+	// false and 0 are real values and are encrypted.
+	if field.IsZero() && field.Kind() != reflect.Bool && !isNumberKind(field.Kind()) {
+		w.pending = append(w.pending, pendingWrite{dst: target, str: ""})
+		return nil
+	}
+	result, err := w.apply(fn, plain)
+	if err != nil {
+		return err
+	}
+	w.pending = append(w.pending,
+		pendingWrite{dst: target, str: result},
+		pendingWrite{dst: field, val: reflect.Zero(field.Type())},
+	)
+	return nil
+}
+
+// aadOf returns the associated data of a field tagged aad=: its sibling
+// serialised like an into= value.
+func (w *structWalker) aadOf(parent reflect.Value, meta fieldMeta) ([]byte, error) {
+	if meta.tag == tagDeterministic {
+		return nil, fmt.Errorf("%w: %s: deterministic fields can't take aad=", ErrUnsupportedField, w.fieldPath())
+	}
+	if w.aead == nil {
+		return nil, fmt.Errorf("%w: %s: aad= is not supported by this operation", ErrUnsupportedField, w.fieldPath())
+	}
+	sibling := parent.FieldByName(meta.aad)
+	if !sibling.IsValid() || !sibling.CanInterface() {
+		return nil, fmt.Errorf("%w: %s: aad=%s must name an exported field", ErrUnsupportedField, w.fieldPath(), meta.aad)
+	}
+	aad, err := formatEnvelope(sibling, w.fieldPath()+": aad="+meta.aad)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(aad), nil
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// formatEnvelope serialises a typed field into the plaintext that gets encrypted.
+func formatEnvelope(field reflect.Value, fieldPath string) (string, error) {
+	switch {
+	case field.Type() == timeType:
+		return field.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	case field.Type() == bytesType:
+		return base64.StdEncoding.EncodeToString(field.Bytes()), nil
 	}
 
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, field.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("%w: %s is %s", ErrUnsupportedField, fieldPath, field.Type())
+}
+
+// parseEnvelope reverses formatEnvelope. Errors never include the plaintext.
+func parseEnvelope(field reflect.Value, fieldPath, plain string) error {
+	fail := func(err error) error {
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+		return fmt.Errorf("%w: %s is %s: %v", ErrInvalidEnvelope, fieldPath, field.Type(), err)
+	}
+
+	switch {
+	case field.Type() == timeType:
+		t, err := time.Parse(time.RFC3339Nano, plain)
+		if err != nil {
+			return fail(errors.New("not an RFC 3339 time"))
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case field.Type() == bytesType:
+		b, err := base64.StdEncoding.DecodeString(plain)
+		if err != nil {
+			return fail(errors.New("not base64"))
+		}
+		field.SetBytes(b)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(plain)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(plain)
+		if err != nil {
+			return fail(err)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(plain, 10, field.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(plain, 10, field.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(plain, field.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("%w: %s is %s", ErrUnsupportedField, fieldPath, field.Type())
+	}
 	return nil
 }
 
+// mayHoldTagged reports whether values of type t can contain struct fields,
+// so slices and maps of plain values are not iterated for nothing.
+func mayHoldTagged(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return mayHoldTagged(t.Elem())
+	default:
+		return false
+	}
+}
+
 // EncryptFields encrypts specific fields by name
 func (s *Service) EncryptFields(v interface{}, fieldNames ...string) error {
 	val := reflect.ValueOf(v)
@@ -0,0 +1,189 @@
+package astrocrypt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// newTestService returns a Service with a fixed 32-byte key.
+func newTestService(t testing.TB) *Service {
+	t.Helper()
+	s, err := NewService([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+type testCard struct {
+	Number string `encrypt:"true"`
+	Label  string
+}
+
+type testAddress struct {
+	Street string `encrypt:"true"`
+	Card   *testCard
+}
+
+type testProfile struct {
+	Phone   string `encrypt:"true"`
+	Address testAddress
+}
+
+type testUser struct {
+	Name    string
+	Email   string `encrypt:"true"`
+	Profile testProfile
+	Backup  *testProfile
+	Cards   []testCard
+	ByName  map[string]testCard
+}
+
+func TestEncryptStructNested(t *testing.T) {
+	s := newTestService(t)
+	u := testUser{
+		Name:  "ada",
+		Email: "ada@example.com",
+		Profile: testProfile{
+			Phone: "555-0100",
+			Address: testAddress{
+				Street: "1 Main St",
+				Card:   &testCard{Number: "4111", Label: "visa"},
+			},
+		},
+		ByName: map[string]testCard{"home": {Number: "5500"}},
+	}
+
+	if err := s.EncryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := map[string]string{
+		"Email":                       u.Email,
+		"Profile.Phone":               u.Profile.Phone,
+		"Profile.Address.Street":      u.Profile.Address.Street,
+		"Profile.Address.Card.Number": u.Profile.Address.Card.Number,
+		"ByName[home].Number":         u.ByName["home"].Number,
+	}
+	plain := map[string]string{
+		"Email":                       "ada@example.com",
+		"Profile.Phone":               "555-0100",
+		"Profile.Address.Street":      "1 Main St",
+		"Profile.Address.Card.Number": "4111",
+		"ByName[home].Number":         "5500",
+	}
+	for path, got := range encrypted {
+		if got == plain[path] {
+			t.Errorf("%s left in plaintext", path)
+		}
+	}
+	if u.Name != "ada" || u.Profile.Address.Card.Label != "visa" {
+		t.Error("untagged fields were modified")
+	}
+
+	if err := s.DecryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	decrypted := map[string]string{
+		"Email":                       u.Email,
+		"Profile.Phone":               u.Profile.Phone,
+		"Profile.Address.Street":      u.Profile.Address.Street,
+		"Profile.Address.Card.Number": u.Profile.Address.Card.Number,
+		"ByName[home].Number":         u.ByName["home"].Number,
+	}
+	for path, got := range decrypted {
+		if got != plain[path] {
+			t.Errorf("%s = %q after round-trip, want %q", path, got, plain[path])
+		}
+	}
+}
+
+func TestEncryptStructNilPointer(t *testing.T) {
+	s := newTestService(t)
+	u := testUser{Email: "ada@example.com", Backup: nil}
+	u.Profile.Address.Card = nil
+
+	if err := s.EncryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Backup != nil || u.Profile.Address.Card != nil {
+		t.Error("nil pointers were allocated")
+	}
+	if err := s.DecryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Email != "ada@example.com" {
+		t.Errorf("Email = %q after round-trip", u.Email)
+	}
+}
+
+func TestEncryptStructLargeSlice(t *testing.T) {
+	s := newTestService(t)
+	cards := make([]testCard, 100)
+	for i := range cards {
+		cards[i] = testCard{Number: fmt.Sprintf("card-%03d", i)}
+	}
+	u := testUser{Cards: cards}
+
+	if err := s.EncryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range u.Cards {
+		if c.Number == fmt.Sprintf("card-%03d", i) {
+			t.Fatalf("Cards[%d] left in plaintext", i)
+		}
+	}
+	if err := s.DecryptStruct(&u); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range u.Cards {
+		if want := fmt.Sprintf("card-%03d", i); c.Number != want {
+			t.Fatalf("Cards[%d] = %q after round-trip, want %q", i, c.Number, want)
+		}
+	}
+}
+
+func TestEncryptStructSharedBackingArray(t *testing.T) {
+	s := newTestService(t)
+	type pair struct {
+		A []testCard
+		B []testCard
+	}
+	cards := make([]testCard, 100)
+	for i := range cards {
+		cards[i] = testCard{Number: fmt.Sprintf("card-%03d", i)}
+	}
+	// A and B alias the same elements; the round-trip must still restore them.
+	p := pair{A: cards, B: cards[:50]}
+
+	if err := s.EncryptStruct(&p); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DecryptStruct(&p); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range p.A {
+		if want := fmt.Sprintf("card-%03d", i); c.Number != want {
+			t.Fatalf("A[%d] = %q after round-trip, want %q", i, c.Number, want)
+		}
+	}
+}
+
+func TestEncryptStructNestedUnsupportedField(t *testing.T) {
+	s := newTestService(t)
+	type inner struct {
+		Age int `encrypt:"true"`
+	}
+	type outer struct {
+		Items []inner
+	}
+	v := outer{Items: []inner{{Age: 1}, {Age: 2}}}
+
+	err := s.EncryptStruct(&v)
+	if !errors.Is(err, ErrUnsupportedField) {
+		t.Fatalf("err = %v, want ErrUnsupportedField", err)
+	}
+	if v.Items[0].Age != 1 || v.Items[1].Age != 2 {
+		t.Error("v was modified by a failed EncryptStruct")
+	}
+}
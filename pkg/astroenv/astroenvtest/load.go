@@ -0,0 +1,26 @@
+// ================ Version : V1.1.0 ===========
+package astroenvtest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astroenv"
+)
+
+// Load fills cfg from vars only, ignoring the process environment and any
+// .env file, and fails the test on error. Because nothing global is read or
+// written it is safe to call from tests using t.Parallel (each test must pass
+// its own cfg). cfg is reset to its zero value when the test ends.
+func Load(t testing.TB, cfg interface{}, vars map[string]string) {
+	t.Helper()
+
+	if err := astroenv.NewLoaderFromMap(vars).Load(cfg); err != nil {
+		t.Fatalf("astroenvtest.Load: %v", err)
+	}
+
+	t.Cleanup(func() {
+		v := reflect.ValueOf(cfg).Elem()
+		v.Set(reflect.Zero(v.Type()))
+	})
+}
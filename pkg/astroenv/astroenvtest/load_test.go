@@ -0,0 +1,70 @@
+package astroenvtest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astroenv/astroenvtest"
+)
+
+type serverConfig struct {
+	Host    string        `env:"SERVER_HOST"`
+	Port    int           `env:"SERVER_PORT,8080"`
+	Timeout time.Duration `env:"SERVER_TIMEOUT,5s"`
+	Tags    []string      `env:"SERVER_TAGS,"`
+	DB      struct {
+		Name string `env:"DB_NAME,app"`
+	}
+}
+
+// TestLoadParallelIsolation loads the same keys with conflicting values from
+// parallel tests, over and over, each into its own struct: a test only ever
+// sees its own map, never the process environment or another test's values.
+func TestLoadParallelIsolation(t *testing.T) {
+	t.Setenv("SERVER_HOST", "from-process")
+	t.Setenv("SERVER_PORT", "1")
+
+	for i := 0; i < 8; i++ {
+		i := i
+		t.Run(fmt.Sprintf("loader%d", i), func(t *testing.T) {
+			t.Parallel()
+
+			host := fmt.Sprintf("host-%d", i)
+			port := 9000 + i
+			vars := map[string]string{
+				"SERVER_HOST":    host,
+				"SERVER_PORT":    fmt.Sprint(port),
+				"SERVER_TIMEOUT": fmt.Sprintf("%ds", i+1),
+				"SERVER_TAGS":    fmt.Sprintf("a%d,b%d", i, i),
+				"DB_NAME":        fmt.Sprintf("db%d", i),
+			}
+			if i%2 == 1 {
+				delete(vars, "SERVER_PORT") // falls back to the default
+				port = 8080
+			}
+
+			for n := 0; n < 200; n++ {
+				var cfg serverConfig
+				astroenvtest.Load(t, &cfg, vars)
+				if cfg.Host != host || cfg.Port != port || cfg.Timeout != time.Duration(i+1)*time.Second ||
+					len(cfg.Tags) != 2 || cfg.Tags[0] != fmt.Sprintf("a%d", i) || cfg.DB.Name != fmt.Sprintf("db%d", i) {
+					t.Fatalf("load %d: got %+v, want host %s port %d", n, cfg, host, port)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadResetsOnCleanup(t *testing.T) {
+	var cfg serverConfig
+	t.Run("load", func(t *testing.T) {
+		astroenvtest.Load(t, &cfg, map[string]string{"SERVER_HOST": "h"})
+		if cfg.Host != "h" || cfg.Port != 8080 {
+			t.Fatalf("got %+v", cfg)
+		}
+	})
+	if cfg.Host != "" || cfg.Port != 0 || cfg.DB.Name != "" {
+		t.Errorf("cfg = %+v after the test ended, want the zero value", cfg)
+	}
+}
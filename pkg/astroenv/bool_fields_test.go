@@ -0,0 +1,67 @@
+package astroenv
+
+import "testing"
+
+type boolConfig struct {
+	Plain     bool `env:"FEATURE_PLAIN"`
+	TagOn     bool `env:"FEATURE_TAG,true"`
+	DefaultOn bool `env:"FEATURE_DEFAULT" default:"true"`
+	TagOff    bool `env:"FEATURE_OFF,false"`
+}
+
+func TestBoolFieldDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		preset boolConfig
+		vars   map[string]string
+		want   boolConfig
+	}{
+		{
+			name: "unset",
+			want: boolConfig{TagOn: true, DefaultOn: true},
+		},
+		{
+			name:   "unset keeps the preset value",
+			preset: boolConfig{Plain: true},
+			want:   boolConfig{Plain: true, TagOn: true, DefaultOn: true},
+		},
+		{
+			name: "explicit false overrides a true default",
+			vars: map[string]string{"FEATURE_TAG": "false", "FEATURE_DEFAULT": "false"},
+			want: boolConfig{},
+		},
+		{
+			name:   "explicit false overrides the preset value",
+			preset: boolConfig{Plain: true},
+			vars:   map[string]string{"FEATURE_PLAIN": "false"},
+			want:   boolConfig{TagOn: true, DefaultOn: true},
+		},
+		{
+			name: "explicit true overrides a false default",
+			vars: map[string]string{"FEATURE_PLAIN": "true", "FEATURE_OFF": "1"},
+			want: boolConfig{Plain: true, TagOn: true, DefaultOn: true, TagOff: true},
+		},
+		{
+			name: "empty is unset",
+			vars: map[string]string{"FEATURE_PLAIN": "", "FEATURE_DEFAULT": ""},
+			want: boolConfig{TagOn: true, DefaultOn: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.preset
+			if err := NewLoaderFromMap(tc.vars).Load(&cfg); err != nil {
+				t.Fatal(err)
+			}
+			if cfg != tc.want {
+				t.Errorf("got %+v, want %+v", cfg, tc.want)
+			}
+		})
+	}
+}
+
+func TestBoolFieldInvalid(t *testing.T) {
+	var cfg boolConfig
+	if err := NewLoaderFromMap(map[string]string{"FEATURE_PLAIN": "maybe"}).Load(&cfg); err == nil {
+		t.Error("FEATURE_PLAIN=maybe loaded without error")
+	}
+}
@@ -0,0 +1,34 @@
+// ================ Version : V1.1.0 ===========
+// Command astroenvgen writes a reflection-free loader for a config struct,
+// see astroenv.GenerateLoader. Meant for go:generate:
+//
+//	//go:generate go run github.com/Asteroidea-tn/asterogo/pkg/astroenv/cmd/astroenvgen -type Config -out config_env.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astroenv"
+)
+
+func main() {
+	typeName := flag.String("type", "", "config struct type name (required)")
+	out := flag.String("out", "", "output file; empty → <type in lower case>_env.go")
+	dir := flag.String("dir", ".", "directory of the package declaring the type")
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		*out = strings.ToLower(*typeName) + "_env.go"
+	}
+	if err := astroenv.GenerateLoader(*dir, *typeName, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "astroenvgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,41 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Dump returns the effective configuration held in cfg (a *ConfigSnapshot
+// or a pointer to a struct loaded with LoadEnvVarible) as one line per `env`
+// field, in struct order, for a startup log:
+//
+//	Server.Port  = 8080  (env PORT)
+//	DB.Password  = ****  (default DB_PASSWORD)
+//
+// The source is only known from a ConfigSnapshot's report; lines of a plain
+// struct name the key alone.
+//
+// Secrets are masked the same way ConfigHandler masks them: SecretString
+// fields, fields tagged `secret:"true"` and keys that look like credentials.
+func Dump(cfg interface{}) (string, error) {
+	entries, err := configEntries("Dump", cfg)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		if e.Source == "" {
+			fmt.Fprintf(tw, "%s\t= %s\t(%s)\n", e.Field, e.Value, e.Key)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t= %s\t(%s %s)\n", e.Field, e.Value, e.Source, e.Key)
+	}
+	if err := tw.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,250 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maskedValue replaces secret values in every config dump.
+const maskedValue = "****"
+
+// secretKeyHints mark an env key as secret when it contains one of them.
+var secretKeyHints = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "API_KEY", "PRIVATE_KEY"}
+
+// ConfigEntry is one resolved config value, as exposed by ConfigHandler.
+type ConfigEntry struct {
+	Key    string `json:"key"`              // env variable name
+	Field  string `json:"field"`            // dotted Go field path
+	Value  string `json:"value"`            // current value, masked when secret
+	Source string `json:"source,omitempty"` // "env" or "default"; "" without a LoadReport
+	Masked bool   `json:"masked,omitempty"`
+}
+
+// ConfigSnapshot is a loaded config with the LoadReport of its Load, for
+// ConfigHandler and Dump to read while the application reloads it. Update
+// swaps both under a lock; the config handed in must not be changed
+// afterwards, so load each new version into a new struct.
+type ConfigSnapshot struct {
+	mu     sync.RWMutex
+	cfg    interface{}
+	report LoadReport
+}
+
+// NewConfigSnapshot returns a ConfigSnapshot of cfg (a pointer to a struct)
+// and the report its Load returned:
+//
+//	report, err := astroenv.LoadEnvVaribleWithReport(&cfg)
+//	snap := astroenv.NewConfigSnapshot(&cfg, report)
+//	http.Handle("/debug/config", astroenv.ConfigHandler(snap))
+func NewConfigSnapshot(cfg interface{}, report LoadReport) *ConfigSnapshot {
+	return &ConfigSnapshot{cfg: cfg, report: report}
+}
+
+// Update replaces the config and its report, e.g. after a reload.
+func (s *ConfigSnapshot) Update(cfg interface{}, report LoadReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg, s.report = cfg, report
+}
+
+// configEntries returns the entries of cfg, a *ConfigSnapshot or a pointer
+// to a struct, in struct order. Sources come from the snapshot's report and
+// are empty for a plain struct.
+func configEntries(caller string, cfg interface{}) ([]ConfigEntry, error) {
+	var sources map[string]string // Go field path → source
+	if snap, ok := cfg.(*ConfigSnapshot); ok {
+		snap.mu.RLock()
+		defer snap.mu.RUnlock()
+		cfg = snap.cfg
+		sources = make(map[string]string, len(snap.report.Fields))
+		for _, f := range snap.report.Fields {
+			sources[f.Field] = f.Source
+		}
+	}
+
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s: expected a pointer to a struct or a *ConfigSnapshot, got %T", caller, cfg)
+	}
+	return collectEntries(v.Elem(), "", sources), nil
+}
+
+// ConfigHandler serves the effective configuration held in cfg, a
+// *ConfigSnapshot or a pointer to a struct loaded with LoadEnvVarible.
+// Secret values are masked, and with a ConfigSnapshot every entry says where
+// its value came from, as recorded by the Load.
+//
+//	GET /debug/config             → JSON array, sorted by key
+//	GET /debug/config?format=env  → KEY=value lines, sorted by key
+//
+// When allowedPrefixes is not empty only keys starting with one of them are
+// exposed. cfg is read on every request: a plain struct must not change
+// while the handler runs, use a ConfigSnapshot for configs reloaded at
+// runtime.
+func ConfigHandler(cfg interface{}, allowedPrefixes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		entries, err := configEntries("ConfigHandler", cfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		entries = filterEntries(entries, allowedPrefixes)
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Key < entries[j].Key
+		})
+
+		if r.URL.Query().Get("format") == "env" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s=%s\n", e.Key, e.Value)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(entries)
+	})
+}
+
+// collectEntries walks the struct the same way parseStruct does and returns
+// one entry per `env` tagged field, and one per element of prefix maps.
+// sources maps Go field paths to their LoadReport source; nil → unknown.
+func collectEntries(v reflect.Value, prefix string, sources map[string]string) []ConfigEntry {
+	var entries []ConfigEntry
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		fieldType := t.Field(i)
+		path := prefix + fieldType.Name
+
+		if field.Kind() == reflect.Struct && field.Type() != secretStringType {
+			entries = append(entries, collectEntries(field, path+".", sources)...)
+			continue
+		}
+		if isStructPtr(field.Type()) {
+			if !field.IsNil() {
+				entries = append(entries, collectEntries(field.Elem(), path+".", sources)...)
+			}
+			continue
+		}
+
+		tag := fieldType.Tag.Get("env")
+		if tag == "" || !field.CanInterface() {
+			continue
+		}
+
+		key, options, _ := parseTag(tag)
+		if field.Kind() == reflect.Map {
+			entries = append(entries, mapEntries(field, fieldType, path, key, options, sources)...)
+			continue
+		}
+
+		entry := ConfigEntry{
+			Key:    key,
+			Field:  path,
+			Value:  fmt.Sprint(field.Interface()),
+			Source: sources[path],
+		}
+		switch {
+		case field.Kind() == reflect.Slice:
+			entry.Value = formatSlice(field, fieldType)
+		case field.Kind() == reflect.Ptr && field.IsNil():
+			entry.Value = ""
+		case field.Kind() == reflect.Ptr:
+			entry.Value = fmt.Sprint(field.Elem().Interface())
+		}
+		if isSecret(fieldType, key) {
+			entry.Value = maskedValue
+			entry.Masked = true
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// mapEntries returns one entry per element of a prefix map, sorted, each
+// masked on its own when its variable looks like a credential. Keys are
+// upper-cased back unless the tag has keepcase.
+func mapEntries(field reflect.Value, fieldType reflect.StructField, path, prefix, options string, sources map[string]string) []ConfigEntry {
+	iter := field.MapRange()
+	var entries []ConfigEntry
+	for iter.Next() {
+		k := iter.Key().String()
+		key := prefix + strings.ToUpper(k)
+		if strings.Contains(options, "keepcase") {
+			key = prefix + k
+		}
+		entry := ConfigEntry{
+			Key:   key,
+			Field: path + "[" + k + "]",
+			Value: iter.Value().String(),
+		}
+		if sources != nil {
+			entry.Source = sourceEnv // prefix maps only hold variables
+		}
+		if isSecret(fieldType, key) {
+			entry.Value = maskedValue
+			entry.Masked = true
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Field < entries[j].Field })
+	return entries
+}
+
+// isSecret reports whether a field must be masked: a SecretString, a slice
+// of them or a pointer to one, tagged `secret:"true"` or with a key that
+// looks like a credential.
+func isSecret(fieldType reflect.StructField, key string) bool {
+	if fieldType.Type == secretStringType || fieldType.Tag.Get("secret") == "true" {
+		return true
+	}
+	if k := fieldType.Type.Kind(); (k == reflect.Slice || k == reflect.Ptr) && fieldType.Type.Elem() == secretStringType {
+		return true
+	}
+	return secretKey(key)
+}
+
+// secretKey reports whether key looks like a credential.
+func secretKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, hint := range secretKeyHints {
+		if strings.Contains(upper, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterEntries keeps only entries whose key starts with one of prefixes.
+// An empty prefix list keeps everything.
+func filterEntries(entries []ConfigEntry, prefixes []string) []ConfigEntry {
+	if len(prefixes) == 0 {
+		return entries
+	}
+	var kept []ConfigEntry
+	for _, e := range entries {
+		for _, p := range prefixes {
+			if strings.HasPrefix(e.Key, p) {
+				kept = append(kept, e)
+				break
+			}
+		}
+	}
+	return kept
+}
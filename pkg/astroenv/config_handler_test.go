@@ -0,0 +1,195 @@
+package astroenv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type handlerDB struct {
+	Host     string       `env:"SRV_DB_HOST,localhost"`
+	Password SecretString `env:"SRV_DB_PASSWORD"`
+}
+
+type handlerConfig struct {
+	Port   int               `env:"SRV_PORT,8080"`
+	Name   string            `env:"SRV_NAME"`
+	PIN    string            `env:"SRV_PIN,0000" secret:"true"`
+	Labels map[string]string `env:"SRV_LABEL_,prefix"`
+	DB     handlerDB
+	Other  string `env:"OTHER_KEY,x"`
+}
+
+var handlerVars = map[string]string{
+	"SRV_NAME":            "edge",
+	"SRV_DB_PASSWORD":     "hunter2",
+	"SRV_LABEL_TEAM":      "video",
+	"SRV_LABEL_API_TOKEN": "tok-123",
+}
+
+// loadSnapshot loads vars through a Loader and wraps the result with its
+// report.
+func loadSnapshot(t *testing.T, vars map[string]string) *ConfigSnapshot {
+	t.Helper()
+	var cfg handlerConfig
+	report, err := NewLoaderFromMap(vars).LoadWithReport(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewConfigSnapshot(&cfg, report)
+}
+
+// get serves target through h and returns the recorded response.
+func get(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", target, rec.Code, rec.Body)
+	}
+	return rec
+}
+
+func TestConfigHandlerJSON(t *testing.T) {
+	rec := get(t, ConfigHandler(loadSnapshot(t, handlerVars)), "/debug/config")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var entries []ConfigEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ConfigEntry{
+		{Key: "OTHER_KEY", Field: "Other", Value: "x", Source: sourceDefault},
+		{Key: "SRV_DB_HOST", Field: "DB.Host", Value: "localhost", Source: sourceDefault},
+		{Key: "SRV_DB_PASSWORD", Field: "DB.Password", Value: maskedValue, Source: sourceEnv, Masked: true},
+		{Key: "SRV_LABEL_API_TOKEN", Field: "Labels[api_token]", Value: maskedValue, Source: sourceEnv, Masked: true},
+		{Key: "SRV_LABEL_TEAM", Field: "Labels[team]", Value: "video", Source: sourceEnv},
+		{Key: "SRV_NAME", Field: "Name", Value: "edge", Source: sourceEnv},
+		{Key: "SRV_PIN", Field: "PIN", Value: maskedValue, Source: sourceDefault, Masked: true},
+		{Key: "SRV_PORT", Field: "Port", Value: "8080", Source: sourceDefault},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestConfigHandlerEnvFormat(t *testing.T) {
+	rec := get(t, ConfigHandler(loadSnapshot(t, handlerVars), "SRV_DB_", "SRV_P"), "/debug/config?format=env")
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	want := "SRV_DB_HOST=localhost\nSRV_DB_PASSWORD=****\nSRV_PIN=****\nSRV_PORT=8080\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConfigHandlerMasksSecrets(t *testing.T) {
+	h := ConfigHandler(loadSnapshot(t, handlerVars))
+	for _, target := range []string{"/debug/config", "/debug/config?format=env"} {
+		body := get(t, h, target).Body.String()
+		for _, secret := range []string{"hunter2", "tok-123", "0000"} {
+			if strings.Contains(body, secret) {
+				t.Errorf("%s leaks %q:\n%s", target, secret, body)
+			}
+		}
+	}
+}
+
+func TestConfigHandlerSourcesFromReport(t *testing.T) {
+	// SRV_PORT is set in the process environment, not in the Loader's map:
+	// the value came from the default.
+	t.Setenv("SRV_PORT", "9999")
+	var entries []ConfigEntry
+	if err := json.Unmarshal(get(t, ConfigHandler(loadSnapshot(t, handlerVars), "SRV_PORT"), "/debug/config").Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Value != "8080" || entries[0].Source != sourceDefault {
+		t.Errorf("entries = %+v, want SRV_PORT=8080 from its default", entries)
+	}
+
+	// A plain struct has no report, so no source.
+	var cfg handlerConfig
+	if err := NewLoaderFromMap(handlerVars).Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	var plain []ConfigEntry
+	if err := json.Unmarshal(get(t, ConfigHandler(&cfg, "SRV_PORT"), "/debug/config").Body.Bytes(), &plain); err != nil {
+		t.Fatal(err)
+	}
+	if len(plain) != 1 || plain[0].Source != "" {
+		t.Errorf("entries = %+v, want one without a source", plain)
+	}
+}
+
+func TestConfigHandlerRejects(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ConfigHandler(loadSnapshot(t, handlerVars)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/config", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	ConfigHandler(handlerConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("struct value: status %d", rec.Code)
+	}
+}
+
+func TestConfigSnapshotConcurrentUpdate(t *testing.T) {
+	snap := loadSnapshot(t, handlerVars)
+	h := ConfigHandler(snap)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config?format=env", nil))
+				if body := rec.Body.String(); !strings.Contains(body, "SRV_NAME=edge") && !strings.Contains(body, "SRV_NAME=core") {
+					t.Errorf("unexpected body:\n%s", body)
+				}
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		name := "edge"
+		if j%2 == 0 {
+			name = "core"
+		}
+		var cfg handlerConfig
+		report, err := NewLoaderFromMap(map[string]string{"SRV_NAME": name, "SRV_DB_PASSWORD": "x"}).LoadWithReport(&cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap.Update(&cfg, report)
+	}
+	wg.Wait()
+}
+
+func TestDumpSnapshot(t *testing.T) {
+	dump, err := Dump(loadSnapshot(t, handlerVars))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"(env SRV_NAME)", "(default SRV_PORT)", "(env SRV_LABEL_TEAM)"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump lacks %q:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, "hunter2") {
+		t.Errorf("dump leaks the password:\n%s", dump)
+	}
+}
@@ -0,0 +1,95 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrocrypt"
+)
+
+// ExportEncryptedConfig serializes cfg (a pointer to a struct loaded with
+// LoadEnvVarible) and encrypts it with svc. The snapshot is a JSON object of
+// env key → raw value, secrets included in clear before encryption, so it
+// can be restored with ImportEncryptedConfig exactly as if the variables had
+// been set.
+func ExportEncryptedConfig(cfg interface{}, svc *astrocrypt.Service) (string, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return "", fmt.Errorf("ExportEncryptedConfig: expected a pointer to a struct, got %T", cfg)
+	}
+
+	vars := make(map[string]string)
+	collectVars(v.Elem(), vars)
+
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return "", err
+	}
+	return svc.Encrypt(string(data))
+}
+
+// ImportEncryptedConfig decrypts a snapshot made by ExportEncryptedConfig and
+// loads it into cfg. Only the snapshot is used; the process environment is
+// ignored, and keys missing from the snapshot fall back to their defaults.
+func ImportEncryptedConfig(snapshot string, svc *astrocrypt.Service, cfg interface{}) error {
+	data, err := svc.Decrypt(snapshot)
+	if err != nil {
+		return err
+	}
+
+	var vars map[string]string
+	if err := json.Unmarshal([]byte(data), &vars); err != nil {
+		return fmt.Errorf("ImportEncryptedConfig: invalid snapshot: %w", err)
+	}
+	return NewLoaderFromMap(vars).Load(cfg)
+}
+
+// collectVars walks the struct the same way parseStruct does and stores the
+// raw value of every `env` tagged field in vars, in the form setField reads.
+func collectVars(v reflect.Value, vars map[string]string) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		fieldType := t.Field(i)
+
+		if field.Kind() == reflect.Struct && field.Type() != secretStringType {
+			collectVars(field, vars)
+			continue
+		}
+		if isStructPtr(field.Type()) {
+			if !field.IsNil() {
+				collectVars(field.Elem(), vars)
+			}
+			continue
+		}
+
+		tag := fieldType.Tag.Get("env")
+		if tag == "" || !field.CanInterface() {
+			continue
+		}
+		key, _, _ := parseTag(tag)
+
+		switch {
+		case field.Type() == secretStringType:
+			vars[key] = field.Interface().(SecretString).Value()
+		case field.Kind() == reflect.Slice:
+			vars[key] = formatSlice(field, fieldType)
+		case field.Kind() == reflect.Ptr:
+			// nil stays unset, so it loads back as nil (or the default).
+			if !field.IsNil() {
+				vars[key] = fmt.Sprint(field.Elem().Interface())
+			}
+		case field.Kind() == reflect.Map:
+			// key is the prefix; entries go back under prefix + map key.
+			iter := field.MapRange()
+			for iter.Next() {
+				vars[key+iter.Key().String()] = iter.Value().String()
+			}
+		default:
+			vars[key] = fmt.Sprint(field.Interface())
+		}
+	}
+}
@@ -2,14 +2,17 @@
 package astroenv
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
 	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
 )
 
 // LoadEnv reads environment variables into a struct using `env` tags.
@@ -18,27 +21,243 @@ import (
 //
 //	`env:"ENV_KEY"`           → required, error if missing
 //	`env:"ENV_KEY,default"`   → optional, uses default if missing
+//	`env:"ENV_KEY" default:"x"` → same as above, default in its own tag
 //
-// Supported types: string, int, bool, float64
-// Supports nested structs.
+// map[string]string fields take a prefix instead of a key:
+//
+//	`env:"LABEL_,prefix"`     → every LABEL_* var, prefix stripped, keys lowercased
+//
+// The map is built from the Loader's own variables only: the Lookup source
+// of NewLoaderWithLookup is never asked, as it can't list its keys. A var
+// also read by a tagged field, e.g. LABEL_HOST next to `env:"LABEL_HOST"`,
+// is in the map too.
+//
+// Bool fields are never required: with no default they keep their current
+// value (false, or whatever was set before loading) when the var is unset.
+//
+// A field can be required only when another variable has a given value:
+//
+//	`env:"TLS_CERT,required_if=TLS_ENABLED=true"` → required when TLS_ENABLED is true
+//
+// or only when another variable is empty, for "one of these" pairs:
+//
+//	`env:"API_KEY,required_unless=API_KEY_FILE"` → required when API_KEY_FILE is empty
+//
+// Conditions are checked once every field is loaded, against the value the
+// other key resolved to (env or default), case-insensitively.
+//
+// After loading, a cfg implementing Validator has its Validate method called,
+// e.g. for "exactly one of S3_* or GCS_*" rules.
+//
+// Slice fields take a comma-separated list, each element trimmed and parsed
+// as the element type; another separator can be given in its own tag:
+//
+//	`env:"PORTS" envSeparator:";"` → PORTS=8080;8081
+//
+// Pointer fields (*string, *int, *bool, *float64, *time.Duration, ...) stay
+// nil when the var is unset and has no default, so nil means "not
+// configured" and a zero value means "set to zero". A *string is set even
+// when the var is set to "".
+//
+// Supported types: string, int, uint, bool, float64, time.Duration,
+// zerolog.Level, SecretString, slices of them and pointers to them. Supports
+// nested structs and struct pointers; a nil struct pointer is allocated
+// when its struct has at least one `env` tagged field.
 func LoadEnvVarible(cfg interface{}) error {
+	return LoadEnvFrom(cfg)
+}
+
+// LoadEnvFrom is LoadEnvVarible reading the given .env files instead of
+// ./.env, e.g. LoadEnvFrom(&cfg, ".env.production"). As with godotenv, no
+// file overrides a variable already set, by the process or by an earlier
+// file. Every path must exist and parse. Without paths it loads ./.env (or
+// ENV_FILE) like LoadEnvVarible.
+func LoadEnvFrom(cfg interface{}, paths ...string) error {
+	if err := loadDotEnv(paths...); err != nil {
+		return err
+	}
+
+	return NewLoader().Load(cfg)
+}
+
+// envFileVar names a .env file to load instead of ./.env.
+const envFileVar = "ENV_FILE"
+
+// loadDotEnv loads variables from .env files into the process environment,
+// without overriding those already set. Explicit paths, or the file named by
+// ENV_FILE when there are none, must exist and parse. Otherwise ./.env is
+// read: its absence is normal in production and only logged at debug level,
+// and a file that can't be parsed is logged and skipped.
+func loadDotEnv(paths ...string) error {
+	for _, path := range paths {
+		if err := godotenv.Load(path); err != nil {
+			return fmt.Errorf("env file %s: %w", path, err)
+		}
+	}
+	if len(paths) > 0 {
+		return nil
+	}
+
+	if path := os.Getenv(envFileVar); path != "" {
+		if err := godotenv.Load(path); err != nil {
+			return fmt.Errorf("%s=%s: %w", envFileVar, path, err)
+		}
+		return nil
+	}
+
+	err := godotenv.Load()
+	logger := astrolog.GetLogger()
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		logger.Debug().Msg("no .env file, using the process environment")
+	case err != nil:
+		logger.Warn().Err(err).Msg("could not load .env file")
+	}
+	return nil
+}
+
+// Validator is implemented by config structs that check themselves once
+// loaded. Its error is returned by Load together with any required_if or
+// required_unless violation.
+type Validator interface {
+	Validate() error
+}
+
+// requiredIfOption and requiredUnlessOption introduce a conditional
+// requirement in an `env` tag.
+const (
+	requiredIfOption     = "required_if="
+	requiredUnlessOption = "required_unless="
+)
+
+// loadState tracks one Load call: the value every key resolved to and the
+// conditional fields left unset, checked once the whole struct is loaded so
+// field order doesn't matter.
+type loadState struct {
+	resolved map[string]string
+	pending  []requiredIf
+	path     string      // dotted path of the struct being parsed, "" at the top
+	report   *LoadReport // where each field got its value; nil → not recorded
+
+	parsing map[reflect.Type]bool // struct types being parsed, for recursive types
+}
+
+// requiredIf is an unset field whose requirement depends on another key:
+// required when condKey resolves to condVal, or with unless when condKey
+// resolves to "".
+type requiredIf struct {
+	key, fieldName string
+	condKey        string
+	condVal        string
+	unless         bool
+}
+
+// Loader resolves `env` tags against its own snapshot of variables, so loads
+// from different Loaders never see each other's values. Safe for concurrent
+// use as long as each Load targets a different struct.
+type Loader struct {
+	vars map[string]string
+	src  Lookup // asked for keys vars doesn't set; nil → none
+
+	srcErrs *sourceErrors // source errors of the Load in progress
+}
 
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: Could not load .env file: %v", err)
+// sourceErrors collects the Lookup errors of one Load, once per key.
+type sourceErrors struct {
+	seen map[string]bool
+	errs []error
+}
+
+// NewLoader snapshots the current process environment.
+func NewLoader() *Loader {
+	vars := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
 	}
+	return &Loader{vars: vars}
+}
 
+// NewLoaderFromMap uses a copy of vars as the only source; the process
+// environment is ignored.
+func NewLoaderFromMap(vars map[string]string) *Loader {
+	snapshot := make(map[string]string, len(vars))
+	for k, v := range vars {
+		snapshot[k] = v
+	}
+	return &Loader{vars: snapshot}
+}
+
+// Load fills cfg (a pointer to a struct) from the Loader's snapshot.
+func (l *Loader) Load(cfg interface{}) error {
+	return l.load(cfg, &loadState{resolved: make(map[string]string)})
+}
+
+func (l *Loader) load(cfg interface{}, st *loadState) error {
 	// We need a pointer to a struct to be able to set fields
 	v := reflect.ValueOf(cfg)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
 		return fmt.Errorf("LoadEnv: expected a pointer to a struct, got %T", cfg)
 	}
 
-	return parseStruct(v.Elem())
+	// Each Load collects its own source errors, so concurrent loads on one
+	// Loader stay independent.
+	srcErrs := &sourceErrors{seen: make(map[string]bool)}
+	if l.src != nil {
+		l = &Loader{vars: l.vars, src: l.src, srcErrs: srcErrs}
+	}
+
+	if err := l.parseStruct(v.Elem(), st); err != nil {
+		return errors.Join(append(srcErrs.errs, err)...)
+	}
+
+	errs := srcErrs.errs
+	for _, r := range st.pending {
+		got, ok := st.resolved[r.condKey]
+		if !ok {
+			got = l.lookup(r.condKey)
+		}
+		switch {
+		case r.unless && got == "":
+			errs = append(errs, fmt.Errorf("missing env variable %q (for field %q): required unless %s is set", r.key, r.fieldName, r.condKey))
+		case !r.unless && strings.EqualFold(got, r.condVal):
+			errs = append(errs, fmt.Errorf("missing env variable %q (for field %q): required because %s=%s", r.key, r.fieldName, r.condKey, r.condVal))
+		}
+	}
+	if validator, ok := cfg.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lookup returns the value of key in the snapshot, then in the Lookup
+// source, "" when unset.
+func (l *Loader) lookup(key string) string {
+	v, _ := l.lookupSet(key)
+	return v
+}
+
+// lookupSet is lookup, also reporting whether key is set at all, so an
+// empty value can be told apart from a missing one.
+func (l *Loader) lookupSet(key string) (string, bool) {
+	v, inVars := l.vars[key]
+	if v != "" || l.src == nil {
+		return v, inVars
+	}
+	v, ok, err := l.src.Lookup(key)
+	if err != nil && l.srcErrs != nil && !l.srcErrs.seen[key] {
+		l.srcErrs.seen[key] = true
+		l.srcErrs.errs = append(l.srcErrs.errs, fmt.Errorf("lookup %q: %w", key, err))
+	}
+	return v, ok || inVars
 }
 
 // parseStruct iterates over every field in the struct and processes its `env` tag.
 // If a field is itself a nested struct, it recurses into it.
-func parseStruct(v reflect.Value) error {
+func (l *Loader) parseStruct(v reflect.Value, st *loadState) error {
 	t := v.Type()
 
 	for i := 0; i < t.NumField(); i++ {
@@ -46,8 +265,16 @@ func parseStruct(v reflect.Value) error {
 		fieldType := t.Field(i)
 
 		// ── Nested struct → recurse ──────────────────────────────────────────
-		if field.Kind() == reflect.Struct {
-			if err := parseStruct(field); err != nil {
+		if field.Kind() == reflect.Struct && field.Type() != secretStringType {
+			if err := l.parseNested(field, fieldType.Name, st); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// ── Nested struct pointer → recurse, allocating it when nil ─────────
+		if isStructPtr(field.Type()) {
+			if err := l.parseNestedPtr(field, fieldType.Name, st); err != nil {
 				return err
 			}
 			continue
@@ -61,21 +288,256 @@ func parseStruct(v reflect.Value) error {
 
 		key, defaultVal, hasDefault := parseTag(tag)
 
+		// ── map[string]string → collect every var starting with the prefix ──
+		if field.Kind() == reflect.Map {
+			if err := l.setPrefixMap(field, fieldType.Name, key, defaultVal); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// ── envDeprecated:"OLD_KEY" → old name used while the new one is unset
+		from := l.sourceKey(key, fieldType)
+
+		// ── required_if=KEY=value → only required under a condition ──────────
+		var cond *requiredIf
+		if c, ok := strings.CutPrefix(defaultVal, requiredIfOption); ok {
+			condKey, condVal, found := strings.Cut(c, "=")
+			if !found || condKey == "" {
+				return fmt.Errorf("field %q: invalid %s%s, want %sKEY=value", fieldType.Name, requiredIfOption, c, requiredIfOption)
+			}
+			cond = &requiredIf{key: key, fieldName: fieldType.Name, condKey: condKey, condVal: condVal}
+			defaultVal, hasDefault = "", false
+		}
+
+		// ── `default:"..."` tag → used when the env tag has no default ───────
+		if !hasDefault {
+			defaultVal, hasDefault = fieldType.Tag.Lookup("default")
+		}
+
+		// ── Bool without any default → keep the field's current value ───────
+		// The Go zero value (false) or whatever the caller set before loading
+		// acts as the default, so flags are never "required".
+		if !hasDefault && field.Kind() == reflect.Bool && l.lookup(from) == "" {
+			st.resolved[key] = strconv.FormatBool(field.Bool())
+			st.record(key, fieldType, sourceDefault, st.resolved[key])
+			continue
+		}
+
+		// ── required_unless=KEY → only required while KEY is empty ───────────
+		if c, ok := strings.CutPrefix(defaultVal, requiredUnlessOption); ok {
+			if c == "" || strings.Contains(c, "=") {
+				return fmt.Errorf("field %q: invalid %s%s, want %sKEY", fieldType.Name, requiredUnlessOption, c, requiredUnlessOption)
+			}
+			cond = &requiredIf{key: key, fieldName: fieldType.Name, condKey: c, unless: true}
+			defaultVal, hasDefault = "", false
+		}
+
+		// ── Unset conditional field → checked after the whole struct ─────────
+		if cond != nil && !hasDefault && l.lookup(from) == "" {
+			st.pending = append(st.pending, *cond)
+			continue
+		}
+
+		// ── Pointer → nil when unset without default, the pointer itself
+		// expresses optionality; set (even to "" for *string) → allocated ──
+		if field.Kind() == reflect.Ptr {
+			rawVal, set := l.lookupSet(from)
+			if rawVal == "" && field.Type().Elem().Kind() != reflect.String {
+				set = false
+			}
+			if !set {
+				if !hasDefault {
+					continue
+				}
+				rawVal = defaultVal
+			}
+			ptr := reflect.New(field.Type().Elem())
+			if err := setField(ptr.Elem(), fieldType.Name, rawVal, isSecret(fieldType, key)); err != nil {
+				return err
+			}
+			field.Set(ptr)
+			st.resolved[key] = rawVal
+
+			source := sourceEnv
+			if !set {
+				source = sourceDefault
+			}
+			st.record(key, fieldType, source, defaultVal)
+			continue
+		}
+
 		// ── Resolve the value: env var → default → error ─────────────────────
-		rawVal, err := resolveValue(key, defaultVal, hasDefault, fieldType.Name)
+		rawVal, err := l.resolveValue(from, defaultVal, hasDefault, fieldType.Name)
 		if err != nil {
 			return err
 		}
 
 		// ── Cast and set the value into the struct field ──────────────────────
-		if err := setField(field, fieldType.Name, rawVal); err != nil {
+		if field.Kind() == reflect.Slice {
+			err = setSlice(field, fieldType.Name, rawVal, listSeparator(fieldType), isSecret(fieldType, key))
+		} else {
+			err = setField(field, fieldType.Name, rawVal, isSecret(fieldType, key))
+		}
+		if err != nil {
 			return err
 		}
+		st.resolved[key] = rawVal
+
+		if st.report != nil {
+			source := sourceEnv
+			if l.lookup(from) == "" {
+				source = sourceDefault
+			}
+			st.record(key, fieldType, source, defaultVal)
+		}
+	}
+
+	return nil
+}
+
+// sourceKey returns the key to read for a field: key itself, or the first
+// of its `envDeprecated:"OLD_A,OLD_B"` aliases that is set when key is not.
+// Reading an alias logs a warning naming the new key.
+func (l *Loader) sourceKey(key string, fieldType reflect.StructField) string {
+	aliases := fieldType.Tag.Get("envDeprecated")
+	if aliases == "" || l.lookup(key) != "" {
+		return key
+	}
+	for _, old := range strings.Split(aliases, ",") {
+		old = strings.TrimSpace(old)
+		if old == "" || l.lookup(old) == "" {
+			continue
+		}
+		logger := astrolog.GetLogger()
+		logger.Warn().
+			Str("deprecated_key", old).
+			Str("key", key).
+			Str("field", fieldType.Name).
+			Msgf("env variable %s is deprecated, use %s instead", old, key)
+		return old
+	}
+	return key
+}
+
+// setPrefixMap fills a map[string]string field with every variable whose name
+// starts with prefix, the prefix stripped from the keys.
+//
+//	`env:"LABEL_,prefix"`          → LABEL_Team=a  ⇒  map["team"] = "a"
+//	`env:"LABEL_,prefix,keepcase"` → LABEL_Team=a  ⇒  map["Team"] = "a"
+//
+// The map is never nil, even when no variable matches.
+func (l *Loader) setPrefixMap(field reflect.Value, fieldName, prefix, options string) error {
+	if field.Type() != reflect.TypeOf(map[string]string(nil)) {
+		return fmt.Errorf("field %q: unsupported type %s (only map[string]string)", fieldName, field.Type())
+	}
+
+	isPrefix, keepCase := false, false
+	for _, opt := range strings.Split(options, ",") {
+		switch strings.TrimSpace(opt) {
+		case "prefix":
+			isPrefix = true
+		case "keepcase":
+			keepCase = true
+		}
+	}
+	if !isPrefix {
+		return fmt.Errorf("field %q: map fields need the prefix option, e.g. `env:\"%s,prefix\"`", fieldName, prefix)
+	}
+
+	m := make(map[string]string)
+	for name, val := range l.vars {
+		if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		k := strings.TrimPrefix(name, prefix)
+		if !keepCase {
+			k = strings.ToLower(k)
+		}
+		m[k] = val
+	}
+
+	field.Set(reflect.ValueOf(m))
+	return nil
+}
+
+// parseNested parses the nested struct field, named name, under its path.
+func (l *Loader) parseNested(field reflect.Value, name string, st *loadState) error {
+	if st.parsing == nil {
+		st.parsing = make(map[reflect.Type]bool)
+	}
+	outer := st.path
+	st.path += name + "."
+	st.parsing[field.Type()] = true
+	err := l.parseStruct(field, st)
+	delete(st.parsing, field.Type())
+	st.path = outer
+	return err
+}
+
+// parseNestedPtr parses the struct a struct pointer field points to. A nil
+// pointer is allocated only if the struct has `env` tagged fields, so
+// sections without any stay nil, and never for a struct already being
+// parsed, which would allocate forever on recursive types.
+func (l *Loader) parseNestedPtr(field reflect.Value, name string, st *loadState) error {
+	elem := field.Type().Elem()
+	if !field.IsNil() {
+		return l.parseNested(field.Elem(), name, st)
+	}
+	if st.parsing[elem] || !hasEnvTags(elem, nil) {
+		return nil
+	}
+	ptr := reflect.New(elem)
+	if err := l.parseNested(ptr.Elem(), name, st); err != nil {
+		return err
 	}
+	field.Set(ptr)
+	return nil
+}
+
+// isStructPtr reports whether t points to a nested config struct.
+// *SecretString is a value, not a section.
+func isStructPtr(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && t.Elem() != secretStringType
+}
 
+// nestedType returns the config struct a field of type t holds, t itself or
+// what it points to, nil for any other field.
+func nestedType(t reflect.Type) reflect.Type {
+	switch {
+	case t.Kind() == reflect.Struct && t != secretStringType:
+		return t
+	case isStructPtr(t):
+		return t.Elem()
+	}
 	return nil
 }
 
+// hasEnvTags reports whether struct type t or a struct nested in it has an
+// `env` tagged field. seen holds the types already visited; nil → none.
+func hasEnvTags(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	if seen == nil {
+		seen = make(map[reflect.Type]bool)
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if nested := nestedType(f.Type); nested != nil {
+			if hasEnvTags(nested, seen) {
+				return true
+			}
+			continue
+		}
+		if f.Tag.Get("env") != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // parseTag splits "ENV_KEY,default_value" into its parts.
 // Returns: key, defaultValue, hasDefault
 func parseTag(tag string) (string, string, bool) {
@@ -90,8 +552,8 @@ func parseTag(tag string) (string, string, bool) {
 }
 
 // resolveValue looks up the env var. Falls back to default. Errors if required and missing.
-func resolveValue(key, defaultVal string, hasDefault bool, fieldName string) (string, error) {
-	if val := os.Getenv(key); val != "" {
+func (l *Loader) resolveValue(key, defaultVal string, hasDefault bool, fieldName string) (string, error) {
+	if val := l.lookup(key); val != "" {
 		return val, nil
 	}
 
@@ -102,8 +564,45 @@ func resolveValue(key, defaultVal string, hasDefault bool, fieldName string) (st
 	return "", fmt.Errorf("missing required env variable %q (for field %q)", key, fieldName)
 }
 
+// levelType is matched before the kind switch so zerolog.Level fields take
+// level names rather than their underlying int8.
+var levelType = reflect.TypeOf(zerolog.Level(0))
+
+// durationType is matched before the kind switch so time.Duration fields
+// take "10s" / "1m30s" rather than a count of nanoseconds.
+var durationType = reflect.TypeOf(time.Duration(0))
+
 // setField converts the raw string value to the correct type and sets it on the struct field.
-func setField(field reflect.Value, fieldName, rawVal string) error {
+// When secret is true, error messages show maskedValue instead of rawVal.
+func setField(field reflect.Value, fieldName, rawVal string, secret bool) error {
+	shown := strconv.Quote(rawVal)
+	if secret {
+		shown = maskedValue
+	}
+
+	if field.Type() == secretStringType {
+		field.Set(reflect.ValueOf(NewSecretString(rawVal)))
+		return nil
+	}
+
+	if field.Type() == levelType {
+		level, err := zerolog.ParseLevel(rawVal)
+		if err != nil {
+			return fmt.Errorf("field %q: cannot parse %s as log level (use debug/info/warn/error/...): %w", fieldName, shown, hideValue(err, secret))
+		}
+		field.Set(reflect.ValueOf(level))
+		return nil
+	}
+
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(rawVal)
+		if err != nil {
+			return fmt.Errorf("field %q: cannot parse %s as duration (use e.g. 10s, 1m30s): %w", fieldName, shown, hideValue(err, secret))
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
 	switch field.Kind() {
 
 	case reflect.String:
@@ -112,21 +611,30 @@ func setField(field reflect.Value, fieldName, rawVal string) error {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		n, err := strconv.ParseInt(rawVal, 10, 64)
 		if err != nil {
-			return fmt.Errorf("field %q: cannot parse %q as int: %w", fieldName, rawVal, err)
+			return fmt.Errorf("field %q: cannot parse %s as int: %w", fieldName, shown, hideValue(err, secret))
 		}
 		field.SetInt(n)
 
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// Parsed at the field's size so negative and out-of-range values
+		// fail instead of wrapping around.
+		n, err := strconv.ParseUint(rawVal, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("field %q: cannot parse %s as %s: %w", fieldName, shown, field.Kind(), hideValue(err, secret))
+		}
+		field.SetUint(n)
+
 	case reflect.Bool:
 		b, err := strconv.ParseBool(rawVal)
 		if err != nil {
-			return fmt.Errorf("field %q: cannot parse %q as bool (use true/false/1/0): %w", fieldName, rawVal, err)
+			return fmt.Errorf("field %q: cannot parse %s as bool (use true/false/1/0): %w", fieldName, shown, hideValue(err, secret))
 		}
 		field.SetBool(b)
 
 	case reflect.Float32, reflect.Float64:
 		f, err := strconv.ParseFloat(rawVal, 64)
 		if err != nil {
-			return fmt.Errorf("field %q: cannot parse %q as float: %w", fieldName, rawVal, err)
+			return fmt.Errorf("field %q: cannot parse %s as float: %w", fieldName, shown, hideValue(err, secret))
 		}
 		field.SetFloat(f)
 
@@ -136,3 +644,60 @@ func setField(field reflect.Value, fieldName, rawVal string) error {
 
 	return nil
 }
+
+// defaultListSeparator splits slice values unless `envSeparator` says otherwise.
+const defaultListSeparator = ","
+
+// listSeparator returns the separator of a slice field.
+func listSeparator(fieldType reflect.StructField) string {
+	if sep := fieldType.Tag.Get("envSeparator"); sep != "" {
+		return sep
+	}
+	return defaultListSeparator
+}
+
+// setSlice splits rawVal on sep and sets each trimmed element with setField.
+// An empty rawVal gives an empty, non-nil slice.
+func setSlice(field reflect.Value, fieldName, rawVal, sep string, secret bool) error {
+	var parts []string
+	if strings.TrimSpace(rawVal) != "" {
+		parts = strings.Split(rawVal, sep)
+	}
+
+	slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+	for i, part := range parts {
+		name := fmt.Sprintf("%s[%d]", fieldName, i)
+		if err := setField(slice.Index(i), name, strings.TrimSpace(part), secret); err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// formatSlice joins the elements of a slice field with its separator, the
+// form setSlice reads back.
+func formatSlice(field reflect.Value, fieldType reflect.StructField) string {
+	parts := make([]string, field.Len())
+	for i := range parts {
+		elem := field.Index(i)
+		if elem.Type() == secretStringType {
+			parts[i] = elem.Interface().(SecretString).Value()
+			continue
+		}
+		parts[i] = fmt.Sprint(elem.Interface())
+	}
+	return strings.Join(parts, listSeparator(fieldType))
+}
+
+// hideValue strips the parsed value from a parse error of a secret field.
+func hideValue(err error, secret bool) error {
+	if !secret {
+		return err
+	}
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+	return errors.New("invalid value")
+}
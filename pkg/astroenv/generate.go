@@ -0,0 +1,566 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedField is returned by GenerateLoader for fields the generated
+// loader can't handle; load those structs with Load instead.
+var ErrUnsupportedField = errors.New("not supported by generated loaders")
+
+// GenerateLoader writes to outFile a Load<typeName> function filling a
+// *typeName from a Lookup with the same `env` tag semantics as Load, but
+// without reflection, for configs reloaded in a loop:
+//
+//	//go:generate go run github.com/Asteroidea-tn/asterogo/pkg/astroenv/cmd/astroenvgen -type Config -out config_env.go
+//
+//	err := LoadConfig(&cfg, astroenv.NewLoader())
+//
+// pkg is the directory of the package declaring typeName, "." under
+// go:generate. Supported fields are the scalar types of Load (string, ints,
+// uints, bool, floats, time.Duration, zerolog.Level, SecretString, and
+// named types based on them), slices of them, and nested structs declared
+// in pkg or inline and pointers to structs declared in pkg, with defaults
+// from the tag or a `default` tag. Prefix maps, other pointers, recursive
+// types, required_if, required_unless and envDeprecated fail with
+// ErrUnsupportedField. Structs of other packages are skipped, as their
+// fields can't be read from pkg's source.
+func GenerateLoader(pkg, typeName, outFile string) error {
+	fset := token.NewFileSet()
+	out := filepath.Base(outFile)
+	pkgs, err := parser.ParseDir(fset, pkg, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != out
+	}, 0)
+	if err != nil {
+		return fmt.Errorf("GenerateLoader: %w", err)
+	}
+
+	for name, p := range pkgs {
+		g := &loaderGen{
+			types:   make(map[string]typeDecl),
+			self:    name == "astroenv",
+			imports: make(map[string]bool),
+			body:    new(bytes.Buffer),
+			parsing: map[string]bool{typeName: true},
+		}
+		for _, f := range p.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					g.types[ts.Name.Name] = typeDecl{spec: ts, file: f}
+				}
+			}
+		}
+		decl, ok := g.types[typeName]
+		if !ok {
+			continue
+		}
+		st, ok := decl.spec.Type.(*ast.StructType)
+		if !ok {
+			return fmt.Errorf("GenerateLoader: %s is not a struct", typeName)
+		}
+		src, err := g.generate(name, typeName, st, decl.file)
+		if err != nil {
+			return fmt.Errorf("GenerateLoader: %s: %w", typeName, err)
+		}
+		return os.WriteFile(outFile, src, 0644)
+	}
+	return fmt.Errorf("GenerateLoader: type %s not found in %s", typeName, pkg)
+}
+
+// typeDecl is a type declared in the generated package and its file, whose
+// imports name the packages its fields refer to.
+type typeDecl struct {
+	spec *ast.TypeSpec
+	file *ast.File
+}
+
+// loaderGen writes the body of one generated loader.
+type loaderGen struct {
+	types   map[string]typeDecl
+	self    bool            // generating into package astroenv itself
+	imports map[string]bool // import paths used by the body
+	body    *bytes.Buffer
+	fails   bool // the body returns parse or missing-variable errors
+
+	parsing map[string]bool // named struct types being generated
+}
+
+// genKind is how a field value is parsed, setField's cases.
+type genKind int
+
+const (
+	genString genKind = iota
+	genSecret
+	genInt
+	genUint
+	genBool
+	genFloat
+	genDuration
+	genLevel
+)
+
+// genType is a resolved field type.
+type genType struct {
+	kind   genKind
+	goType string // as written in the generated code
+	basic  string // underlying basic type, e.g. "uint16", for numbers
+	slice  bool   // a slice of kind elements; goType is the slice type
+	elem   string // element type of a slice
+}
+
+func (g *loaderGen) generate(pkgName, typeName string, st *ast.StructType, file *ast.File) ([]byte, error) {
+	if err := g.fields(st, file, "cfg."); err != nil {
+		return nil, err
+	}
+
+	g.imports["errors"] = true
+	g.imports["fmt"] = true
+	if !g.self {
+		g.imports["github.com/Asteroidea-tn/asterogo/pkg/astroenv"] = true
+	}
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	// Standard library first, like goimports.
+	sort.Slice(paths, func(i, j int) bool {
+		si, sj := isStdPath(paths[i]), isStdPath(paths[j])
+		if si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by astroenv.GenerateLoader; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkgName)
+	for i, p := range paths {
+		if i > 0 && isStdPath(p) != isStdPath(paths[i-1]) {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "\t%q\n", p)
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	fmt.Fprintf(&b, "// Load%s fills cfg from lookup with the semantics of astroenv's Load,\n// without reflection.\n", typeName)
+	fmt.Fprintf(&b, "func Load%s(cfg *%s, lookup %sLookup) error {\n", typeName, typeName, g.qual())
+	b.WriteString(`var errs []error
+	get := func(key string) string {
+		v, _, err := lookup.Lookup(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lookup %q: %w", key, err))
+		}
+		return v
+	}
+`)
+	if g.fails {
+		b.WriteString("fail := func(err error) error { return errors.Join(append(errs, err)...) }\n")
+	}
+	if g.body.Len() > 0 {
+		b.WriteString("var raw string\n")
+		b.Write(g.body.Bytes())
+	} else {
+		b.WriteString("_ = get\n")
+	}
+	fmt.Fprintf(&b, `
+	if v, ok := any(cfg).(%sValidator); ok {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+`, g.qual())
+
+	return format.Source(b.Bytes())
+}
+
+// isStdPath reports whether an import path is in the standard library.
+func isStdPath(p string) bool {
+	first, _, _ := strings.Cut(p, "/")
+	return !strings.Contains(first, ".")
+}
+
+// qual is the qualifier of astroenv identifiers in the generated code.
+func (g *loaderGen) qual() string {
+	if g.self {
+		return ""
+	}
+	return "astroenv."
+}
+
+// fields writes the loading code of every field of st, reached from cfg
+// through prefix, in declaration order.
+func (g *loaderGen) fields(st *ast.StructType, file *ast.File, prefix string) error {
+	for _, f := range st.Fields.List {
+		names := f.Names
+		if len(names) == 0 {
+			// Embedded field: named after its type.
+			id := embeddedName(f.Type)
+			if id == "" {
+				continue
+			}
+			names = []*ast.Ident{ast.NewIdent(id)}
+		}
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			raw, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(raw)
+		}
+		for _, name := range names {
+			if err := g.field(name.Name, f.Type, tag, file, prefix); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// embeddedName returns the field name of an embedded field of type expr.
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	}
+	return ""
+}
+
+func (g *loaderGen) field(name string, expr ast.Expr, tag reflect.StructTag, file *ast.File, prefix string) error {
+	// ── Nested struct → recurse ──────────────────────────────────────────
+	if st, stFile := g.structOf(expr, file); st != nil {
+		return g.nested(expr, st, stFile, prefix+name+".")
+	}
+
+	// ── Nested struct pointer → allocated when nil, if it has env fields ─
+	if star, ok := expr.(*ast.StarExpr); ok {
+		if st, stFile := g.structOf(star.X, file); st != nil {
+			outer := g.body
+			g.body = new(bytes.Buffer)
+			err := g.nested(star.X, st, stFile, prefix+name+".")
+			inner := g.body
+			g.body = outer
+			if err != nil || inner.Len() == 0 {
+				return err
+			}
+			id, ok := star.X.(*ast.Ident)
+			if !ok {
+				return fmt.Errorf("field %q: pointer to an inline struct %w", name, ErrUnsupportedField)
+			}
+			fmt.Fprintf(g.body, "\nif %s%s == nil {\n%s%s = new(%s)\n}\n", prefix, name, prefix, name, id.Name)
+			g.body.Write(inner.Bytes())
+			return nil
+		}
+	}
+
+	envTag := tag.Get("env")
+	if envTag == "" {
+		return nil
+	}
+	key, defaultVal, hasDefault := parseTag(envTag)
+
+	if strings.HasPrefix(defaultVal, requiredIfOption) || strings.HasPrefix(defaultVal, requiredUnlessOption) {
+		return fmt.Errorf("field %q: conditional requirement %w", name, ErrUnsupportedField)
+	}
+	if tag.Get("envDeprecated") != "" {
+		return fmt.Errorf("field %q: envDeprecated %w", name, ErrUnsupportedField)
+	}
+	t, err := g.resolve(expr, file)
+	if err != nil {
+		return fmt.Errorf("field %q: %w", name, err)
+	}
+	if !hasDefault {
+		defaultVal, hasDefault = tag.Lookup("default")
+	}
+	secret := t.kind == genSecret || tag.Get("secret") == "true" || secretKey(key)
+	dst := prefix + name
+
+	fmt.Fprintf(g.body, "\n// %s ← %s\n", strings.TrimPrefix(dst, "cfg."), key)
+	switch {
+	case !hasDefault && t.kind == genBool && !t.slice:
+		// Bool without any default keeps the field's current value.
+		fmt.Fprintf(g.body, "if raw = get(%q); raw != \"\" {\n", key)
+	case hasDefault:
+		fmt.Fprintf(g.body, "if raw = get(%q); raw == \"\" {\nraw = %q\n}\n{\n", key, defaultVal)
+	default:
+		g.fails = true
+		fmt.Fprintf(g.body, "if raw = get(%q); raw == \"\" {\nreturn fail(fmt.Errorf(\"missing required env variable %%q (for field %%q)\", %q, %q))\n}\n{\n", key, key, name)
+	}
+
+	if !t.slice {
+		g.assign(t, dst, "raw", strconv.Quote(name), secret)
+		g.body.WriteString("}\n")
+		return nil
+	}
+
+	sep := tag.Get("envSeparator")
+	if sep == "" {
+		sep = defaultListSeparator
+	}
+	g.imports["strings"] = true
+	fmt.Fprintf(g.body, `var parts []string
+	if strings.TrimSpace(raw) != "" {
+		parts = strings.Split(raw, %q)
+	}
+	s := make(%s, len(parts))
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+`, sep, t.goType)
+	g.assign(genType{kind: t.kind, goType: t.elem, basic: t.basic}, "s[i]", "part", fmt.Sprintf("fmt.Sprintf(%q, i)", name+"[%d]"), secret)
+	fmt.Fprintf(g.body, "}\n%s = s\n}\n", dst)
+	return nil
+}
+
+// assign writes the code parsing the string variable v into dst, failing
+// with setField's messages for the field named by the expression name.
+func (g *loaderGen) assign(t genType, dst, v, name string, secret bool) {
+	shown := "strconv.Quote(" + v + ")"
+	if secret {
+		shown = strconv.Quote(maskedValue)
+	}
+	// hideValue, decided here: numbers and bools fail with *strconv.NumError.
+	numErr, otherErr := "err", "err"
+	if secret {
+		numErr, otherErr = "err.(*strconv.NumError).Err", `errors.New("invalid value")`
+	}
+	// convert converts x, of the type its parse function returns, to the
+	// field type.
+	convert := func(x, parsed string) string {
+		if t.goType == parsed {
+			return x
+		}
+		return t.goType + "(" + x + ")"
+	}
+	fail := func(what, errExpr string) {
+		g.fails = true
+		if !secret {
+			g.imports["strconv"] = true
+		}
+		fmt.Fprintf(g.body, "if err != nil {\nreturn fail(fmt.Errorf(\"field %%q: cannot parse %%s as %s: %%w\", %s, %s, %s))\n}\n", what, name, shown, errExpr)
+	}
+
+	switch t.kind {
+	case genString:
+		fmt.Fprintf(g.body, "%s = %s\n", dst, convert(v, "string"))
+	case genSecret:
+		fmt.Fprintf(g.body, "%s = %sNewSecretString(%s)\n", dst, g.qual(), v)
+	case genInt:
+		g.imports["strconv"] = true
+		fmt.Fprintf(g.body, "n, err := strconv.ParseInt(%s, 10, 64)\n", v)
+		fail("int", numErr)
+		fmt.Fprintf(g.body, "%s = %s\n", dst, convert("n", "int64"))
+	case genUint:
+		g.imports["strconv"] = true
+		bits := "strconv.IntSize"
+		if b := strings.TrimPrefix(t.basic, "uint"); b != "" && b != "ptr" {
+			bits = b
+		}
+		fmt.Fprintf(g.body, "n, err := strconv.ParseUint(%s, 10, %s)\n", v, bits)
+		fail(t.basic, numErr)
+		fmt.Fprintf(g.body, "%s = %s\n", dst, convert("n", "uint64"))
+	case genBool:
+		g.imports["strconv"] = true
+		fmt.Fprintf(g.body, "b, err := strconv.ParseBool(%s)\n", v)
+		fail("bool (use true/false/1/0)", numErr)
+		fmt.Fprintf(g.body, "%s = %s\n", dst, convert("b", "bool"))
+	case genFloat:
+		g.imports["strconv"] = true
+		fmt.Fprintf(g.body, "f, err := strconv.ParseFloat(%s, 64)\n", v)
+		fail("float", numErr)
+		fmt.Fprintf(g.body, "%s = %s\n", dst, convert("f", "float64"))
+	case genDuration:
+		g.imports["time"] = true
+		fmt.Fprintf(g.body, "d, err := time.ParseDuration(%s)\n", v)
+		fail("duration (use e.g. 10s, 1m30s)", otherErr)
+		fmt.Fprintf(g.body, "%s = d\n", dst)
+	case genLevel:
+		g.imports["github.com/rs/zerolog"] = true
+		fmt.Fprintf(g.body, "level, err := zerolog.ParseLevel(%s)\n", v)
+		fail("log level (use debug/info/warn/error/...)", otherErr)
+		fmt.Fprintf(g.body, "%s = level\n", dst)
+	}
+}
+
+// nested writes the fields of the struct st of type expr, reached through
+// prefix. Named structs already being generated are recursive types.
+func (g *loaderGen) nested(expr ast.Expr, st *ast.StructType, file *ast.File, prefix string) error {
+	id, named := expr.(*ast.Ident)
+	if !named {
+		return g.fields(st, file, prefix)
+	}
+	if g.parsing[id.Name] {
+		return fmt.Errorf("recursive type %s %w", id.Name, ErrUnsupportedField)
+	}
+	g.parsing[id.Name] = true
+	defer delete(g.parsing, id.Name)
+	return g.fields(st, file, prefix)
+}
+
+// structOf returns the struct a field of type expr holds, inline or declared
+// in the package, with the file declaring it; nil for any other type.
+func (g *loaderGen) structOf(expr ast.Expr, file *ast.File) (*ast.StructType, *ast.File) {
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return t, file
+	case *ast.Ident:
+		if decl, ok := g.types[t.Name]; ok {
+			if st, ok := decl.spec.Type.(*ast.StructType); ok && !(g.self && t.Name == "SecretString") {
+				return st, decl.file
+			}
+		}
+	}
+	return nil, nil
+}
+
+// basicKinds maps Go's basic types to their genKind.
+var basicKinds = map[string]genKind{
+	"string": genString,
+	"int":    genInt, "int8": genInt, "int16": genInt, "int32": genInt, "int64": genInt,
+	"uint": genUint, "uint8": genUint, "uint16": genUint, "uint32": genUint, "uint64": genUint, "uintptr": genUint,
+	"byte": genUint, "rune": genInt,
+	"bool":    genBool,
+	"float32": genFloat, "float64": genFloat,
+}
+
+// resolve returns how a tagged field of type expr is parsed.
+func (g *loaderGen) resolve(expr ast.Expr, file *ast.File) (genType, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if kind, ok := basicKinds[t.Name]; ok {
+			return genType{kind: kind, goType: t.Name, basic: basicName(t.Name)}, nil
+		}
+		decl, ok := g.types[t.Name]
+		if !ok {
+			return genType{}, fmt.Errorf("type %s %w", t.Name, ErrUnsupportedField)
+		}
+		if g.self && t.Name == "SecretString" {
+			return genType{kind: genSecret, goType: "SecretString"}, nil
+		}
+		under, err := g.resolve(decl.spec.Type, decl.file)
+		if err != nil {
+			return genType{}, err
+		}
+		if under.slice {
+			under.goType = t.Name
+			return under, nil
+		}
+		// Only time.Duration itself parses as a duration, like setField.
+		switch {
+		case under.kind == genDuration:
+			under = genType{kind: genInt, basic: "int64"}
+		case under.kind == genLevel:
+			under = genType{kind: genInt, basic: "int8"}
+		case under.kind == genSecret:
+			return genType{}, fmt.Errorf("type %s %w", t.Name, ErrUnsupportedField)
+		}
+		under.goType = t.Name
+		return under, nil
+
+	case *ast.SelectorExpr:
+		pkgIdent, ok := t.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		switch importPath(file, pkgIdent.Name) + "." + t.Sel.Name {
+		case "time.Duration":
+			return genType{kind: genDuration, goType: "time.Duration"}, nil
+		case "github.com/rs/zerolog.Level":
+			return genType{kind: genLevel, goType: "zerolog.Level"}, nil
+		case "github.com/Asteroidea-tn/asterogo/pkg/astroenv.SecretString":
+			return genType{kind: genSecret, goType: g.qual() + "SecretString"}, nil
+		}
+
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		elem, err := g.resolve(t.Elt, file)
+		if err != nil {
+			return genType{}, err
+		}
+		if elem.slice {
+			break
+		}
+		if elem.kind == genDuration {
+			g.imports["time"] = true
+		}
+		if elem.kind == genLevel {
+			g.imports["github.com/rs/zerolog"] = true
+		}
+		elem.slice, elem.elem, elem.goType = true, elem.goType, "[]"+elem.goType
+		return elem, nil
+	}
+	return genType{}, fmt.Errorf("type %s %w", exprString(expr), ErrUnsupportedField)
+}
+
+// basicName returns the type a basic type alias stands for.
+func basicName(name string) string {
+	switch name {
+	case "byte":
+		return "uint8"
+	case "rune":
+		return "int32"
+	}
+	return name
+}
+
+// importPath returns the path file imports under name, name itself when
+// there is none.
+func importPath(file *ast.File, name string) string {
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		local := path.Base(p)
+		if spec.Name != nil {
+			local = spec.Name.Name
+		}
+		if local == name {
+			return p
+		}
+	}
+	return name
+}
+
+// exprString formats a type expression for error messages.
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(t.X)
+	case *ast.ArrayType:
+		return "[]" + exprString(t.Elt)
+	case *ast.MapType:
+		return "map[" + exprString(t.Key) + "]" + exprString(t.Value)
+	}
+	return fmt.Sprintf("%T", expr)
+}
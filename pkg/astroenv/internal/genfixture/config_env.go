@@ -0,0 +1,366 @@
+// Code generated by astroenv.GenerateLoader; DO NOT EDIT.
+
+package genfixture
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astroenv"
+	"github.com/rs/zerolog"
+)
+
+// LoadConfig fills cfg from lookup with the semantics of astroenv's Load,
+// without reflection.
+func LoadConfig(cfg *Config, lookup astroenv.Lookup) error {
+	var errs []error
+	get := func(key string) string {
+		v, _, err := lookup.Lookup(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lookup %q: %w", key, err))
+		}
+		return v
+	}
+	fail := func(err error) error { return errors.Join(append(errs, err)...) }
+	var raw string
+
+	// Name ← APP_NAME
+	if raw = get("APP_NAME"); raw == "" {
+		return fail(fmt.Errorf("missing required env variable %q (for field %q)", "APP_NAME", "Name"))
+	}
+	{
+		cfg.Name = raw
+	}
+
+	// Env ← APP_ENV
+	if raw = get("APP_ENV"); raw == "" {
+		raw = "development"
+	}
+	{
+		cfg.Env = raw
+	}
+
+	// Region ← APP_REGION
+	if raw = get("APP_REGION"); raw == "" {
+		raw = "eu-west-1"
+	}
+	{
+		cfg.Region = raw
+	}
+
+	// Workers ← APP_WORKERS
+	if raw = get("APP_WORKERS"); raw == "" {
+		raw = "4"
+	}
+	{
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", "Workers", strconv.Quote(raw), err))
+		}
+		cfg.Workers = int(n)
+	}
+
+	// Shard ← APP_SHARD
+	if raw = get("APP_SHARD"); raw == "" {
+		raw = "0"
+	}
+	{
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", "Shard", strconv.Quote(raw), err))
+		}
+		cfg.Shard = int8(n)
+	}
+
+	// MaxConns ← APP_MAX_CONNS
+	if raw = get("APP_MAX_CONNS"); raw == "" {
+		raw = "100"
+	}
+	{
+		n, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as uint32: %w", "MaxConns", strconv.Quote(raw), err))
+		}
+		cfg.MaxConns = uint32(n)
+	}
+
+	// Port ← APP_PORT
+	if raw = get("APP_PORT"); raw == "" {
+		raw = "8080"
+	}
+	{
+		n, err := strconv.ParseUint(raw, 10, 16)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as uint16: %w", "Port", strconv.Quote(raw), err))
+		}
+		cfg.Port = Port(n)
+	}
+
+	// Ratio ← APP_RATIO
+	if raw = get("APP_RATIO"); raw == "" {
+		raw = "0.5"
+	}
+	{
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as float: %w", "Ratio", strconv.Quote(raw), err))
+		}
+		cfg.Ratio = f
+	}
+
+	// Debug ← APP_DEBUG
+	if raw = get("APP_DEBUG"); raw != "" {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as bool (use true/false/1/0): %w", "Debug", strconv.Quote(raw), err))
+		}
+		cfg.Debug = b
+	}
+
+	// Metrics ← APP_METRICS
+	if raw = get("APP_METRICS"); raw == "" {
+		raw = "true"
+	}
+	{
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as bool (use true/false/1/0): %w", "Metrics", strconv.Quote(raw), err))
+		}
+		cfg.Metrics = b
+	}
+
+	// Interval ← APP_INTERVAL
+	if raw = get("APP_INTERVAL"); raw == "" {
+		raw = "30s"
+	}
+	{
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as duration (use e.g. 10s, 1m30s): %w", "Interval", strconv.Quote(raw), err))
+		}
+		cfg.Interval = d
+	}
+
+	// LogLevel ← APP_LOG_LEVEL
+	if raw = get("APP_LOG_LEVEL"); raw == "" {
+		raw = "info"
+	}
+	{
+		level, err := zerolog.ParseLevel(raw)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as log level (use debug/info/warn/error/...): %w", "LogLevel", strconv.Quote(raw), err))
+		}
+		cfg.LogLevel = level
+	}
+
+	// Hosts ← APP_HOSTS
+	if raw = get("APP_HOSTS"); raw == "" {
+		raw = "localhost"
+	}
+	{
+		var parts []string
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ",")
+		}
+		s := make([]string, len(parts))
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			s[i] = part
+		}
+		cfg.Hosts = s
+	}
+
+	// Ports ← APP_PORTS
+	if raw = get("APP_PORTS"); raw == "" {
+		raw = "80,443"
+	}
+	{
+		var parts []string
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ",")
+		}
+		s := make([]int, len(parts))
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", fmt.Sprintf("Ports[%d]", i), strconv.Quote(part), err))
+			}
+			s[i] = int(n)
+		}
+		cfg.Ports = s
+	}
+
+	// Delays ← APP_DELAYS
+	if raw = get("APP_DELAYS"); raw == "" {
+		raw = "1s;2s"
+	}
+	{
+		var parts []string
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ";")
+		}
+		s := make([]time.Duration, len(parts))
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			d, err := time.ParseDuration(part)
+			if err != nil {
+				return fail(fmt.Errorf("field %q: cannot parse %s as duration (use e.g. 10s, 1m30s): %w", fmt.Sprintf("Delays[%d]", i), strconv.Quote(part), err))
+			}
+			s[i] = d
+		}
+		cfg.Delays = s
+	}
+
+	// Weights ← APP_WEIGHTS
+	if raw = get("APP_WEIGHTS"); raw == "" {
+		raw = ""
+	}
+	{
+		var parts []string
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ",")
+		}
+		s := make([]float32, len(parts))
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			f, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return fail(fmt.Errorf("field %q: cannot parse %s as float: %w", fmt.Sprintf("Weights[%d]", i), strconv.Quote(part), err))
+			}
+			s[i] = float32(f)
+		}
+		cfg.Weights = s
+	}
+
+	// Password ← DB_PASSWORD
+	if raw = get("DB_PASSWORD"); raw == "" {
+		raw = ""
+	}
+	{
+		cfg.Password = astroenv.NewSecretString(raw)
+	}
+
+	// PIN ← APP_PIN
+	if raw = get("APP_PIN"); raw == "" {
+		raw = "0"
+	}
+	{
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", "PIN", "****", err.(*strconv.NumError).Err))
+		}
+		cfg.PIN = int(n)
+	}
+
+	// Tokens ← APP_TOKENS
+	if raw = get("APP_TOKENS"); raw == "" {
+		raw = ""
+	}
+	{
+		var parts []string
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ",")
+		}
+		s := make([]astroenv.SecretString, len(parts))
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			s[i] = astroenv.NewSecretString(part)
+		}
+		cfg.Tokens = s
+	}
+
+	// APIKeyID ← APP_API_KEY_ID
+	if raw = get("APP_API_KEY_ID"); raw == "" {
+		raw = "0"
+	}
+	{
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", "APIKeyID", "****", err.(*strconv.NumError).Err))
+		}
+		cfg.APIKeyID = int(n)
+	}
+
+	// DB.Host ← DB_HOST
+	if raw = get("DB_HOST"); raw == "" {
+		return fail(fmt.Errorf("missing required env variable %q (for field %q)", "DB_HOST", "Host"))
+	}
+	{
+		cfg.DB.Host = raw
+	}
+
+	// DB.Port ← DB_PORT
+	if raw = get("DB_PORT"); raw == "" {
+		raw = "5432"
+	}
+	{
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", "Port", strconv.Quote(raw), err))
+		}
+		cfg.DB.Port = int(n)
+	}
+
+	if cfg.Cache == nil {
+		cfg.Cache = new(Cache)
+	}
+
+	// Cache.TTL ← CACHE_TTL
+	if raw = get("CACHE_TTL"); raw == "" {
+		raw = "1m"
+	}
+	{
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as duration (use e.g. 10s, 1m30s): %w", "TTL", strconv.Quote(raw), err))
+		}
+		cfg.Cache.TTL = d
+	}
+
+	// Cache.Size ← CACHE_SIZE
+	if raw = get("CACHE_SIZE"); raw == "" {
+		raw = "1024"
+	}
+	{
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", "Size", strconv.Quote(raw), err))
+		}
+		cfg.Cache.Size = int(n)
+	}
+
+	// Retry.Attempts ← RETRY_ATTEMPTS
+	if raw = get("RETRY_ATTEMPTS"); raw == "" {
+		raw = "3"
+	}
+	{
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as int: %w", "Attempts", strconv.Quote(raw), err))
+		}
+		cfg.Retry.Attempts = int(n)
+	}
+
+	// Retry.Backoff ← RETRY_BACKOFF
+	if raw = get("RETRY_BACKOFF"); raw == "" {
+		raw = "500ms"
+	}
+	{
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fail(fmt.Errorf("field %q: cannot parse %s as duration (use e.g. 10s, 1m30s): %w", "Backoff", strconv.Quote(raw), err))
+		}
+		cfg.Retry.Backoff = d
+	}
+
+	if v, ok := any(cfg).(astroenv.Validator); ok {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
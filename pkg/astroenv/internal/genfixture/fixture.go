@@ -0,0 +1,77 @@
+// ================ Version : V1.1.0 ===========
+// Package genfixture holds the config structs the generated loader is
+// checked against: config_env.go is astroenv.GenerateLoader's output for
+// Config, and the tests load the same variables through it and through
+// astroenv's reflective Load.
+package genfixture
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astroenv"
+	"github.com/rs/zerolog"
+)
+
+//go:generate go run ../../cmd/astroenvgen -type Config -out config_env.go
+
+// Port is a named type based on a scalar.
+type Port uint16
+
+// Config covers every field kind generated loaders support.
+type Config struct {
+	Name     string        `env:"APP_NAME"`
+	Env      string        `env:"APP_ENV,development"`
+	Region   string        `env:"APP_REGION" default:"eu-west-1"`
+	Workers  int           `env:"APP_WORKERS,4"`
+	Shard    int8          `env:"APP_SHARD,0"`
+	MaxConns uint32        `env:"APP_MAX_CONNS,100"`
+	Port     Port          `env:"APP_PORT,8080"`
+	Ratio    float64       `env:"APP_RATIO,0.5"`
+	Debug    bool          `env:"APP_DEBUG"`
+	Metrics  bool          `env:"APP_METRICS,true"`
+	Interval time.Duration `env:"APP_INTERVAL,30s"`
+	LogLevel zerolog.Level `env:"APP_LOG_LEVEL,info"`
+
+	Hosts   []string        `env:"APP_HOSTS,localhost"`
+	Ports   []int           `env:"APP_PORTS" default:"80,443"`
+	Delays  []time.Duration `env:"APP_DELAYS,1s;2s" envSeparator:";"`
+	Weights []float32       `env:"APP_WEIGHTS,"`
+
+	Password astroenv.SecretString   `env:"DB_PASSWORD,"`
+	PIN      int                     `env:"APP_PIN,0" secret:"true"`
+	Tokens   []astroenv.SecretString `env:"APP_TOKENS,"`
+	APIKeyID int                     `env:"APP_API_KEY_ID,0"` // secret by its name
+
+	DB    Database
+	Cache *Cache
+	Retry struct {
+		Attempts int           `env:"RETRY_ATTEMPTS,3"`
+		Backoff  time.Duration `env:"RETRY_BACKOFF,500ms"`
+	}
+
+	Untagged string
+}
+
+// Database is a nested struct with a required field.
+type Database struct {
+	Host string `env:"DB_HOST"`
+	Port int    `env:"DB_PORT,5432"`
+}
+
+// Cache is allocated by the loaders when nil.
+type Cache struct {
+	TTL  time.Duration `env:"CACHE_TTL,1m"`
+	Size int           `env:"CACHE_SIZE,1024"`
+}
+
+// ErrInvalidWorkers is returned by Config.Validate.
+var ErrInvalidWorkers = errors.New("APP_WORKERS must be positive")
+
+// Validate is called by both loaders once the fields are set.
+func (c *Config) Validate() error {
+	if c.Workers <= 0 {
+		return ErrInvalidWorkers
+	}
+	return nil
+}
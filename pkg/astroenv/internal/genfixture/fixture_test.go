@@ -0,0 +1,226 @@
+package genfixture
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astroenv"
+)
+
+// required are the variables Config can't load without.
+var required = map[string]string{"APP_NAME": "edge", "DB_HOST": "db.local"}
+
+// withVars returns required plus vars.
+func withVars(vars map[string]string) map[string]string {
+	out := make(map[string]string, len(required)+len(vars))
+	for k, v := range required {
+		out[k] = v
+	}
+	for k, v := range vars {
+		out[k] = v
+	}
+	return out
+}
+
+// loadBoth loads start's copies through Load and LoadConfig from the same
+// Loader and fails unless both end with the same config and error text.
+func loadBoth(t *testing.T, l *astroenv.Loader, start Config) (Config, error) {
+	t.Helper()
+	reflected, generated := start, start
+	if start.Cache != nil {
+		c := *start.Cache
+		generated.Cache = &c
+	}
+	errReflect := l.Load(&reflected)
+	errGenerated := LoadConfig(&generated, l)
+
+	if (errReflect == nil) != (errGenerated == nil) ||
+		errReflect != nil && errReflect.Error() != errGenerated.Error() {
+		t.Fatalf("errors differ:\n  Load:       %v\n  LoadConfig: %v", errReflect, errGenerated)
+	}
+	if errReflect == nil && !reflect.DeepEqual(reflected, generated) {
+		t.Fatalf("configs differ:\n  Load:       %+v\n  LoadConfig: %+v", reflected, generated)
+	}
+	return generated, errGenerated
+}
+
+func TestGeneratedMatchesLoad(t *testing.T) {
+	for name, vars := range map[string]map[string]string{
+		"defaults": {},
+		"all set": {
+			"APP_ENV": "prod", "APP_REGION": "us-east-2", "APP_WORKERS": "16", "APP_SHARD": "-3",
+			"APP_MAX_CONNS": "4000000000", "APP_PORT": "9090", "APP_RATIO": "0.25",
+			"APP_DEBUG": "1", "APP_METRICS": "false", "APP_INTERVAL": "1m30s", "APP_LOG_LEVEL": "warn",
+			"APP_HOSTS": "a, b ,c", "APP_PORTS": "1,2", "APP_DELAYS": "5ms; 1h", "APP_WEIGHTS": "0.5,1.5",
+			"DB_PASSWORD": "hunter2", "APP_PIN": "1234", "APP_TOKENS": "t1,t2", "APP_API_KEY_ID": "7",
+			"DB_PORT": "6543", "CACHE_TTL": "5m", "CACHE_SIZE": "1", "RETRY_ATTEMPTS": "9", "RETRY_BACKOFF": "2s",
+		},
+		"empty lists": {"APP_HOSTS": " ", "APP_PORTS": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := loadBoth(t, astroenv.NewLoaderFromMap(withVars(vars)), Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Cache == nil {
+				t.Error("Cache was not allocated")
+			}
+		})
+	}
+}
+
+func TestGeneratedDefaults(t *testing.T) {
+	cfg, err := loadBoth(t, astroenv.NewLoaderFromMap(required), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Env != "development" || cfg.Region != "eu-west-1" || cfg.Port != 8080 || cfg.Interval != 30*time.Second {
+		t.Errorf("scalar defaults: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Ports, []int{80, 443}) || !reflect.DeepEqual(cfg.Delays, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("slice defaults: Ports=%v Delays=%v", cfg.Ports, cfg.Delays)
+	}
+	if cfg.DB.Port != 5432 || cfg.Cache.Size != 1024 || cfg.Retry.Backoff != 500*time.Millisecond {
+		t.Errorf("nested defaults: DB=%+v Cache=%+v Retry=%+v", cfg.DB, *cfg.Cache, cfg.Retry)
+	}
+}
+
+func TestGeneratedKeepsCallerValues(t *testing.T) {
+	cache := &Cache{}
+	cfg, err := loadBoth(t, astroenv.NewLoaderFromMap(required), Config{Debug: true, Untagged: "kept", Cache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A bool without default keeps its value; untagged fields are left alone.
+	if !cfg.Debug || cfg.Untagged != "kept" {
+		t.Errorf("Debug=%v Untagged=%q", cfg.Debug, cfg.Untagged)
+	}
+}
+
+func TestGeneratedErrorsMatchLoad(t *testing.T) {
+	for name, vars := range map[string]map[string]string{
+		"missing required":    {"APP_NAME": ""},
+		"missing nested":      {"DB_HOST": ""},
+		"bad int":             {"APP_WORKERS": "four"},
+		"uint overflow":       {"APP_PORT": "70000"},
+		"negative uint":       {"APP_MAX_CONNS": "-1"},
+		"bad float":           {"APP_RATIO": "half"},
+		"bad bool":            {"APP_DEBUG": "yes"},
+		"bad duration":        {"APP_INTERVAL": "30"},
+		"bad level":           {"APP_LOG_LEVEL": "loud"},
+		"bad slice element":   {"APP_PORTS": "80,http"},
+		"bad nested pointer":  {"CACHE_SIZE": "big"},
+		"bad inline struct":   {"RETRY_BACKOFF": "soon"},
+		"validation":          {"APP_WORKERS": "0"},
+		"first error wins":    {"APP_WORKERS": "x", "APP_RATIO": "y"},
+		"secret tag":          {"APP_PIN": "12ab"},
+		"secret by key name":  {"APP_API_KEY_ID": "id-99"},
+		"required and secret": {"APP_NAME": "", "APP_PIN": "x"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadBoth(t, astroenv.NewLoaderFromMap(withVars(vars)), Config{})
+			if err == nil {
+				t.Fatal("want an error")
+			}
+		})
+	}
+}
+
+func TestGeneratedValidationError(t *testing.T) {
+	_, err := loadBoth(t, astroenv.NewLoaderFromMap(withVars(map[string]string{"APP_WORKERS": "-1"})), Config{})
+	if !errors.Is(err, ErrInvalidWorkers) {
+		t.Errorf("err = %v, want ErrInvalidWorkers", err)
+	}
+}
+
+func TestGeneratedMasksSecrets(t *testing.T) {
+	for key, value := range map[string]string{
+		"APP_PIN":        "98765x", // secret:"true"
+		"APP_API_KEY_ID": "k-4711", // credential-looking key
+	} {
+		_, err := loadBoth(t, astroenv.NewLoaderFromMap(withVars(map[string]string{key: value})), Config{})
+		if err == nil {
+			t.Fatalf("%s: want an error", key)
+		}
+		if strings.Contains(err.Error(), value) {
+			t.Errorf("%s: error %q leaks the value", key, err)
+		}
+		if !strings.Contains(err.Error(), "****") {
+			t.Errorf("%s: error %q does not show the mask", key, err)
+		}
+	}
+}
+
+func TestGeneratedLookupErrors(t *testing.T) {
+	errDown := errors.New("config service down")
+	src := astroenv.LookupFunc(func(key string) (string, bool, error) {
+		switch key {
+		case "APP_NAME", "DB_HOST":
+			return required[key], true, nil
+		case "APP_REGION":
+			return "", false, errDown
+		}
+		return "", false, nil
+	})
+
+	_, err := loadBoth(t, astroenv.NewLoaderWithLookup(src), Config{})
+	if !errors.Is(err, errDown) || !strings.Contains(err.Error(), `lookup "APP_REGION"`) {
+		t.Errorf("err = %v, want the APP_REGION lookup error", err)
+	}
+}
+
+// TestFixtureUpToDate fails when config_env.go is not what GenerateLoader
+// writes today; run go generate in this directory to refresh it.
+func TestFixtureUpToDate(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "config_env.go")
+	if err := astroenv.GenerateLoader(".", "Config", out); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("config_env.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("config_env.go is stale, run go generate")
+	}
+}
+
+// benchVars sets most variables of Config, like the reload of a real config.
+var benchVars = withVars(map[string]string{
+	"APP_ENV": "prod", "APP_WORKERS": "16", "APP_MAX_CONNS": "4000", "APP_PORT": "9090",
+	"APP_RATIO": "0.25", "APP_INTERVAL": "1m30s", "APP_LOG_LEVEL": "warn",
+	"APP_HOSTS": "a,b,c", "APP_PORTS": "1,2", "APP_DELAYS": "5ms;1h",
+	"DB_PASSWORD": "hunter2", "DB_PORT": "6543", "CACHE_TTL": "5m",
+})
+
+func BenchmarkLoadReflect(b *testing.B) {
+	l := astroenv.NewLoaderFromMap(benchVars)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var cfg Config
+		if err := l.Load(&cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadGenerated(b *testing.B) {
+	l := astroenv.NewLoaderFromMap(benchVars)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var cfg Config
+		if err := LoadConfig(&cfg, l); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrDuplicateKey is returned by LoadAll when two of the structs read the
+// same env variable.
+var ErrDuplicateKey = errors.New("env variable bound by more than one config struct")
+
+// ErrUnknownKey is returned by LoadAll in strict mode for a variable none of
+// the structs reads.
+var ErrUnknownKey = errors.New("env variable not read by any config struct")
+
+// Options configures LoadAll.
+type Options struct {
+	// Files are the .env files to read, see LoadEnvFrom. nil → ./.env, or
+	// the file named by ENV_FILE. Loader.LoadAll ignores it.
+	Files []string
+
+	// Lookup is asked for the keys the environment doesn't set, see
+	// NewLoaderWithLookup. nil → environment only. Loader.LoadAll ignores
+	// it.
+	Lookup Lookup
+
+	// Strict fails the load with one ErrUnknownKey error per variable
+	// starting with one of StrictPrefixes that no struct reads, by key,
+	// envDeprecated alias, indexed slice element or prefix map; see
+	// UnusedKeys. Only the environment snapshot is checked, a Lookup can't
+	// list its keys. No prefixes → every variable, system ones included.
+	Strict         bool
+	StrictPrefixes []string
+
+	// Report, when set, receives where each field of every struct got its
+	// value, Field paths starting with the struct type name. nil → not
+	// recorded.
+	Report *LoadReport
+}
+
+// LoadAll reads .env once, builds one Loader and loads every cfg (pointers
+// to structs) from it, e.g. sub-configs owned by different packages:
+//
+//	err := astroenv.LoadAll(astroenv.Options{Strict: true, StrictPrefixes: []string{"APP_"}}, &logCfg, &rtspCfg, &cryptoCfg)
+func LoadAll(opts Options, cfgs ...interface{}) error {
+	if err := loadDotEnv(opts.Files...); err != nil {
+		return err
+	}
+
+	l := NewLoader()
+	l.src = opts.Lookup
+	return l.LoadAll(opts, cfgs...)
+}
+
+// LoadAll loads every cfg from the Loader's snapshot and source. All structs
+// are loaded even when one fails; the returned error joins one error per
+// failing struct, prefixed with its type, then one ErrDuplicateKey error per
+// env variable read by more than one struct, then in strict mode one
+// ErrUnknownKey error per variable none reads.
+func (l *Loader) LoadAll(opts Options, cfgs ...interface{}) error {
+	var errs []error
+	var types []reflect.Type
+
+	owners := make(map[string][]string) // env key → struct types reading it
+	for _, cfg := range cfgs {
+		name := fmt.Sprintf("%T", cfg)
+		report, err := l.LoadWithReport(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		t := reflect.TypeOf(cfg)
+		if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		types = append(types, t.Elem())
+		for _, key := range envKeys(t.Elem()) {
+			owners[key] = append(owners[key], name)
+		}
+		if opts.Report != nil {
+			for _, f := range report.Fields {
+				f.Field = t.Elem().Name() + "." + f.Field
+				opts.Report.Fields = append(opts.Report.Fields, f)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(owners))
+	for key, names := range owners {
+		if len(names) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		errs = append(errs, fmt.Errorf("%w: %q in %s", ErrDuplicateKey, key, strings.Join(owners[key], ", ")))
+	}
+
+	if opts.Strict {
+		environ := make([]string, 0, len(l.vars))
+		for k, v := range l.vars {
+			environ = append(environ, k+"="+v)
+		}
+		for _, key := range unusedKeys(types, environ, opts.StrictPrefixes) {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrUnknownKey, key))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// envKeys returns the env keys read by struct type t, nested structs and
+// struct pointers included. Prefix maps are left out since they don't own a
+// single key.
+func envKeys(t reflect.Type) []string {
+	return envKeysOf(t, make(map[reflect.Type]bool))
+}
+
+// envKeysOf is envKeys skipping the struct types in visiting, the ones
+// being walked, so recursive types end.
+func envKeysOf(t reflect.Type, visiting map[reflect.Type]bool) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+
+		if nested := nestedType(fieldType.Type); nested != nil {
+			if visiting[nested] {
+				continue
+			}
+			for _, key := range envKeysOf(nested, visiting) {
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+			continue
+		}
+
+		tag := fieldType.Tag.Get("env")
+		if tag == "" || fieldType.Type.Kind() == reflect.Map {
+			continue
+		}
+		key, _, _ := parseTag(tag)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,149 @@
+package astroenv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Three sub-configs with overlapping prefixes: RTSP_ and RTSP_CAM_, and
+// CRYPTO_ reading LOG_LEVEL too.
+type allLogCfg struct {
+	Level string `env:"LOG_LEVEL,info"`
+	Dir   string `env:"LOG_DIR,/var/log"`
+}
+
+type allRTSPCfg struct {
+	URL     string            `env:"RTSP_URL"`
+	Timeout time.Duration     `env:"RTSP_TIMEOUT,5s"`
+	Cameras map[string]string `env:"RTSP_CAM_,prefix"`
+}
+
+type allCryptoCfg struct {
+	Key      SecretString `env:"CRYPTO_KEY"`
+	LogLevel string       `env:"LOG_LEVEL,warn"` // collides with allLogCfg
+}
+
+var allVars = map[string]string{
+	"LOG_LEVEL":      "debug",
+	"RTSP_URL":       "rtsp://cam.local/live",
+	"RTSP_CAM_DOOR":  "rtsp://door.local",
+	"RTSP_CAM_GATE":  "rtsp://gate.local",
+	"CRYPTO_KEY":     "hunter2",
+	"CRYPTO_UNKNOWN": "x",
+	"RTSP_TIMEOUTS":  "3s",
+	"PATH":           "/usr/bin",
+}
+
+func TestLoadAllThreeStructs(t *testing.T) {
+	var logCfg allLogCfg
+	var rtspCfg allRTSPCfg
+	var cryptoCfg allCryptoCfg
+	var report LoadReport
+	err := NewLoaderFromMap(allVars).LoadAll(Options{
+		Strict:         true,
+		StrictPrefixes: []string{"LOG_", "RTSP_", "CRYPTO_"},
+		Report:         &report,
+	}, &logCfg, &rtspCfg, &cryptoCfg)
+
+	// Every struct is loaded from the same snapshot.
+	if logCfg.Level != "debug" || cryptoCfg.LogLevel != "debug" || rtspCfg.URL != "rtsp://cam.local/live" ||
+		len(rtspCfg.Cameras) != 2 || cryptoCfg.Key.Value() != "hunter2" {
+		t.Errorf("loaded %+v %+v %+v", logCfg, rtspCfg, cryptoCfg)
+	}
+
+	// One duplicate key, and the two variables no struct reads; RTSP_CAM_*
+	// is read by the prefix map and PATH is outside the prefixes.
+	if !errors.Is(err, ErrDuplicateKey) || !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("err = %v, want ErrDuplicateKey and ErrUnknownKey", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		`"LOG_LEVEL" in *astroenv.allLogCfg, *astroenv.allCryptoCfg`,
+		`"CRYPTO_UNKNOWN"`,
+		`"RTSP_TIMEOUTS"`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q lacks %q", msg, want)
+		}
+	}
+	for _, unwanted := range []string{"RTSP_CAM_DOOR", "PATH", "hunter2"} {
+		if strings.Contains(msg, unwanted) {
+			t.Errorf("error %q mentions %q", msg, unwanted)
+		}
+	}
+	if n := strings.Count(msg, ErrDuplicateKey.Error()); n != 1 {
+		t.Errorf("%d duplicate key errors, want 1", n)
+	}
+
+	// One report for the three structs.
+	got := make(map[string]string)
+	for _, f := range report.Fields {
+		got[f.Field] = f.Source
+	}
+	want := map[string]string{
+		"allLogCfg.Level":       sourceEnv,
+		"allLogCfg.Dir":         sourceDefault,
+		"allRTSPCfg.URL":        sourceEnv,
+		"allRTSPCfg.Timeout":    sourceDefault,
+		"allCryptoCfg.Key":      sourceEnv,
+		"allCryptoCfg.LogLevel": sourceEnv,
+	}
+	if len(got) != len(want) {
+		t.Errorf("report = %v, want %v", got, want)
+	}
+	for field, source := range want {
+		if got[field] != source {
+			t.Errorf("report %s = %q, want %q", field, got[field], source)
+		}
+	}
+}
+
+func TestLoadAllGroupsErrorsByStruct(t *testing.T) {
+	var logCfg allLogCfg
+	var rtspCfg allRTSPCfg
+	var cryptoCfg allCryptoCfg
+	err := NewLoaderFromMap(map[string]string{"RTSP_URL": "rtsp://x", "RTSP_TIMEOUT": "soon"}).
+		LoadAll(Options{}, &logCfg, &rtspCfg, &cryptoCfg)
+	if err == nil {
+		t.Fatal("want an error")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		`*astroenv.allRTSPCfg: `,
+		`"Timeout"`,
+		`*astroenv.allCryptoCfg: `,
+		`"CRYPTO_KEY"`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q lacks %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "*astroenv.allLogCfg:") {
+		t.Errorf("error %q blames allLogCfg, which loaded", msg)
+	}
+	// The first struct still loaded despite the others failing.
+	if logCfg.Level != "info" {
+		t.Errorf("Level = %q, want the default", logCfg.Level)
+	}
+	// Strict mode is off.
+	if errors.Is(err, ErrUnknownKey) {
+		t.Errorf("err = %v, want no ErrUnknownKey without Strict", err)
+	}
+}
+
+func TestLoadAllStrictCountsEveryStruct(t *testing.T) {
+	vars := map[string]string{"LOG_LEVEL": "debug", "RTSP_URL": "rtsp://x", "RTSP_CAM_A": "a"}
+
+	// RTSP_ keys are unknown to allLogCfg alone but read by allRTSPCfg.
+	var logCfg allLogCfg
+	var rtspCfg allRTSPCfg
+	if err := NewLoaderFromMap(vars).LoadAll(Options{Strict: true}, &logCfg, &rtspCfg); err != nil {
+		t.Errorf("err = %v, want every variable read", err)
+	}
+	err := NewLoaderFromMap(vars).LoadAll(Options{Strict: true}, &logCfg)
+	if !errors.Is(err, ErrUnknownKey) || !strings.Contains(err.Error(), `"RTSP_CAM_A"`) {
+		t.Errorf("err = %v, want RTSP_CAM_A unknown", err)
+	}
+}
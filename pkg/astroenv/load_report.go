@@ -0,0 +1,77 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import "reflect"
+
+// Sources a FieldReport can name, the same as ConfigEntry.Source.
+const (
+	sourceEnv     = "env"
+	sourceDefault = "default"
+)
+
+// FieldReport says where one field got its value during a Load.
+type FieldReport struct {
+	Key     string // env variable name
+	Field   string // dotted Go field path
+	Source  string // "env" (environment or Lookup source) or "default"
+	Default string // the tag default, or a bool's value before loading; masked when secret
+}
+
+// LoadReport lists every field a Load set, in struct order. Prefix maps and
+// unset required_if and required_unless fields are not included.
+type LoadReport struct {
+	Fields []FieldReport
+}
+
+// Defaulted returns the fields that fell back to their default because their
+// key (and its envDeprecated aliases) was unset. A field whose variable is
+// set to the same value as its default is not included: the environment
+// configures it explicitly.
+func (r LoadReport) Defaulted() []FieldReport {
+	var out []FieldReport
+	for _, f := range r.Fields {
+		if f.Source == sourceDefault {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// LoadEnvVaribleWithReport is LoadEnvVarible, also returning where each
+// field got its value, e.g. to audit which defaults an environment relies on:
+//
+//	report, err := astroenv.LoadEnvVaribleWithReport(&cfg)
+//	for _, f := range report.Defaulted() {
+//	    logger.Info().Str("key", f.Key).Str("default", f.Default).Msg("env default in use")
+//	}
+func LoadEnvVaribleWithReport(cfg interface{}) (LoadReport, error) {
+	if err := loadDotEnv(); err != nil {
+		return LoadReport{}, err
+	}
+
+	return NewLoader().LoadWithReport(cfg)
+}
+
+// LoadWithReport is Load, also returning where each field got its value.
+// The report covers the fields set before an error stopped the load.
+func (l *Loader) LoadWithReport(cfg interface{}) (LoadReport, error) {
+	report := &LoadReport{}
+	err := l.load(cfg, &loadState{resolved: make(map[string]string), report: report})
+	return *report, err
+}
+
+// record adds a field to the report of the Load, when one is kept.
+func (st *loadState) record(key string, fieldType reflect.StructField, source, defaultVal string) {
+	if st.report == nil {
+		return
+	}
+	if defaultVal != "" && isSecret(fieldType, key) {
+		defaultVal = maskedValue
+	}
+	st.report.Fields = append(st.report.Fields, FieldReport{
+		Key:     key,
+		Field:   st.path + fieldType.Name,
+		Source:  source,
+		Default: defaultVal,
+	})
+}
@@ -0,0 +1,214 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lookup is an external source of variables, e.g. a config service. ok is
+// false when the source has no value for key; err reports that the source
+// could not be asked.
+type Lookup interface {
+	Lookup(key string) (value string, ok bool, err error)
+}
+
+// LookupFunc adapts a function to Lookup.
+type LookupFunc func(key string) (string, bool, error)
+
+func (f LookupFunc) Lookup(key string) (string, bool, error) { return f(key) }
+
+// NewLoaderWithLookup snapshots the process environment like NewLoader and
+// asks src for every key the environment doesn't set, so env vars override
+// the source. Prefix maps only see the environment, since a Lookup can't list
+// its keys. Source errors fail the Load, joined with its other errors.
+func NewLoaderWithLookup(src Lookup) *Loader {
+	l := NewLoader()
+	l.src = src
+	return l
+}
+
+// Lookup makes a Loader the source of generated loaders (see
+// GenerateLoader): key is read from the snapshot, then from the Lookup
+// source.
+func (l *Loader) Lookup(key string) (string, bool, error) {
+	v, inVars := l.vars[key]
+	if v != "" || l.src == nil {
+		return v, inVars, nil
+	}
+	v, ok, err := l.src.Lookup(key)
+	return v, ok || inVars, err
+}
+
+// =============================
+// Cached Lookup
+// =============================
+
+// defaultLookupTTL is used when CachedLookup gets a ttl of 0.
+const defaultLookupTTL = time.Minute
+
+// LookupCache is a Lookup that memoizes another one per key. Fresh values are
+// served from memory; a value older than the TTL is still served while one
+// background refresh per key fetches a new one. A failed refresh keeps the
+// stale value and is reported to OnError, so loads don't fail because the
+// source blipped; MaxStale bounds how long. Only the first fetch of a key,
+// and the first once its value is past MaxStale, return the source's error;
+// concurrent callers waiting for that fetch share its result.
+type LookupCache struct {
+	// OnError receives refresh errors. It may run on a background goroutine.
+	// nil → errors are only counted.
+	OnError func(key string, err error)
+
+	// MaxStale is how long past the TTL a value is still served while its
+	// refreshes fail. Beyond it, Lookup waits for the source again and
+	// returns its error. 0 → the last value is served for as long as the
+	// source keeps failing.
+	MaxStale time.Duration
+
+	inner Lookup
+	ttl   time.Duration
+	now   func() time.Time // nil → time.Now
+
+	mu       sync.Mutex
+	entries  map[string]*cacheEntry
+	inflight map[string]*lookupCall
+
+	hits, stale, misses, refreshes, errs atomic.Uint64
+}
+
+// cacheEntry is the last value fetched for a key.
+type cacheEntry struct {
+	value   string
+	ok      bool
+	fetched time.Time
+}
+
+// lookupCall is one fetch in progress; done is closed once the result is set.
+type lookupCall struct {
+	done  chan struct{}
+	value string
+	ok    bool
+	err   error
+}
+
+// LookupStats counts LookupCache outcomes.
+type LookupStats struct {
+	Hits      uint64 // served fresh from memory
+	StaleHits uint64 // served past the TTL while refreshing
+	Misses    uint64 // waited for the source
+	Refreshes uint64 // calls made to the source
+	Errors    uint64 // failed calls to the source
+	Keys      int    // keys held
+}
+
+// CachedLookup wraps inner with a per-key cache whose entries stay fresh for
+// ttl. 0 → 1 minute.
+func CachedLookup(inner Lookup, ttl time.Duration) *LookupCache {
+	if ttl <= 0 {
+		ttl = defaultLookupTTL
+	}
+	return &LookupCache{
+		inner:    inner,
+		ttl:      ttl,
+		entries:  make(map[string]*cacheEntry),
+		inflight: make(map[string]*lookupCall),
+	}
+}
+
+// Lookup implements Lookup.
+func (c *LookupCache) Lookup(key string) (string, bool, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && !c.expired(e) {
+		if c.clock().Sub(e.fetched) < c.ttl {
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return e.value, e.ok, nil
+		}
+		if _, busy := c.inflight[key]; !busy {
+			go c.fetch(c.startCall(key), key)
+		}
+		c.mu.Unlock()
+		c.stale.Add(1)
+		return e.value, e.ok, nil
+	}
+
+	call, busy := c.inflight[key]
+	if !busy {
+		call = c.startCall(key)
+	}
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	if !busy {
+		c.fetch(call, key)
+	}
+	<-call.done
+	return call.value, call.ok, call.err
+}
+
+// Stats returns the cache counters.
+func (c *LookupCache) Stats() LookupStats {
+	c.mu.Lock()
+	keys := len(c.entries)
+	c.mu.Unlock()
+	return LookupStats{
+		Hits:      c.hits.Load(),
+		StaleHits: c.stale.Load(),
+		Misses:    c.misses.Load(),
+		Refreshes: c.refreshes.Load(),
+		Errors:    c.errs.Load(),
+		Keys:      keys,
+	}
+}
+
+// startCall registers a fetch of key. c.mu must be held.
+func (c *LookupCache) startCall(key string) *lookupCall {
+	call := &lookupCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	return call
+}
+
+// fetch asks the source for key and stores the result. When the source
+// fails and a previous value exists, that value is kept and served until it
+// is past MaxStale.
+func (c *LookupCache) fetch(call *lookupCall, key string) {
+	c.refreshes.Add(1)
+	value, ok, err := c.inner.Lookup(key)
+
+	c.mu.Lock()
+	prev, cached := c.entries[key]
+	switch {
+	case err == nil:
+		c.entries[key] = &cacheEntry{value: value, ok: ok, fetched: c.clock()}
+		call.value, call.ok = value, ok
+	case cached && !c.expired(prev):
+		call.value, call.ok = prev.value, prev.ok
+	default:
+		delete(c.entries, key)
+		call.err = err
+	}
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(call.done)
+
+	if err != nil {
+		c.errs.Add(1)
+		if c.OnError != nil {
+			c.OnError(key, err)
+		}
+	}
+}
+
+// expired reports whether e is past MaxStale and must not be served. c.mu
+// must be held.
+func (c *LookupCache) expired(e *cacheEntry) bool {
+	return c.MaxStale > 0 && c.clock().Sub(e.fetched) >= c.ttl+c.MaxStale
+}
+
+func (c *LookupCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
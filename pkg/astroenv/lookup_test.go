@@ -0,0 +1,265 @@
+package astroenv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingLookup serves "<key>-v<n>" and counts the calls per key. While
+// gate is set, calls block until it is closed; err fails them.
+type countingLookup struct {
+	mu    sync.Mutex
+	calls map[string]int
+	gate  chan struct{}
+	err   error
+}
+
+func (l *countingLookup) Lookup(key string) (string, bool, error) {
+	l.mu.Lock()
+	if l.calls == nil {
+		l.calls = make(map[string]int)
+	}
+	l.calls[key]++
+	n, gate, err := l.calls[key], l.gate, l.err
+	l.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("%s-v%d", key, n), true, nil
+}
+
+func (l *countingLookup) count(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls[key]
+}
+
+func (l *countingLookup) set(gate chan struct{}, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.gate, l.err = gate, err
+}
+
+// testClock is a settable LookupCache clock.
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// newTestCache returns a cache over inner with a 1 minute TTL on a fake
+// clock.
+func newTestCache(inner Lookup) (*LookupCache, *testClock) {
+	clock := &testClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := CachedLookup(inner, time.Minute)
+	c.now = clock.now
+	return c, clock
+}
+
+// waitIdle waits for the background refreshes of c to end.
+func waitIdle(t *testing.T, c *LookupCache) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		n := len(c.inflight)
+		c.mu.Unlock()
+		if n == 0 {
+			return
+		}
+	}
+	t.Fatal("refresh still running")
+}
+
+// lookupAll runs n concurrent lookups of key and returns their values.
+func lookupAll(t *testing.T, c *LookupCache, key string, n int) []string {
+	t.Helper()
+	values := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _, errs[i] = c.Lookup(key)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err) // may run off the test goroutine
+			break
+		}
+	}
+	return values
+}
+
+func TestLookupCacheOneRefreshPerWindow(t *testing.T) {
+	inner := &countingLookup{}
+	c, clock := newTestCache(inner)
+
+	// Concurrent misses share one fetch.
+	gate := make(chan struct{})
+	inner.set(gate, nil)
+	done := make(chan []string)
+	go func() { done <- lookupAll(t, c, "A", 50) }()
+	for inner.count("A") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // let the others queue behind it
+	close(gate)
+	inner.set(nil, nil)
+	for _, v := range <-done {
+		if v != "A-v1" {
+			t.Fatalf("miss served %q, want A-v1", v)
+		}
+	}
+
+	for window := 1; window <= 3; window++ {
+		// Fresh: no call at all.
+		for _, v := range lookupAll(t, c, "A", 50) {
+			if v != fmt.Sprintf("A-v%d", window) {
+				t.Fatalf("window %d: fresh value %q", window, v)
+			}
+		}
+		if n := inner.count("A"); n != window {
+			t.Fatalf("window %d: %d calls while fresh, want %d", window, n, window)
+		}
+
+		// Stale: served at once while one refresh, held here, runs in the
+		// background.
+		clock.advance(time.Minute)
+		gate := make(chan struct{})
+		inner.set(gate, nil)
+		for _, v := range lookupAll(t, c, "A", 50) {
+			if v != fmt.Sprintf("A-v%d", window) {
+				t.Fatalf("window %d: stale value %q", window, v)
+			}
+		}
+		close(gate)
+		inner.set(nil, nil)
+		waitIdle(t, c)
+		if n := inner.count("A"); n != window+1 {
+			t.Fatalf("window %d: %d calls after expiry, want %d", window, n, window+1)
+		}
+	}
+
+	if stats := c.Stats(); stats.Misses != 50 || stats.Refreshes != 4 || stats.Keys != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestLookupCacheKeysAreIndependent(t *testing.T) {
+	inner := &countingLookup{}
+	c, _ := newTestCache(inner)
+	var wg sync.WaitGroup
+	for _, key := range []string{"A", "B", "C"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			lookupAll(t, c, key, 20)
+		}(key)
+	}
+	wg.Wait()
+	for _, key := range []string{"A", "B", "C"} {
+		if n := inner.count(key); n != 1 {
+			t.Errorf("%s: %d calls, want 1", key, n)
+		}
+	}
+}
+
+func TestLookupCacheServesStaleOnError(t *testing.T) {
+	inner := &countingLookup{}
+	c, clock := newTestCache(inner)
+	var reported []string
+	var mu sync.Mutex
+	c.OnError = func(key string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, key)
+	}
+
+	if v, _, _ := c.Lookup("A"); v != "A-v1" {
+		t.Fatalf("first lookup = %q", v)
+	}
+	errDown := errors.New("source down")
+	inner.set(nil, errDown)
+
+	// With no MaxStale the value outlives any outage.
+	for i := 0; i < 3; i++ {
+		clock.advance(time.Hour)
+		v, ok, err := c.Lookup("A")
+		if err != nil || !ok || v != "A-v1" {
+			t.Fatalf("after %dh: %q, %v, %v; want the stale value", i+1, v, ok, err)
+		}
+		waitIdle(t, c)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 3 || c.Stats().Errors != 3 {
+		t.Errorf("OnError got %q, stats %+v; want 3 errors", reported, c.Stats())
+	}
+}
+
+func TestLookupCacheMaxStale(t *testing.T) {
+	inner := &countingLookup{}
+	c, clock := newTestCache(inner)
+	c.MaxStale = 10 * time.Minute
+
+	if _, _, err := c.Lookup("A"); err != nil {
+		t.Fatal(err)
+	}
+	errDown := errors.New("source down")
+	inner.set(nil, errDown)
+
+	// Within TTL+MaxStale the stale value is served.
+	clock.advance(10 * time.Minute)
+	if v, _, err := c.Lookup("A"); err != nil || v != "A-v1" {
+		t.Fatalf("within MaxStale: %q, %v", v, err)
+	}
+	waitIdle(t, c)
+
+	// Past it, the lookup waits for the source and fails with it.
+	clock.advance(time.Minute)
+	if _, _, err := c.Lookup("A"); !errors.Is(err, errDown) {
+		t.Fatalf("past MaxStale: err = %v, want the source error", err)
+	}
+	if keys := c.Stats().Keys; keys != 0 {
+		t.Errorf("%d keys held, want the expired one dropped", keys)
+	}
+
+	// Once the source is back the key is cached again.
+	inner.set(nil, nil)
+	if v, _, err := c.Lookup("A"); err != nil || v != "A-v4" {
+		t.Errorf("after recovery: %q, %v", v, err)
+	}
+}
+
+func TestLookupCacheFirstFetchError(t *testing.T) {
+	inner := &countingLookup{err: errors.New("source down")}
+	c, _ := newTestCache(inner)
+	if _, _, err := c.Lookup("A"); err == nil {
+		t.Fatal("first fetch error not returned")
+	}
+	// Errors are not cached.
+	inner.set(nil, nil)
+	if v, _, err := c.Lookup("A"); err != nil || v != "A-v2" {
+		t.Errorf("after the error: %q, %v", v, err)
+	}
+}
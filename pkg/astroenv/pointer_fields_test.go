@@ -0,0 +1,129 @@
+package astroenv
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type pointerLimits struct {
+	Burst   *int           `env:"LIMIT_BURST"`
+	Timeout *time.Duration `env:"LIMIT_TIMEOUT,5s"`
+}
+
+type pointerConfig struct {
+	Name       *string        `env:"PTR_NAME"`
+	MaxRetries *int           `env:"PTR_MAX_RETRIES"`
+	Enabled    *bool          `env:"PTR_ENABLED"`
+	Ratio      *float64       `env:"PTR_RATIO"`
+	Interval   *time.Duration `env:"PTR_INTERVAL"`
+	Limits     pointerLimits
+}
+
+func loadPointerConfig(t *testing.T, vars map[string]string) pointerConfig {
+	t.Helper()
+	var cfg pointerConfig
+	if err := NewLoaderFromMap(vars).Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestPointerFieldsSet(t *testing.T) {
+	cfg := loadPointerConfig(t, map[string]string{
+		"PTR_NAME":        "cam",
+		"PTR_MAX_RETRIES": "3",
+		"PTR_ENABLED":     "true",
+		"PTR_RATIO":       "0.75",
+		"PTR_INTERVAL":    "1m30s",
+		"LIMIT_BURST":     "10",
+		"LIMIT_TIMEOUT":   "2s",
+	})
+
+	if cfg.Name == nil || *cfg.Name != "cam" {
+		t.Errorf("Name = %v", cfg.Name)
+	}
+	if cfg.MaxRetries == nil || *cfg.MaxRetries != 3 {
+		t.Errorf("MaxRetries = %v", cfg.MaxRetries)
+	}
+	if cfg.Enabled == nil || !*cfg.Enabled {
+		t.Errorf("Enabled = %v", cfg.Enabled)
+	}
+	if cfg.Ratio == nil || *cfg.Ratio != 0.75 {
+		t.Errorf("Ratio = %v", cfg.Ratio)
+	}
+	if cfg.Interval == nil || *cfg.Interval != 90*time.Second {
+		t.Errorf("Interval = %v", cfg.Interval)
+	}
+	if cfg.Limits.Burst == nil || *cfg.Limits.Burst != 10 {
+		t.Errorf("Limits.Burst = %v", cfg.Limits.Burst)
+	}
+	if cfg.Limits.Timeout == nil || *cfg.Limits.Timeout != 2*time.Second {
+		t.Errorf("Limits.Timeout = %v", cfg.Limits.Timeout)
+	}
+}
+
+func TestPointerFieldsUnsetStayNil(t *testing.T) {
+	cfg := loadPointerConfig(t, nil)
+
+	if cfg.Name != nil || cfg.MaxRetries != nil || cfg.Enabled != nil || cfg.Ratio != nil || cfg.Interval != nil {
+		t.Errorf("unset pointers were allocated: %+v", cfg)
+	}
+	if cfg.Limits.Burst != nil {
+		t.Errorf("Limits.Burst = %v, want nil", *cfg.Limits.Burst)
+	}
+	// A tag default still gives a non-nil pointer.
+	if cfg.Limits.Timeout == nil || *cfg.Limits.Timeout != 5*time.Second {
+		t.Errorf("Limits.Timeout = %v, want the 5s default", cfg.Limits.Timeout)
+	}
+}
+
+func TestPointerFieldsZeroValues(t *testing.T) {
+	cfg := loadPointerConfig(t, map[string]string{
+		"PTR_NAME":        "",
+		"PTR_MAX_RETRIES": "0",
+		"PTR_ENABLED":     "false",
+		"PTR_RATIO":       "0",
+		"PTR_INTERVAL":    "0s",
+	})
+
+	// Set to zero is not the same as unset.
+	if cfg.Name == nil || *cfg.Name != "" {
+		t.Errorf("Name = %v, want a pointer to \"\"", cfg.Name)
+	}
+	if cfg.MaxRetries == nil || *cfg.MaxRetries != 0 {
+		t.Errorf("MaxRetries = %v, want a pointer to 0", cfg.MaxRetries)
+	}
+	if cfg.Enabled == nil || *cfg.Enabled {
+		t.Errorf("Enabled = %v, want a pointer to false", cfg.Enabled)
+	}
+	if cfg.Ratio == nil || *cfg.Ratio != 0 {
+		t.Errorf("Ratio = %v, want a pointer to 0", cfg.Ratio)
+	}
+	if cfg.Interval == nil || *cfg.Interval != 0 {
+		t.Errorf("Interval = %v, want a pointer to 0", cfg.Interval)
+	}
+}
+
+func TestPointerFieldDefaultTag(t *testing.T) {
+	var cfg struct {
+		Port *int `env:"PTR_PORT" default:"8080"`
+	}
+	if err := NewLoaderFromMap(nil).Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port == nil || *cfg.Port != 8080 {
+		t.Errorf("Port = %v, want a pointer to 8080", cfg.Port)
+	}
+}
+
+func TestPointerFieldParseError(t *testing.T) {
+	var cfg pointerConfig
+	err := NewLoaderFromMap(map[string]string{"PTR_MAX_RETRIES": "three"}).Load(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "MaxRetries") {
+		t.Fatalf("err = %v, want a parse error naming MaxRetries", err)
+	}
+	if cfg.MaxRetries != nil {
+		t.Error("MaxRetries allocated despite the parse error")
+	}
+}
@@ -0,0 +1,83 @@
+package astroenv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type prefixConfig struct {
+	Host   string            `env:"LABEL_HOST,localhost"`
+	Labels map[string]string `env:"LABEL_,prefix"`
+	Raw    map[string]string `env:"RAW_,prefix,keepcase"`
+}
+
+func TestPrefixMapCollidesWithTaggedField(t *testing.T) {
+	var cfg prefixConfig
+	err := NewLoaderFromMap(map[string]string{
+		"LABEL_HOST": "cam.local",
+		"LABEL_TEAM": "video",
+		"LABEL_":     "prefix alone",
+	}).Load(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "cam.local" {
+		t.Errorf("Host = %q, want the tagged key's value", cfg.Host)
+	}
+	want := map[string]string{"host": "cam.local", "team": "video"}
+	if !reflect.DeepEqual(cfg.Labels, want) {
+		t.Errorf("Labels = %v, want %v", cfg.Labels, want)
+	}
+	if cfg.Raw == nil || len(cfg.Raw) != 0 {
+		t.Errorf("Raw = %#v, want an empty map", cfg.Raw)
+	}
+}
+
+func TestPrefixMapValuesWithEquals(t *testing.T) {
+	// Through the process environment, split on the first "=" only.
+	t.Setenv("LABEL_DSN", "host=db user=app sslmode=disable")
+	t.Setenv("LABEL_EMPTY", "")
+	t.Setenv("RAW_Query", "a=b&c==d")
+	var cfg prefixConfig
+	if err := NewLoader().Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Labels["dsn"]; got != "host=db user=app sslmode=disable" {
+		t.Errorf("dsn = %q", got)
+	}
+	if got, ok := cfg.Labels["empty"]; !ok || got != "" {
+		t.Errorf("empty = %q, %v; want an empty value", got, ok)
+	}
+	if got := cfg.Raw["Query"]; got != "a=b&c==d" {
+		t.Errorf("Query = %q", got)
+	}
+	for k := range cfg.Labels {
+		if strings.Contains(k, "=") {
+			t.Errorf("key %q holds part of a value", k)
+		}
+	}
+}
+
+func TestPrefixMapIgnoresLookupSource(t *testing.T) {
+	var asked []string
+	src := LookupFunc(func(key string) (string, bool, error) {
+		asked = append(asked, key)
+		return "from-source", true, nil
+	})
+	var cfg prefixConfig
+	if err := NewLoaderWithLookup(src).Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "from-source" {
+		t.Errorf("Host = %q, want the source's value", cfg.Host)
+	}
+	if _, ok := cfg.Labels["host"]; ok {
+		t.Errorf("Labels = %v: the source was listed", cfg.Labels)
+	}
+	for _, key := range asked {
+		if key == "LABEL_" || key == "RAW_" {
+			t.Errorf("the source was asked for the prefix %q", key)
+		}
+	}
+}
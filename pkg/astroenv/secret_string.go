@@ -0,0 +1,66 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"reflect"
+)
+
+// SecretString holds a secret loaded from the environment. The value lives
+// in a byte slice that Zero overwrites, and every fmt verb, String and JSON
+// encoding print "****" instead of it, so it can't leak through logs or
+// config dumps.
+//
+//	type Config struct {
+//	    APIToken astroenv.SecretString `env:"API_TOKEN"`
+//	}
+type SecretString struct {
+	b []byte
+}
+
+var secretStringType = reflect.TypeOf(SecretString{})
+
+// NewSecretString copies s into a new SecretString.
+func NewSecretString(s string) SecretString {
+	return SecretString{b: []byte(s)}
+}
+
+// Value returns the secret. The returned string is a copy that Zero can't
+// wipe, so keep it short-lived.
+func (s SecretString) Value() string {
+	return string(s.b)
+}
+
+// IsZero reports whether the secret is empty or has been zeroed.
+func (s SecretString) IsZero() bool {
+	return len(s.b) == 0
+}
+
+// Equal compares the secret with other in constant time, for token checks.
+func (s SecretString) Equal(other string) bool {
+	return subtle.ConstantTimeCompare(s.b, []byte(other)) == 1
+}
+
+// Zero overwrites the secret in memory and empties it.
+func (s *SecretString) Zero() {
+	for i := range s.b {
+		s.b[i] = 0
+	}
+	s.b = nil
+}
+
+// String always returns the mask.
+func (s SecretString) String() string {
+	return maskedValue
+}
+
+// Format prints the mask for every verb, including %+v and %#v.
+func (s SecretString) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(maskedValue))
+}
+
+// MarshalJSON always encodes the mask.
+func (s SecretString) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + maskedValue + `"`), nil
+}
@@ -0,0 +1,111 @@
+package astroenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testToken = "s3cr3t-t0ken"
+
+type secretConfig struct {
+	Name   string         `env:"APP_NAME"`
+	Token  SecretString   `env:"API_TOKEN"`
+	Backup *SecretString  `env:"BACKUP_TOKEN,"`
+	Keys   []SecretString `env:"API_KEYS"`
+}
+
+func loadSecretConfig(t *testing.T) secretConfig {
+	t.Helper()
+	var cfg secretConfig
+	err := NewLoaderFromMap(map[string]string{
+		"APP_NAME":     "svc",
+		"API_TOKEN":    testToken,
+		"BACKUP_TOKEN": "backup-" + testToken,
+		"API_KEYS":     "k1-" + testToken + ",k2-" + testToken,
+	}).Load(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestSecretStringPopulation(t *testing.T) {
+	cfg := loadSecretConfig(t)
+
+	if got := cfg.Token.Value(); got != testToken {
+		t.Errorf("Token = %q, want %q", got, testToken)
+	}
+	if cfg.Backup == nil || cfg.Backup.Value() != "backup-"+testToken {
+		t.Errorf("Backup = %v, want it set", cfg.Backup)
+	}
+	if len(cfg.Keys) != 2 || cfg.Keys[1].Value() != "k2-"+testToken {
+		t.Errorf("Keys has %d elements", len(cfg.Keys))
+	}
+}
+
+func TestSecretStringMasking(t *testing.T) {
+	cfg := loadSecretConfig(t)
+
+	for _, verb := range []string{"%v", "%s", "%+v", "%#v", "%q", "%x"} {
+		for name, v := range map[string]any{"value": cfg.Token, "pointer": cfg.Backup, "struct": cfg} {
+			out := fmt.Sprintf(verb, v)
+			if strings.Contains(out, testToken) {
+				t.Errorf("%s of %s leaks the secret: %s", verb, name, out)
+			}
+		}
+	}
+	if got := cfg.Token.String(); got != maskedValue {
+		t.Errorf("String() = %q, want %q", got, maskedValue)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testToken) {
+		t.Errorf("JSON leaks the secret: %s", data)
+	}
+
+	dump, err := Dump(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dump, testToken) || !strings.Contains(dump, maskedValue) {
+		t.Errorf("Dump does not mask the secret:\n%s", dump)
+	}
+}
+
+func TestSecretStringZero(t *testing.T) {
+	s := NewSecretString(testToken)
+	buf := s.b
+
+	s.Zero()
+	if !s.IsZero() || s.Value() != "" {
+		t.Errorf("Value() = %q after Zero", s.Value())
+	}
+	for i, b := range buf {
+		if b != 0 {
+			t.Fatalf("byte %d = %q after Zero, want 0", i, b)
+		}
+	}
+
+	s.Zero() // zeroing twice is harmless
+}
+
+func TestSecretStringEqual(t *testing.T) {
+	s := NewSecretString(testToken)
+
+	if !s.Equal(testToken) {
+		t.Error("Equal rejects the secret")
+	}
+	for _, other := range []string{"", testToken[:len(testToken)-1], testToken + "x", strings.ToUpper(testToken)} {
+		if s.Equal(other) {
+			t.Errorf("Equal(%q) = true", other)
+		}
+	}
+	if !(SecretString{}).Equal("") {
+		t.Error("an empty secret does not equal \"\"")
+	}
+}
@@ -0,0 +1,147 @@
+// ================ Version : V1.1.0 ===========
+package astroenv
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ListKeys returns the env keys cfg (a struct or a pointer to one) reads, in
+// struct order, nested structs included. Deprecated aliases and prefix maps
+// are left out; UnusedKeys accounts for them.
+func ListKeys(cfg interface{}) []string {
+	t := structType(cfg)
+	if t == nil {
+		return nil
+	}
+	return envKeys(t)
+}
+
+// UnusedKeys returns the variables of environ ("KEY=value" entries, as from
+// os.Environ) starting with one of prefixes that no field of cfg would read,
+// sorted, e.g. leftovers in deployment manifests:
+//
+//	if unused := astroenv.UnusedKeys(&cfg, os.Environ(), "APP_"); len(unused) > 0 { … }
+//
+// A variable counts as read when it is a field's key or one of its
+// `envDeprecated` aliases, an indexed element of a slice field's key
+// (HOSTS_0, HOSTS_1, … for `env:"HOSTS"`, as some charts spell lists), or
+// when it starts with the prefix of a map[string]string `prefix` field. With
+// no prefixes every variable is checked, including those of the system.
+func UnusedKeys(cfg interface{}, environ []string, prefixes ...string) []string {
+	var types []reflect.Type
+	if t := structType(cfg); t != nil {
+		types = append(types, t)
+	}
+	return unusedKeys(types, environ, prefixes)
+}
+
+// unusedKeys is UnusedKeys for variables read by none of the struct types.
+func unusedKeys(types []reflect.Type, environ []string, prefixes []string) []string {
+	known := make(map[string]bool)
+	indexed := make(map[string]bool)
+	var catchAll []string
+	for _, t := range types {
+		catchAll = append(catchAll, usedKeys(t, known, indexed, make(map[reflect.Type]bool))...)
+	}
+
+	seen := make(map[string]bool)
+	var unused []string
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		if key == "" || seen[key] || known[key] || isIndexed(key, indexed) || !hasAnyPrefix(key, prefixes) {
+			continue
+		}
+		seen[key] = true
+		if len(catchAll) == 0 || !hasAnyPrefix(key, catchAll) {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// usedKeys adds the keys and aliases read by struct type t to known, those
+// of its slice fields to indexed as well, and returns the prefixes of its
+// prefix maps. Struct types in visiting are being walked and skipped.
+func usedKeys(t reflect.Type, known, indexed map[string]bool, visiting map[reflect.Type]bool) []string {
+	var catchAll []string
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+
+		if nested := nestedType(fieldType.Type); nested != nil {
+			if !visiting[nested] {
+				catchAll = append(catchAll, usedKeys(nested, known, indexed, visiting)...)
+			}
+			continue
+		}
+
+		tag := fieldType.Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		key, _, _ := parseTag(tag)
+		if fieldType.Type.Kind() == reflect.Map {
+			catchAll = append(catchAll, key)
+			continue
+		}
+		names := []string{key}
+		for _, old := range strings.Split(fieldType.Tag.Get("envDeprecated"), ",") {
+			if old = strings.TrimSpace(old); old != "" {
+				names = append(names, old)
+			}
+		}
+		for _, name := range names {
+			known[name] = true
+			if fieldType.Type.Kind() == reflect.Slice {
+				indexed[name] = true
+			}
+		}
+	}
+	return catchAll
+}
+
+// isIndexed reports whether key is KEY_<n> for a slice key KEY in indexed.
+func isIndexed(key string, indexed map[string]bool) bool {
+	i := strings.LastIndexByte(key, '_')
+	if i <= 0 || i == len(key)-1 || !indexed[key[:i]] {
+		return false
+	}
+	for _, c := range key[i+1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// structType returns the struct type of cfg or of the struct it points to,
+// nil for anything else.
+func structType(cfg interface{}) reflect.Type {
+	t := reflect.TypeOf(cfg)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// hasAnyPrefix reports whether key starts with one of prefixes. An empty
+// list matches every key.
+func hasAnyPrefix(key string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
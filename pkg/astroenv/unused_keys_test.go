@@ -0,0 +1,88 @@
+package astroenv
+
+import (
+	"reflect"
+	"testing"
+)
+
+type unusedDB struct {
+	URL string `env:"APP_DB_URL" envDeprecated:"APP_DATABASE_URL, APP_PG_URL"`
+}
+
+type unusedConfig struct {
+	Name   string            `env:"APP_NAME"`
+	Port   int               `env:"APP_PORT,8080"`
+	Hosts  []string          `env:"APP_HOSTS" envDeprecated:"APP_SERVERS"`
+	Labels map[string]string `env:"APP_LABEL_,prefix"`
+	DB     unusedDB
+	Cache  *struct {
+		TTL string `env:"APP_CACHE_TTL,1m"`
+	}
+}
+
+// unusedEnviron is a fixture covering every way a variable can be read, and
+// near misses of each.
+var unusedEnviron = []string{
+	"APP_NAME=edge",                // key
+	"APP_PORT=9090",                // key
+	"APP_DB_URL=postgres://",       // nested key
+	"APP_DATABASE_URL=postgres://", // alias
+	"APP_PG_URL=postgres://",       // alias after a space in the tag
+	"APP_CACHE_TTL=5m",             // key in a struct pointer
+	"APP_HOSTS_0=a",                // indexed slice
+	"APP_HOSTS_12=b",               // indexed slice
+	"APP_SERVERS_1=c",              // indexed alias of a slice
+	"APP_LABEL_TEAM=video",         // map prefix
+	"HOME=/root",                   // outside the prefixes
+
+	"APP_OLD_FLAG=1",  // left behind
+	"APP_HOSTS_=a",    // no index
+	"APP_HOSTS_X=a",   // not a number
+	"APP_HOSTS_1_2=a", // nested index
+	"APP_PORT_0=1",    // index of a scalar
+	"APP_NAMES=x",     // longer than a key
+	"APP_DB_URL_0=x",  // index of a scalar in a nested struct
+	"APP_OLD_FLAG=2",  // duplicate
+	"APP_LABEL=x",     // map prefix without its underscore
+	"=no key",         // malformed
+}
+
+func TestUnusedKeysFixture(t *testing.T) {
+	got := UnusedKeys(&unusedConfig{}, unusedEnviron, "APP_")
+	want := []string{
+		"APP_DB_URL_0", "APP_HOSTS_", "APP_HOSTS_1_2", "APP_HOSTS_X",
+		"APP_LABEL", "APP_NAMES", "APP_OLD_FLAG", "APP_PORT_0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnusedKeys = %q, want %q", got, want)
+	}
+
+	// A struct value works like a pointer.
+	if byValue := UnusedKeys(unusedConfig{}, unusedEnviron, "APP_"); !reflect.DeepEqual(byValue, want) {
+		t.Errorf("UnusedKeys(value) = %q, want %q", byValue, want)
+	}
+}
+
+func TestUnusedKeysPrefixes(t *testing.T) {
+	if got := UnusedKeys(&unusedConfig{}, unusedEnviron, "APP_HOSTS", "APP_PORT"); !reflect.DeepEqual(got,
+		[]string{"APP_HOSTS_", "APP_HOSTS_1_2", "APP_HOSTS_X", "APP_PORT_0"}) {
+		t.Errorf("two prefixes: %q", got)
+	}
+
+	// Without prefixes, system variables are checked too.
+	got := UnusedKeys(&unusedConfig{}, unusedEnviron)
+	found := false
+	for _, key := range got {
+		found = found || key == "HOME"
+	}
+	if !found {
+		t.Errorf("no prefixes: %q lacks HOME", got)
+	}
+}
+
+func TestUnusedKeysNotAStruct(t *testing.T) {
+	got := UnusedKeys(42, []string{"APP_A=1", "APP_B=2"}, "APP_")
+	if !reflect.DeepEqual(got, []string{"APP_A", "APP_B"}) {
+		t.Errorf("UnusedKeys(42) = %q, want every key", got)
+	}
+}
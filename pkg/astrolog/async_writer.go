@@ -0,0 +1,180 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultAsyncQueueSize      = 10000
+	defaultAsyncReportInterval = 10 * time.Second
+)
+
+// =============================
+// Async Writer
+// =============================
+
+type asyncEntry struct {
+	level zerolog.Level
+	p     []byte
+}
+
+// AsyncWriterWithLevel hands entries to a background goroutine that writes
+// them to Out, so logging calls return without waiting on the disk. When the
+// queue is full new entries are dropped (and counted); the count is reported
+// through Out every report interval. Fatal and panic entries wait for the
+// queue to drain because the process is about to die.
+type AsyncWriterWithLevel struct {
+	Out zerolog.LevelWriter
+
+	queue    chan asyncEntry
+	flush    chan chan struct{}
+	interval time.Duration
+	dropped  atomic.Uint64
+	reported uint64 // dropped count at the last report, owned by run
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newAsyncWriter(out zerolog.LevelWriter, cfg CofigLogger) *AsyncWriterWithLevel {
+	queueSize := cfg.AsyncQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultAsyncQueueSize
+	}
+	interval := cfg.AsyncReportInterval
+	if interval <= 0 {
+		interval = defaultAsyncReportInterval
+	}
+
+	w := &AsyncWriterWithLevel{
+		Out:      out,
+		queue:    make(chan asyncEntry, queueSize),
+		flush:    make(chan chan struct{}),
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriterWithLevel) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *AsyncWriterWithLevel) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	// After Close there is nobody left to drain the queue.
+	select {
+	case <-w.done:
+		return w.Out.WriteLevel(level, p)
+	default:
+	}
+
+	// zerolog reuses p after Write returns.
+	entry := asyncEntry{level: level, p: append([]byte(nil), p...)}
+
+	if level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		select {
+		case w.queue <- entry:
+			w.Flush()
+		case <-w.done:
+			_, _ = w.Out.WriteLevel(level, entry.p)
+		}
+		return len(p), nil
+	}
+
+	select {
+	case w.queue <- entry:
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Flush blocks until every entry queued before the call has been written.
+func (w *AsyncWriterWithLevel) Flush() {
+	ack := make(chan struct{})
+	select {
+	case w.flush <- ack:
+		<-ack
+	case <-w.done:
+	}
+}
+
+// Dropped returns how many entries were discarded because the queue was full.
+func (w *AsyncWriterWithLevel) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close stops the background loop after writing what is still queued.
+func (w *AsyncWriterWithLevel) Close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+	return nil
+}
+
+func (w *AsyncWriterWithLevel) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry := <-w.queue:
+			_, _ = w.Out.WriteLevel(entry.level, entry.p)
+		case ack := <-w.flush:
+			w.drain()
+			close(ack)
+		case <-ticker.C:
+			w.report()
+		case <-w.stop:
+			w.drain()
+			w.report()
+			return
+		}
+	}
+}
+
+// drain writes every queued entry without blocking for new ones.
+func (w *AsyncWriterWithLevel) drain() {
+	for {
+		select {
+		case entry := <-w.queue:
+			_, _ = w.Out.WriteLevel(entry.level, entry.p)
+		default:
+			return
+		}
+	}
+}
+
+// report writes a warning through Out when entries were dropped since the
+// last report.
+func (w *AsyncWriterWithLevel) report() {
+	total := w.dropped.Load()
+	if total == w.reported {
+		return
+	}
+	logger := zerolog.New(w.Out)
+	logger.Warn().
+		Timestamp().
+		Uint64("dropped", total-w.reported).
+		Uint64("dropped_total", total).
+		Msg("Async log queue full, entries dropped")
+	w.reported = total
+}
+
+// AsyncDropped returns how many entries the current async writer has
+// dropped, or 0 when Async is off.
+func AsyncDropped() uint64 {
+	mu.Lock()
+	defer mu.Unlock()
+	if global == nil || global.async == nil {
+		return 0
+	}
+	return global.async.Dropped()
+}
@@ -0,0 +1,84 @@
+package astrolog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog/log"
+)
+
+func TestCloseWritesEveryLine(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprintf("async=%v", async), func(t *testing.T) {
+			logDir := initTestLogger(t, CofigLogger{
+				LogLevel:            "info",
+				LogToFile:           true,
+				LogFileName:         "app",
+				Formatted:           true,
+				Async:               async,
+				DisableRunSeparator: true,
+			})
+
+			const n = 500
+			for i := 0; i < n; i++ {
+				log.Info().Int("i", i).Msg("line")
+			}
+			if err := Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			lines := logLines(t, logDir)
+			if len(lines) != n {
+				t.Fatalf("file has %d lines, want %d", len(lines), n)
+			}
+			if !strings.Contains(lines[n-1], fmt.Sprintf(`"i":%d`, n-1)) {
+				t.Errorf("last line = %s, want entry %d", lines[n-1], n-1)
+			}
+		})
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	logDir := initTestLogger(t, CofigLogger{
+		LogLevel:            "info",
+		LogToFile:           true,
+		LogFileName:         "app",
+		Formatted:           true,
+		Async:               true,
+		DisableRunSeparator: true,
+	})
+	log.Info().Msg("before close")
+
+	if err := Close(); err != nil {
+		t.Fatalf("first Close: %v", err)
+	}
+	if err := Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	// Late entries must neither panic nor reach the closed file.
+	log.Info().Msg("after close")
+	Flush()
+
+	lines := logLines(t, logDir)
+	if len(lines) != 1 || !strings.Contains(lines[0], "before close") {
+		t.Errorf("file lines = %q, want only the entry before Close", lines)
+	}
+}
+
+func TestCloseWithoutInit(t *testing.T) {
+	mu.Lock()
+	prev := global
+	global = nil
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		global = prev
+		mu.Unlock()
+	}()
+
+	if err := Close(); err != nil {
+		t.Errorf("Close before InitLogger: %v", err)
+	}
+}
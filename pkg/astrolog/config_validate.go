@@ -0,0 +1,94 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrInvalidConfig is returned by CofigLogger.Validate for settings that
+// would otherwise be silently replaced by a default.
+var ErrInvalidConfig = errors.New("invalid logger config")
+
+// =============================
+// Config validation
+// =============================
+
+// Validate reports the settings of cfg that InitLogger and New would
+// otherwise ignore or replace: unknown level names, rotation modes, field
+// layouts and Transformers outputs, negative sizes, counts and intervals,
+// and LogToFile without a LogFileName. Zero values mean "use the default" and are valid. The
+// returned error wraps ErrInvalidConfig and lists every problem.
+func (cfg CofigLogger) Validate() error {
+	var problems []string
+
+	checkLevel := func(name, level string) {
+		if level == "" {
+			return
+		}
+		if _, err := zerolog.ParseLevel(strings.ToLower(level)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: unknown level %q", name, level))
+		}
+	}
+	checkLevel("LogLevel", cfg.LogLevel)
+	checkLevel("WebhookLevel", cfg.WebhookLevel)
+
+	if cfg.LogToFile && strings.TrimSpace(cfg.LogFileName) == "" {
+		problems = append(problems, "LogFileName: empty while LogToFile is set")
+	}
+
+	switch cfg.RotationMode {
+	case "", RotationDaily, RotationPerRun:
+	default:
+		problems = append(problems, fmt.Sprintf("RotationMode: unknown mode %q", cfg.RotationMode))
+	}
+	switch cfg.FieldsLayout {
+	case "", FieldsInline, FieldsAligned, FieldsMultiline:
+	default:
+		problems = append(problems, fmt.Sprintf("FieldsLayout: unknown layout %q", cfg.FieldsLayout))
+	}
+
+	for output := range cfg.Transformers {
+		switch output {
+		case OutputConsole, OutputFile, OutputSyslog, OutputRemote, OutputWebhook:
+		default:
+			problems = append(problems, fmt.Sprintf("Transformers: unknown output %q", output))
+		}
+	}
+
+	for _, f := range []struct {
+		name     string
+		negative bool
+		value    any
+	}{
+		{"MaxFileSize", cfg.MaxFileSize < 0, cfg.MaxFileSize},
+		{"MaxBackups", cfg.MaxBackups < 0, cfg.MaxBackups},
+		{"MaxLogFiles", cfg.MaxLogFiles < 0, cfg.MaxLogFiles},
+		{"MaxAgeDays", cfg.MaxAgeDays < 0, cfg.MaxAgeDays},
+		{"MaxTotalLogBytes", cfg.MaxTotalLogBytes < 0, cfg.MaxTotalLogBytes},
+		{"DiskGuardInterval", cfg.DiskGuardInterval < 0, cfg.DiskGuardInterval},
+		{"WebhookFlushInterval", cfg.WebhookFlushInterval < 0, cfg.WebhookFlushInterval},
+		{"WebhookQueueSize", cfg.WebhookQueueSize < 0, cfg.WebhookQueueSize},
+		{"MaxFieldLength", cfg.MaxFieldLength < 0, cfg.MaxFieldLength},
+		{"MaskKeepLast", cfg.MaskKeepLast < 0, cfg.MaskKeepLast},
+		{"FieldWidth", cfg.FieldWidth < 0, cfg.FieldWidth},
+		{"CallerSkipFrames", cfg.CallerSkipFrames < 0, cfg.CallerSkipFrames},
+		{"AsyncQueueSize", cfg.AsyncQueueSize < 0, cfg.AsyncQueueSize},
+		{"RemoteQueueSize", cfg.RemoteQueueSize < 0, cfg.RemoteQueueSize},
+		{"RemoteMaxBackoff", cfg.RemoteMaxBackoff < 0, cfg.RemoteMaxBackoff},
+		{"ShutdownTimeout", cfg.ShutdownTimeout < 0, cfg.ShutdownTimeout},
+		{"AsyncReportInterval", cfg.AsyncReportInterval < 0, cfg.AsyncReportInterval},
+	} {
+		if f.negative {
+			problems = append(problems, fmt.Sprintf("%s: must not be negative, got %v", f.name, f.value))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
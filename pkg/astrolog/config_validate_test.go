@@ -0,0 +1,47 @@
+package astrolog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestValidate(t *testing.T) {
+	if err := (CofigLogger{LogLevel: "debug"}).Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	err := CofigLogger{
+		LogLevel:    "dbug",
+		LogToFile:   true,
+		MaxFileSize: -1,
+	}.Validate()
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig", err)
+	}
+	for _, want := range []string{`unknown level "dbug"`, "LogFileName: empty", "MaxFileSize: must not be negative"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %s", err, want)
+		}
+	}
+}
+
+func TestInitLoggerEReportsInvalidConfig(t *testing.T) {
+	initTestLogger(t, CofigLogger{LogLevel: "info"})
+
+	err := InitLoggerE(CofigLogger{LogLevel: "dbug"})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig", err)
+	}
+	// The logger is installed anyway, at the default level.
+	if zerolog.GlobalLevel() != zerolog.InfoLevel {
+		t.Errorf("level = %v, want info", zerolog.GlobalLevel())
+	}
+
+	InitLogger(CofigLogger{LogLevel: "warn"})
+	if zerolog.GlobalLevel() != zerolog.WarnLevel {
+		t.Errorf("level = %v after InitLogger, want warn", zerolog.GlobalLevel())
+	}
+}
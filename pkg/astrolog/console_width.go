@@ -0,0 +1,146 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+)
+
+// =============================
+// Console colour
+// =============================
+
+// consoleColor reports whether the console writer should emit ANSI colours.
+// NO_COLOR (https://no-color.org) wins over FORCE_COLOR; with neither set,
+// colours are used only on a terminal.
+func consoleColor(getenv func(string) string, isTerminal bool) bool {
+	if getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := getenv("FORCE_COLOR"); force != "" {
+		return force != "0"
+	}
+	return isTerminal
+}
+
+// stderrIsTerminal reports whether stderr is a terminal.
+func stderrIsTerminal() bool {
+	fd := os.Stderr.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// =============================
+// Console width
+// =============================
+
+// ellipsis ends console lines cut by widthWriter.
+const ellipsis = "…"
+
+// widthWriter cuts every line written through it to the terminal width.
+// ANSI escape sequences take no columns and are kept, so colours survive.
+type widthWriter struct {
+	out   io.Writer
+	size  func() int   // terminal width provider; <= 0 means unknown
+	width atomic.Int64 // last value returned by size
+}
+
+func newWidthWriter(out io.Writer, size func() int) *widthWriter {
+	w := &widthWriter{out: out, size: size}
+	w.refresh()
+	return w
+}
+
+// refresh re-reads the terminal width, e.g. after a resize.
+func (w *widthWriter) refresh() {
+	w.width.Store(int64(w.size()))
+}
+
+func (w *widthWriter) Write(p []byte) (int, error) {
+	width := int(w.width.Load())
+	if width <= 0 {
+		return w.out.Write(p)
+	}
+
+	lines := bytes.SplitAfter(p, []byte("\n"))
+	out := make([]byte, 0, len(p))
+	for _, line := range lines {
+		body, nl := bytes.CutSuffix(line, []byte("\n"))
+		out = append(out, fitWidth(body, width)...)
+		if nl {
+			out = append(out, '\n')
+		}
+	}
+	if _, err := w.out.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// fitWidth returns line unchanged when it fits in width columns, otherwise
+// cut to width-1 columns followed by an ellipsis. Escape sequences are kept
+// up to the cut, and the colour is reset after the ellipsis if any were seen.
+func fitWidth(line []byte, width int) []byte {
+	if visibleWidth(line) <= width {
+		return line
+	}
+
+	out := make([]byte, 0, len(line))
+	cols, escaped := 0, false
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			out = append(out, line[i:i+n]...)
+			escaped = true
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRune(line[i:])
+		rw := runeWidth(r)
+		if cols+rw > width-1 {
+			break
+		}
+		out = append(out, line[i:i+size]...)
+		cols += rw
+		i += size
+	}
+
+	out = append(out, ellipsis...)
+	if escaped {
+		out = append(out, "\033[0m"...)
+	}
+	return out
+}
+
+// visibleWidth returns the columns line takes once escape sequences are
+// removed.
+func visibleWidth(line []byte) int {
+	w := 0
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRune(line[i:])
+		w += runeWidth(r)
+		i += size
+	}
+	return w
+}
+
+// escapeLen returns the length of the CSI escape sequence ("ESC [ … final")
+// at the start of b, 0 if there is none.
+func escapeLen(b []byte) int {
+	if len(b) < 2 || b[0] != 0x1b || b[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+// stderrWidth always returns 0: terminal size is not available on this
+// platform, so console lines are never cut.
+func stderrWidth() int { return 0 }
+
+// notifyResize is a no-op on this platform.
+func notifyResize(func()) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,104 @@
+package astrolog
+
+import (
+	"bytes"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConsoleColorPrecedence(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		noColor    string
+		forceColor string
+		terminal   bool
+		want       bool
+	}{
+		{"terminal", "", "", true, true},
+		{"pipe", "", "", false, false},
+		{"NO_COLOR on a terminal", "1", "", true, false},
+		{"NO_COLOR wins over FORCE_COLOR", "1", "1", false, false},
+		{"FORCE_COLOR on a pipe", "", "1", false, true},
+		{"FORCE_COLOR=0 on a terminal", "", "0", true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			t.Setenv("FORCE_COLOR", tc.forceColor)
+			if got := consoleColor(os.Getenv, tc.terminal); got != tc.want {
+				t.Errorf("consoleColor(terminal=%v) = %v, want %v", tc.terminal, got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeWidth is a settable terminal-size provider.
+type fakeWidth struct{ cols atomic.Int64 }
+
+func (f *fakeWidth) size() int { return int(f.cols.Load()) }
+
+func TestWidthWriterTruncates(t *testing.T) {
+	var out bytes.Buffer
+	term := &fakeWidth{}
+	term.cols.Store(10)
+	w := newWidthWriter(&out, term.size)
+
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{"fits", "short\n", "short\n"},
+		{"exact", "0123456789\n", "0123456789\n"},
+		{"cut", "0123456789abc\n", "012345678…\n"},
+		{"no newline", "0123456789abc", "012345678…"},
+		{"each line", "0123456789abc\nok\n0123456789abc\n", "012345678…\nok\n012345678…\n"},
+		{"colours take no columns", "\033[31mred\033[0m text\n", "\033[31mred\033[0m text\n"},
+		{"colours kept up to the cut", "\033[31m0123456789abc\033[0m\n", "\033[31m012345678…\033[0m\n"},
+		{"wide runes", "日本語のログです\n", "日本語の…\n"},
+	} {
+		out.Reset()
+		n, err := w.Write([]byte(tc.in))
+		if err != nil || n != len(tc.in) {
+			t.Errorf("%s: Write = %d, %v; want %d, nil", tc.name, n, err, len(tc.in))
+		}
+		if got := out.String(); got != tc.want {
+			t.Errorf("%s: wrote %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWidthWriterResize(t *testing.T) {
+	var out bytes.Buffer
+	term := &fakeWidth{}
+	term.cols.Store(6)
+	w := newWidthWriter(&out, term.size)
+	line := []byte("0123456789\n")
+
+	w.Write(line)
+	if got := out.String(); got != "01234…\n" {
+		t.Fatalf("at 6 columns: %q", got)
+	}
+
+	// The width is only re-read on refresh, as on SIGWINCH.
+	term.cols.Store(20)
+	out.Reset()
+	w.Write(line)
+	if got := out.String(); got != "01234…\n" {
+		t.Errorf("before refresh: %q", got)
+	}
+	w.refresh()
+	out.Reset()
+	w.Write(line)
+	if got := out.String(); got != string(line) {
+		t.Errorf("after refresh to 20 columns: %q", got)
+	}
+
+	// An unknown width passes lines through.
+	term.cols.Store(0)
+	w.refresh()
+	out.Reset()
+	long := bytes.Repeat([]byte("x"), 200)
+	w.Write(long)
+	if !bytes.Equal(out.Bytes(), long) {
+		t.Errorf("unknown width cut the line to %d bytes", out.Len())
+	}
+}
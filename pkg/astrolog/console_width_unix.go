@@ -0,0 +1,43 @@
+//go:build unix
+
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// stderrWidth returns the width (columns) of the terminal on stderr, 0 when
+// it can't be read.
+func stderrWidth() int {
+	ws, err := unix.IoctlGetWinsize(int(os.Stderr.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Col)
+}
+
+// notifyResize calls fn on every SIGWINCH until the returned stop func runs.
+func notifyResize(fn func()) (stop func()) {
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, syscall.SIGWINCH)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sig:
+				fn()
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sig)
+		close(done)
+	}
+}
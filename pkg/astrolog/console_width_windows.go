@@ -0,0 +1,26 @@
+//go:build windows
+
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// stderrWidth returns the width (columns) of the console window on stderr,
+// 0 when it can't be read.
+func stderrWidth() int {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(os.Stderr.Fd()), &info); err != nil {
+		return 0
+	}
+	return int(info.Window.Right-info.Window.Left) + 1
+}
+
+// notifyResize is a no-op: Windows has no resize signal, so the width read at
+// start-up is kept.
+func notifyResize(func()) (stop func()) {
+	return func() {}
+}
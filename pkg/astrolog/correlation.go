@@ -0,0 +1,108 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/binary"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// CorrelationIDField is the log field that groups the entries of one logical
+// operation (e.g. a capture with its retries and upload).
+const CorrelationIDField = "correlation_id"
+
+// idEncoding is Crockford's base32 alphabet: ordered, so IDs sort by time.
+var idEncoding = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+type correlationKey struct{}
+
+type fieldsKey struct{}
+
+// NewID returns a 26-char, time-sortable, base32 ID: 48 bits of Unix
+// milliseconds followed by 80 random bits.
+func NewID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	_, _ = rand.Read(b[6:])
+	return idEncoding.EncodeToString(b[:])
+}
+
+// WithCorrelationID returns a copy of ctx carrying id.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the ID stored in ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// EnsureCorrelationID returns ctx unchanged when it already carries an ID,
+// otherwise a copy with a fresh NewID.
+func EnsureCorrelationID(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if CorrelationID(ctx) != "" {
+		return ctx
+	}
+	return WithCorrelationID(ctx, NewID())
+}
+
+// ContextWithFields returns a copy of ctx carrying fields, merged over the
+// ones ctx already carries, e.g. a request_id set once by a middleware.
+// Loggers from FromContext / LoggerFromContext attach them to every entry.
+func ContextWithFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	prev := contextFields(ctx)
+	merged := make(map[string]interface{}, len(prev)+len(fields))
+	for k, v := range prev {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// contextFields returns the fields stored in ctx, or nil. The map must not
+// be modified.
+func contextFields(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).(map[string]interface{})
+	return fields
+}
+
+// FromContext returns the global logger with the fields of ctx (see
+// ContextWithFields) and its correlation ID, if any, attached as
+// CorrelationIDField. It writes to the outputs set up by InitLogger.
+func FromContext(ctx context.Context) zerolog.Logger {
+	logger := GetLogger()
+	fields := contextFields(ctx)
+	id := CorrelationID(ctx)
+	if len(fields) == 0 && id == "" {
+		return logger
+	}
+	c := logger.With().Fields(fields)
+	if id != "" {
+		c = c.Str(CorrelationIDField, id)
+	}
+	return c.Logger()
+}
+
+// LoggerFromContext is FromContext, named to pair with ContextWithFields.
+func LoggerFromContext(ctx context.Context) zerolog.Logger {
+	return FromContext(ctx)
+}
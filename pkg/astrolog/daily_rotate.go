@@ -0,0 +1,68 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// =============================
+// Daily Rotate
+// =============================
+
+// dailySwitch moves a file writer to a new dated file at local midnight
+// (CofigLogger.DailyRotate). The check runs on the write path, so an idle
+// logger switches on its first entry of the new day. lumberjack reads its
+// Filename from a background goroutine, so the switch opens a new
+// lumberjack.Logger rather than renaming the current one. Writes hold mu
+// for reading and the switch holds it for writing.
+type dailySwitch struct {
+	mu      sync.RWMutex
+	lj      *lumberjack.Logger              // current file
+	next    atomic.Int64                    // next local midnight, Unix nanoseconds
+	path    func(now time.Time) string      // file for the day of now
+	open    func(string) *lumberjack.Logger // writer for a new file
+	cleanup func()                          // retention, run after each switch
+}
+
+func newDailySwitch(lj *lumberjack.Logger, now time.Time, path func(time.Time) string, open func(string) *lumberjack.Logger, cleanup func()) *dailySwitch {
+	d := &dailySwitch{lj: lj, path: path, open: open, cleanup: cleanup}
+	d.next.Store(nextMidnight(now).UnixNano())
+	return d
+}
+
+// due reports whether now is past the day the current file belongs to.
+func (d *dailySwitch) due(now time.Time) bool {
+	return now.UnixNano() >= d.next.Load()
+}
+
+// switchFile closes the current file and moves to the one for now, which
+// lumberjack creates on the next write. Concurrent callers switch once.
+func (d *dailySwitch) switchFile(guard *diskGuard, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.due(now) {
+		return
+	}
+
+	old := d.lj
+	_ = old.Close()
+	d.lj = d.open(d.path(now))
+	d.next.Store(nextMidnight(now).UnixNano())
+
+	if guard != nil {
+		guard.switched(old.Filename, d.lj.Filename)
+	}
+	if d.cleanup != nil {
+		d.cleanup()
+	}
+}
+
+// nextMidnight returns the start of the day after t, in t's location.
+func nextMidnight(t time.Time) time.Time {
+	y, m, day := t.Date()
+	return time.Date(y, m, day+1, 0, 0, 0, 0, t.Location())
+}
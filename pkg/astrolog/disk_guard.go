@@ -0,0 +1,195 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDiskGuardInterval is used when MaxTotalLogBytes is set but
+// DiskGuardInterval is not.
+const defaultDiskGuardInterval = time.Minute
+
+// lumberjackDefaultMaxSize mirrors lumberjack's fallback when MaxSize is 0.
+const lumberjackDefaultMaxSize = 100
+
+// =============================
+// Disk Guard
+// =============================
+
+// diskGuard keeps the total size of the log directory under a byte budget.
+// It tracks how much has been written to each active file so it can run
+// right after lumberjack rolls one of them over.
+type diskGuard struct {
+	mu       sync.Mutex
+	dir      string
+	prefix   string   // LogFileName; only "<prefix>_…" files count
+	active   []string // files currently open for writing; never deleted
+	maxTotal int64
+	maxFile  int64            // lumberjack rollover size in bytes
+	written  map[string]int64 // bytes in each active file since its last rollover
+}
+
+func newDiskGuard(dir, prefix string, active []string, maxTotal int64, maxFileMB int) *diskGuard {
+	if maxFileMB <= 0 {
+		maxFileMB = lumberjackDefaultMaxSize
+	}
+	g := &diskGuard{
+		dir:      dir,
+		prefix:   prefix,
+		active:   active,
+		maxTotal: maxTotal,
+		maxFile:  int64(maxFileMB) * 1024 * 1024,
+		written:  make(map[string]int64, len(active)),
+	}
+	for _, path := range active {
+		if info, err := os.Stat(path); err == nil {
+			g.written[path] = info.Size()
+		}
+	}
+	return g
+}
+
+// afterWrite records n bytes written to the active file at path and enforces
+// the budget when that write made lumberjack rotate.
+func (g *diskGuard) afterWrite(path string, n int) {
+	g.mu.Lock()
+	rotated := g.written[path]+int64(n) > g.maxFile
+	if rotated {
+		g.written[path] = int64(n)
+	} else {
+		g.written[path] += int64(n)
+	}
+	g.mu.Unlock()
+
+	if rotated {
+		_ = g.enforce()
+	}
+}
+
+// rotated resets the byte count of path after an explicit rotation and
+// enforces the budget, which now includes the new backup.
+func (g *diskGuard) rotated(path string) {
+	g.mu.Lock()
+	g.written[path] = 0
+	g.mu.Unlock()
+	_ = g.enforce()
+}
+
+// switched replaces the active file old by new after a DailyRotate switch;
+// old becomes an ordinary file the budget may delete.
+func (g *diskGuard) switched(old, new string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, path := range g.active {
+		if path == old {
+			g.active[i] = new
+		}
+	}
+	delete(g.written, old)
+	g.written[new] = 0
+}
+
+// enforce deletes the oldest log files until the directory is under budget.
+func (g *diskGuard) enforce() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return enforceTotalLogBytes(g.dir, g.prefix, g.maxTotal, g.active...)
+}
+
+// run enforces the budget every interval until stop is closed.
+func (g *diskGuard) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_ = g.enforce()
+		}
+	}
+}
+
+// =============================
+// File Cleanup — size-based
+// =============================
+
+// appLogName matches what follows the prefix in the names this package
+// generates: "_DD-MM-YYYY" (RotationDaily) or "_DD-MM-YYYY_HHMMSS"
+// (RotationPerRun), the DualFileOutput "_json" suffix, the lumberjack backup
+// time and the .gz of Compress.
+var appLogName = regexp.MustCompile(`^_\d{2}-\d{2}-\d{4}(_\d{6})?(_json)?(-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3})?\.log(\.gz)?$`)
+
+// isAppLogFile reports whether name is a log file of the app whose
+// LogFileName is prefix, as resolveLogFilename and lumberjack name them.
+// Other services may share the directory, even under a name starting with
+// prefix+"_"; their files are never counted or deleted.
+func isAppLogFile(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	return ok && appLogName.MatchString(rest)
+}
+
+// enforceTotalLogBytes sums the log files of prefix in logDir and removes
+// them oldest-first until the total is at most maxTotal. The active files
+// are counted but never removed.
+func enforceTotalLogBytes(logDir, prefix string, maxTotal int64, active ...string) error {
+	if maxTotal <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		return err
+	}
+
+	type logFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []logFile
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || !isAppLogFile(entry.Name(), prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFile{
+			path:    filepath.Join(logDir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	keep := make(map[string]bool, len(active))
+	for _, path := range active {
+		abs, _ := filepath.Abs(path)
+		keep[abs] = true
+	}
+	for _, f := range files {
+		if total <= maxTotal {
+			break
+		}
+		if abs, _ := filepath.Abs(f.path); keep[abs] {
+			continue
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package astrolog
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeLog creates name in dir with size bytes, last modified age ago.
+func writeLog(t *testing.T, dir, name string, size int, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// remaining lists the files left in dir, sorted.
+func remaining(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestEnforceTotalLogBytesEvictsOldestFirst(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "app_01-03-2024_101500.log", 100, 4*time.Hour)
+	writeLog(t, dir, "app_02-03-2024_101500-2024-03-02T12-00-00.000.log.gz", 100, 3*time.Hour)
+	writeLog(t, dir, "app_03-03-2024_101500.log", 100, 2*time.Hour)
+	active := writeLog(t, dir, "app_04-03-2024_101500.log", 100, time.Hour)
+
+	if err := enforceTotalLogBytes(dir, "app", 250, active); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app_03-03-2024_101500.log", "app_04-03-2024_101500.log"}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+}
+
+func TestEnforceTotalLogBytesKeepsActiveFiles(t *testing.T) {
+	dir := t.TempDir()
+	// The active file is the oldest and alone over budget: it must survive.
+	active := writeLog(t, dir, "app_01-03-2024_101500.log", 500, 4*time.Hour)
+	writeLog(t, dir, "app_02-03-2024_101500.log", 100, 3*time.Hour)
+
+	if err := enforceTotalLogBytes(dir, "app", 100, active); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app_01-03-2024_101500.log"}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+}
+
+func TestEnforceTotalLogBytesIgnoresOtherApps(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "other_01-03-2024.log", 1000, 5*time.Hour)
+	writeLog(t, dir, "apps_01-03-2024.log", 1000, 5*time.Hour)
+	writeLog(t, dir, "app_edge_01-03-2024.log", 1000, 5*time.Hour) // LogFileName "app_edge"
+	writeLog(t, dir, "notes.txt", 1000, 5*time.Hour)
+	writeLog(t, dir, "app_01-03-2024_101500.log", 100, 4*time.Hour)
+	active := writeLog(t, dir, "app_02-03-2024_101500.log", 100, time.Hour)
+
+	if err := enforceTotalLogBytes(dir, "app", 150, active); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other files neither count towards the budget nor get deleted.
+	want := []string{"app_02-03-2024_101500.log", "app_edge_01-03-2024.log", "apps_01-03-2024.log", "notes.txt", "other_01-03-2024.log"}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+}
+
+func TestIsAppLogFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"app_15-03-2024.log":                                        true, // RotationDaily
+		"app_15-03-2024_101500.log":                                 true, // RotationPerRun
+		"app_15-03-2024_json.log":                                   true, // DualFileOutput
+		"app_15-03-2024_101500_json.log":                            true,
+		"app_15-03-2024-2024-03-15T10-00-00.000.log":                true,  // lumberjack backup
+		"app_15-03-2024_101500_json-2024-03-15T10-00-00.000.log.gz": true,  // compressed
+		"app_edge_15-03-2024.log":                                   false, // another app's prefix
+		"app_edge_15-03-2024_101500.log":                            false,
+		"apps_15-03-2024.log":                                       false,
+		"app_notes.log":                                             false,
+		"app_15-03-2024.log.bak":                                    false,
+		"app_15-03-2024.txt":                                        false,
+		"app.log":                                                   false,
+		"x_app_15-03-2024.log":                                      false,
+	} {
+		if got := isAppLogFile(name, "app"); got != want {
+			t.Errorf("isAppLogFile(%q, app) = %v, want %v", name, got, want)
+		}
+	}
+	if !isAppLogFile("app_edge_15-03-2024.log", "app_edge") {
+		t.Error("app_edge does not own its own file")
+	}
+}
+
+func TestEnforceTotalLogBytesDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "app_01-03-2024_101500.log", 100, time.Hour)
+
+	if err := enforceTotalLogBytes(dir, "app", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := remaining(t, dir); len(got) != 1 {
+		t.Errorf("remaining = %v, want the file kept", got)
+	}
+}
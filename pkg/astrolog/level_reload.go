@@ -0,0 +1,144 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelReloadOptions says where EnableLevelReload reads the new level from on
+// SIGHUP. File wins over EnvVar when both are set and the file is readable.
+//
+// A process can't see changes made to its environment from outside, so
+// EnvVar only changes when the program itself calls os.Setenv (e.g. after
+// reloading a .env file); use File to change the level of a running process.
+type LevelReloadOptions struct {
+	EnvVar string // e.g. "LOG_LEVEL"
+	File   string // file holding a single level name, e.g. "debug"
+
+	// Interval also re-reads the level periodically, not only on SIGHUP.
+	// 0 → SIGHUP only.
+	Interval time.Duration
+}
+
+// EnableLevelReload re-reads the log level every time the process gets SIGHUP
+// (and every opts.Interval when set), until ctx is cancelled. Only actual
+// level changes are logged.
+func EnableLevelReload(ctx context.Context, opts LevelReloadOptions) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sig)
+
+		var tick <-chan time.Time
+		if opts.Interval > 0 {
+			ticker := time.NewTicker(opts.Interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			source := "sighup"
+			select {
+			case <-ctx.Done():
+				return
+			case <-sig:
+			case <-tick:
+				source = "poll"
+			}
+			if raw, ok := readLevelSource(opts); ok {
+				applyLevel(raw, source)
+			}
+		}
+	}()
+}
+
+// ApplyLevelFromEnv sets the global level from envVar, e.g. "LOG_LEVEL", and
+// reports whether the variable was set. Call it after InitLogger to let the
+// environment override CofigLogger.LogLevel.
+func ApplyLevelFromEnv(envVar string) bool {
+	raw := os.Getenv(envVar)
+	if raw == "" {
+		return false
+	}
+	applyLevel(raw, envVar)
+	return true
+}
+
+// WatchLevelFile polls path every interval and applies the level it contains
+// whenever the content changes, until ctx is cancelled.
+func WatchLevelFile(ctx context.Context, path string, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := ""
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				data, err := os.ReadFile(path)
+				if err != nil {
+					continue
+				}
+				raw := strings.TrimSpace(string(data))
+				if raw == last {
+					continue
+				}
+				last = raw
+				applyLevel(raw, path)
+			}
+		}
+	}()
+}
+
+// readLevelSource returns the level configured by opts.
+func readLevelSource(opts LevelReloadOptions) (string, bool) {
+	if opts.File != "" {
+		if data, err := os.ReadFile(opts.File); err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+	}
+	if opts.EnvVar != "" {
+		if val := os.Getenv(opts.EnvVar); val != "" {
+			return val, true
+		}
+	}
+	return "", false
+}
+
+// applyLevel sets the global level to raw and logs one line when it actually
+// changed. Unknown level names are reported and ignored.
+func applyLevel(raw, source string) {
+	parsed, err := zerolog.ParseLevel(strings.ToLower(raw))
+	if err != nil {
+		logger := GetLogger()
+		logger.Warn().Str("level", raw).Str("source", source).Msg("Ignoring unknown log level")
+		return
+	}
+
+	mu.Lock()
+	previous := zerolog.GlobalLevel()
+	changed := previous != parsed
+	if changed {
+		zerolog.SetGlobalLevel(parsed)
+	}
+	mu.Unlock()
+
+	if changed {
+		logger := GetLogger()
+		logger.Info().
+			Str("from", previous.String()).
+			Str("to", parsed.String()).
+			Str("source", source).
+			Msg("Log level changed")
+	}
+}
@@ -2,7 +2,9 @@
 package astrolog
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,7 +12,10 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -19,13 +24,20 @@ import (
 
 var mu sync.Mutex
 
+// logTimeFormat is the timestamp layout used in every output.
+const logTimeFormat = "2006-01-02 15:04:05.000"
+
+// defaultMaxBackups is used when CofigLogger.MaxBackups is 0.
+const defaultMaxBackups = 3
+
 // RotationMode controls how log files are created and rotated.
 type RotationMode string
 
 const (
 	// RotationDaily: one log file per calendar day (e.g. app_02-01-2006.log).
 	// If the container restarts within the same day, logs are appended to the
-	// existing file. A new file is created automatically at midnight.
+	// existing file. With DailyRotate a running process also moves to the
+	// new day's file at midnight.
 	RotationDaily RotationMode = "daily"
 
 	// RotationPerRun: a new log file is created on every program start
@@ -39,27 +51,216 @@ type CofigLogger struct {
 	LogFileName string
 	Formatted   bool // true = JSON everywhere, false = pretty everywhere
 
+	// DualFileOutput writes every entry to two files sharing the rotation
+	// settings: the pretty file and a raw JSON sibling "<file>_json.log", e.g.
+	// one for on-call engineers and one for a log shipper. The run separator
+	// only goes into the pretty file. Console output still follows Formatted
+	// and ConsoleJSON.
+	DualFileOutput bool
+
+	// ── Console ──────────────────────────────────────────────────────────────
+	// Console colours follow the NO_COLOR / FORCE_COLOR conventions: a
+	// non-empty NO_COLOR turns them off, FORCE_COLOR (other than "0") turns
+	// them on even when stderr is not a terminal. Otherwise colours are used
+	// only when stderr is a terminal.
+	//
+	// ConsoleFitWidth cuts pretty console lines that are wider than the
+	// terminal and ends them with "…". The width is re-read when the
+	// terminal is resized (SIGWINCH, Unix only). File, syslog and webhook
+	// outputs are never cut, and nothing is cut when stderr is not a terminal.
+	ConsoleFitWidth bool
+
+	// ConsoleJSON writes raw JSON entries to the console instead of the
+	// pretty format, e.g. for environments that scrape stderr, while files
+	// keep following Formatted. Formatted = true already implies it.
+	ConsoleJSON bool
+
 	// ── Rotation ─────────────────────────────────────────────────────────────
 	// RotationMode selects the file-naming / rotation strategy:
 	//   RotationDaily   – one file per day; survives container restarts.
 	//   RotationPerRun  – new file on every startup (default when empty).
 	RotationMode RotationMode
 
+	// DailyRotate switches to a new dated file at local midnight while the
+	// process runs; without it the name picked at InitLogger is kept until
+	// the next start. In RotationPerRun the new file is stamped with the time
+	// of the switch. MaxLogFiles and MaxAgeDays are applied again after
+	// each switch.
+	DailyRotate bool
+
 	// MaxFileSize is the maximum size (MB) of a single log file before
 	// lumberjack rolls it over with a numeric suffix.
 	// 0 → lumberjack default (100 MB).
 	MaxFileSize int
 
-	// MaxLogFiles is the maximum number of .log files kept in the log
-	// directory. Oldest files are removed when the limit is exceeded.
-	// 0 → no limit enforced by astrolog (lumberjack still manages its own
-	// compressed backups independently).
+	// MaxBackups is the number of rolled-over backups lumberjack keeps for
+	// the current log file.
+	// 0 → defaultMaxBackups (3).
+	MaxBackups int
+
+	// Compress gzips the backups lumberjack rolls over (…-<time>.log.gz).
+	Compress bool
+
+	// MaxLogFiles is the maximum number of this app's .log and .log.gz files
+	// kept in the log directory, checked at InitLogger. Oldest files are
+	// removed when the limit is exceeded. With DualFileOutput the pretty and
+	// JSON files are counted separately, so both keep the same runs.
+	// lumberjack's MaxBackups/MaxAgeDays only prune the backups of the file
+	// being written, so MaxLogFiles is what bounds files of earlier runs;
+	// keep it above MaxBackups+1 or a run's own backups get removed at the
+	// next start.
+	// 0 → no limit enforced by astrolog.
 	MaxLogFiles int
 
 	// MaxAgeDays is the maximum age (in days) of log files to retain.
-	// Files older than this are deleted on each InitLogger call.
+	// This app's files older than this (.log and .log.gz) are deleted on
+	// each InitLogger call; lumberjack also applies it to the current file's backups.
 	// 0 → no age-based deletion.
 	MaxAgeDays int
+
+	// MaxTotalLogBytes is the disk budget (bytes) for this app's .log and
+	// .log.gz files in the log directory. Oldest files are deleted until the total
+	// fits, at init, after every rollover and every DiskGuardInterval.
+	// The file currently being written is never deleted.
+	// 0 → no size budget.
+	MaxTotalLogBytes int64
+
+	// DiskGuardInterval is how often the MaxTotalLogBytes budget is checked
+	// in the background.
+	// 0 → 1 minute.
+	DiskGuardInterval time.Duration
+
+	// ── Syslog ───────────────────────────────────────────────────────────────
+	// SyslogAddr forwards raw JSON entries to syslog in addition to the
+	// console/file outputs: "local", "unix:///dev/log", "udp://host:514" or
+	// "tcp://host:514". If the daemon can't be reached a warning is logged
+	// and the logger carries on without it.
+	// "" → no syslog output.
+	SyslogAddr string
+
+	// ── Webhook ──────────────────────────────────────────────────────────────
+	// WebhookURL receives entries at or above WebhookLevel as JSON arrays,
+	// POSTed every WebhookFlushInterval. At most WebhookQueueSize entries
+	// wait in memory; extra entries are dropped (see WebhookDropped).
+	// "" → no webhook.
+	WebhookURL string
+	// WebhookLevel is the minimum level forwarded. "" → "error".
+	WebhookLevel string
+	// WebhookFlushInterval is the batching period. 0 → 5s.
+	WebhookFlushInterval time.Duration
+	// WebhookQueueSize bounds the pending entries. 0 → 1000.
+	WebhookQueueSize int
+
+	// ── Remote ───────────────────────────────────────────────────────────────
+	// RemoteAddr sends raw JSON entries, one per line, to a log collector
+	// such as rsyslog: "tcp://host:port" or "udp://host:port". Entries wait
+	// in a queue of RemoteQueueSize and are dropped when it is full (see
+	// RemoteDropped); a lost connection is re-dialled with a backoff of up
+	// to RemoteMaxBackoff. If the collector can't be reached at init a
+	// warning is logged and the logger carries on without it. Call Close
+	// before exiting so queued entries are sent.
+	// "" → no remote output.
+	RemoteAddr string
+	// RemoteQueueSize bounds the pending entries. 0 → 10000.
+	RemoteQueueSize int
+	// RemoteMaxBackoff caps the wait between reconnect attempts. 0 → 30s.
+	RemoteMaxBackoff time.Duration
+
+	// ── Field limits ─────────────────────────────────────────────────────────
+	// MaxFieldLength is the maximum length (bytes) of a string field value.
+	// Longer values are cut and suffixed with "...(truncated N bytes)" in both
+	// JSON and formatted output. The message itself is never truncated.
+	// 0 → no limit.
+	MaxFieldLength int
+
+	// MaskFields lists field keys (case-insensitive) whose values are
+	// replaced by "***" in every output, e.g. "password", "token",
+	// "authorization".
+	MaskFields []string
+
+	// MaskKeepLast keeps the last N characters of masked values visible
+	// ("***1234"); values shorter than 2×N are fully masked.
+	// 0 → fully masked.
+	MaskKeepLast int
+
+	// Transformers reshape entries per output, keyed by OutputConsole,
+	// OutputFile, OutputSyslog, OutputRemote or OutputWebhook, e.g.
+	// ECSFields for the remote collector only. They run after masking and
+	// truncation, in slice order. nil → every output gets the same fields.
+	Transformers map[string][]Transformer
+
+	// FieldOrder lists extra field keys that should appear first, in this
+	// order, in the formatted file line. Remaining fields follow sorted
+	// alphabetically. Only affects formatted output, never raw JSON.
+	FieldOrder []string
+
+	// FieldsLayout selects how extra fields are laid out in pretty file
+	// lines (Formatted = false); console and JSON output are unaffected:
+	//   FieldsInline    – key=value pairs joined at the end of the line (default)
+	//   FieldsAligned   – same, each pair padded to FieldWidth columns
+	//   FieldsMultiline – one indented "key = value" line per field below the entry
+	FieldsLayout FieldsLayout
+	// FieldWidth is the column width of a pair in FieldsAligned, or of the
+	// key in FieldsMultiline. 0 → 24 / the longest key of the entry.
+	FieldWidth int
+
+	// CallerSkipFrames is how many extra stack frames to skip when reporting
+	// the caller, e.g. 1 when every call goes through a thin wrapper, so the
+	// caller field shows the wrapper's call site.
+	// 0 → the function that called the logger.
+	CallerSkipFrames int
+
+	// CaptureStack records call stacks for errors: LogError and entries
+	// logged with .Stack().Err(err) get a "stack" field, in full in JSON
+	// output and cut to the top frame in pretty output. Errors wrapped with
+	// WithStack keep the stack of the wrap site.
+	CaptureStack bool
+
+	// Sequence adds a SequenceField ("seq") to every entry: a counter that
+	// starts at 1 with the process and never repeats, so gaps in shipped logs
+	// show lost lines. Entries below the log level are not numbered.
+	Sequence bool
+
+	// ── Run separator ────────────────────────────────────────────────────────
+	// DisableRunSeparator stops the boxed banner written at the top of each
+	// run (or restart, in daily mode), e.g. for files parsed by machines.
+	DisableRunSeparator bool
+	// RunBanner replaces the banner text with a text/template executed with
+	// RunBannerData; each output line becomes a row of the box, the first one
+	// being the title. Invalid templates fall back to the default banner.
+	// "" → "▶ PROGRAM STARTED" / "↺ PROCESS RESTARTED" with the time.
+	RunBanner string
+
+	// ── Async ────────────────────────────────────────────────────────────────
+	// Async makes console, file and syslog writes happen on a background
+	// goroutine so logging calls never wait on I/O. Up to AsyncQueueSize
+	// entries wait in memory; extra entries are dropped (see AsyncDropped)
+	// and the count is logged every AsyncReportInterval. Call Close before
+	// exiting so queued entries are written.
+	Async bool
+	// AsyncQueueSize bounds the pending entries. 0 → 10000.
+	AsyncQueueSize int
+	// AsyncReportInterval is how often dropped entries are reported. 0 → 10s.
+	AsyncReportInterval time.Duration
+
+	// ── Shutdown ─────────────────────────────────────────────────────────────
+	// A fatal or panic entry runs the RegisterShutdownHook hooks once it and
+	// the entries queued before it are written. ShutdownTimeout bounds all
+	// of them together. 0 → 5s.
+	ShutdownTimeout time.Duration
+	// ExitFunc ends the process after a fatal entry and its hooks. In tests
+	// a fake can record the call and then block forever, e.g. with
+	// select {}, since zerolog calls os.Exit itself once it returns.
+	// nil → os.Exit.
+	ExitFunc func(code int)
+
+	// ── Hooks ────────────────────────────────────────────────────────────────
+	// Hooks run on every entry that passes the level filter, in slice order,
+	// after the timestamp, caller and sequence fields have been added, so a
+	// hook's own fields come after them. They run on the logging goroutine
+	// (even with Async) and may read the entry's context via e.GetCtx(), e.g.
+	// to attach a trace ID from a Ctx(ctx) call.
+	Hooks []zerolog.Hook
 }
 
 // =============================
@@ -76,56 +277,228 @@ func (c ConsoleWriterWithLevel) WriteLevel(_ zerolog.Level, p []byte) (int, erro
 	return len(p), err
 }
 
+// =============================
+// JSON Writer
+// =============================
+
+// JSONWriterWithLevel writes raw JSON entries through a FieldFilter.
+type JSONWriterWithLevel struct {
+	Out    io.Writer
+	Filter FieldFilter
+}
+
+func (j JSONWriterWithLevel) Write(p []byte) (int, error) {
+	_, err := j.Out.Write(j.Filter.ApplyJSON(p))
+	return len(p), err
+}
+
 // =============================
 // File Writer
 // =============================
 
 type FileWriterWithLevel struct {
 	*lumberjack.Logger
-	Formatted bool
+	Formatted  bool
+	Filter     FieldFilter
+	FieldOrder []string
+	Layout     FieldsLayout
+	FieldWidth int
+
+	guard *diskGuard   // nil when MaxTotalLogBytes is 0
+	daily *dailySwitch // nil unless DailyRotate
+	state *fileState   // shared by the copies of this writer; nil → never closed
+}
+
+// fileState turns a FileWriterWithLevel into a no-op once closed, so entries
+// logged after Close are dropped instead of reopening the file.
+type fileState struct {
+	mu     sync.RWMutex // held for reading by writes, for writing by Close
+	closed bool
 }
 
 func (f FileWriterWithLevel) WriteLevel(level zerolog.Level, p []byte) (int, error) {
 	// Formatted == true  → RAW JSON
 	if f.Formatted {
-		return f.Logger.Write(p)
+		_, err := f.write(f.Filter.ApplyJSON(p))
+		return len(p), err
 	}
 	// Formatted == false → pretty formatted
-	formatted, err := formatLogEntry(level, p)
+	formatted, err := formatLogEntry(level, p, f.Filter, f.FieldOrder, f.Layout, f.FieldWidth)
 	if err != nil {
-		return f.Logger.Write(p)
+		return f.write(p)
 	}
-	_, err = f.Logger.Write([]byte(formatted))
+	_, err = f.write([]byte(formatted))
 	return len(p), err
 }
 
+// write sends b to lumberjack and lets the disk guard react to rollovers.
+// After Close it drops b.
+func (f FileWriterWithLevel) write(b []byte) (int, error) {
+	if f.state != nil {
+		f.state.mu.RLock()
+		defer f.state.mu.RUnlock()
+		if f.state.closed {
+			return len(b), nil
+		}
+	}
+	lj := f.Logger
+	if f.daily != nil {
+		if now := time.Now(); f.daily.due(now) {
+			f.daily.switchFile(f.guard, now)
+		}
+		f.daily.mu.RLock()
+		defer f.daily.mu.RUnlock()
+		lj = f.daily.lj
+	}
+	n, err := lj.Write(b)
+	if f.guard != nil && err == nil {
+		f.guard.afterWrite(lj.Filename, n)
+	}
+	return n, err
+}
+
+// rotate makes lumberjack start a new file now, for Logger.Rotate. It does
+// nothing after Close.
+func (f FileWriterWithLevel) rotate() error {
+	if f.state != nil {
+		f.state.mu.RLock()
+		defer f.state.mu.RUnlock()
+		if f.state.closed {
+			return nil
+		}
+	}
+	lj := f.Logger
+	if f.daily != nil {
+		f.daily.mu.RLock()
+		defer f.daily.mu.RUnlock()
+		lj = f.daily.lj
+	}
+	if err := lj.Rotate(); err != nil {
+		return fmt.Errorf("rotate %s: %w", lj.Filename, err)
+	}
+	if f.guard != nil {
+		f.guard.rotated(lj.Filename)
+	}
+	return nil
+}
+
+// Close closes the current file, which after a DailyRotate switch is no
+// longer the embedded Logger. Later writes are dropped and later calls do
+// nothing.
+func (f FileWriterWithLevel) Close() error {
+	if f.state != nil {
+		f.state.mu.Lock()
+		defer f.state.mu.Unlock()
+		if f.state.closed {
+			return nil
+		}
+		f.state.closed = true
+	}
+	if f.daily != nil {
+		f.daily.mu.RLock()
+		defer f.daily.mu.RUnlock()
+		return f.daily.lj.Close()
+	}
+	return f.Logger.Close()
+}
+
 // =============================
 // Formatting Helpers
 // =============================
 
-func formatLogEntry(level zerolog.Level, p []byte) (string, error) {
+// FieldsLayout controls how formatLogEntry prints extra fields.
+type FieldsLayout string
+
+const (
+	FieldsInline    FieldsLayout = "inline"
+	FieldsAligned   FieldsLayout = "aligned"
+	FieldsMultiline FieldsLayout = "multiline"
+)
+
+// defaultAlignedFieldWidth is used by FieldsAligned when FieldWidth is 0.
+const defaultAlignedFieldWidth = 24
+
+func formatLogEntry(level zerolog.Level, p []byte, filter FieldFilter, fieldOrder []string, layout FieldsLayout, width int) (string, error) {
 	var entry map[string]interface{}
 	if err := json.Unmarshal(p, &entry); err != nil {
 		return "", err
 	}
+	filter.Apply(entry)
 
-	timestamp, _ := entry["time"].(string)
 	message, _ := entry["message"].(string)
 	caller, _ := entry["caller"].(string)
 
-	formattedTimestamp := timestamp
-	if len(timestamp) >= 22 {
-		formattedTimestamp = strings.ReplaceAll(timestamp, "T", " ")[:22]
-	}
+	formattedTimestamp := formatTimestamp(entry["time"])
 
-	extras := collectExtraFields(entry)
-	return fmt.Sprintf("%s | %-5s | %-25s | %s | %s\n",
+	// The correlation ID is always pinned first so related lines line up.
+	order := append([]string{CorrelationIDField}, fieldOrder...)
+	// A stack is cut to its top frame and kept after every other field.
+	stack, hasStack := entry[zerolog.ErrorStackFieldName]
+	delete(entry, zerolog.ErrorStackFieldName)
+	extras := collectExtraFields(entry, order)
+	if hasStack {
+		extras = append(extras, extraField{zerolog.ErrorStackFieldName, fmt.Sprint(summarizeStack(stack))})
+	}
+	head := fmt.Sprintf("%s | %-5s | %-25s | %s",
 		formattedTimestamp,
 		level.String(),
 		caller,
 		message,
-		strings.Join(extras, " "),
-	), nil
+	)
+
+	switch layout {
+	case FieldsAligned:
+		if width <= 0 {
+			width = defaultAlignedFieldWidth
+		}
+		pairs := make([]string, len(extras))
+		for i, f := range extras {
+			pairs[i] = fmt.Sprintf("%-*s", width, f.Key+"="+f.Value)
+		}
+		return head + " | " + strings.TrimRight(strings.Join(pairs, " "), " ") + "\n", nil
+
+	case FieldsMultiline:
+		if width <= 0 {
+			for _, f := range extras {
+				width = max(width, len(f.Key))
+			}
+		}
+		var b strings.Builder
+		b.WriteString(head + "\n")
+		for _, f := range extras {
+			fmt.Fprintf(&b, "    %-*s = %s\n", width, f.Key, f.Value)
+		}
+		return b.String(), nil
+
+	default:
+		pairs := make([]string, len(extras))
+		for i, f := range extras {
+			pairs[i] = f.Key + "=" + f.Value
+		}
+		return head + " | " + strings.Join(pairs, " ") + "\n", nil
+	}
+}
+
+// formatTimestamp renders the entry's time field with logTimeFormat. The value
+// is parsed with zerolog.TimeFieldFormat (falling back to RFC 3339); anything
+// that does not parse is printed as-is.
+func formatTimestamp(raw interface{}) string {
+	timestamp, ok := raw.(string)
+	if !ok {
+		if raw == nil {
+			return ""
+		}
+		return fmt.Sprint(raw)
+	}
+	for _, layout := range []string{zerolog.TimeFieldFormat, time.RFC3339Nano} {
+		if layout == "" {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, timestamp, time.Local); err == nil {
+			return t.Format(logTimeFormat)
+		}
+	}
+	return timestamp
 }
 
 func stripCallerPath(file string) string {
@@ -138,49 +511,287 @@ func stripCallerPath(file string) string {
 	return base
 }
 
-func collectExtraFields(entry map[string]interface{}) []string {
-	standard := map[string]bool{
-		"time":    true,
-		"message": true,
-		"level":   true,
-		"caller":  true,
+// standardFields are written by zerolog on every entry and are never treated
+// as extra fields.
+var standardFields = map[string]bool{
+	"time":    true,
+	"message": true,
+	"level":   true,
+	"caller":  true,
+}
+
+// extraField is one non-standard field of an entry, its value printed.
+type extraField struct {
+	Key, Value string
+}
+
+// collectExtraFields returns the non-standard fields of entry. Keys listed
+// in order come first, in that order; the rest are sorted by key.
+func collectExtraFields(entry map[string]interface{}, order []string) []extraField {
+	var extras []extraField
+	seen := make(map[string]bool, len(order))
+	for _, k := range order {
+		v, ok := entry[k]
+		if !ok || standardFields[k] || seen[k] {
+			continue
+		}
+		seen[k] = true
+		extras = append(extras, extraField{k, fmt.Sprint(v)})
 	}
-	var extras []string
+
+	var rest []extraField
 	for k, v := range entry {
-		if !standard[k] {
-			extras = append(extras, fmt.Sprintf("%s=%v", k, v))
+		if !standardFields[k] && !seen[k] {
+			rest = append(rest, extraField{k, fmt.Sprint(v)})
 		}
 	}
-	sort.Strings(extras)
-	return extras
+	sort.Slice(rest, func(i, j int) bool { return rest[i].Key < rest[j].Key })
+	return append(extras, rest...)
+}
+
+// =============================
+// Field Filtering
+// =============================
+
+// maskedValue replaces the value of masked fields.
+const maskedValue = "***"
+
+// FieldFilter rewrites extra field values before they reach a writer:
+// masked keys are redacted, then long strings are truncated, then the
+// entry goes through the Transform chain.
+type FieldFilter struct {
+	MaxFieldLength int
+	MaskFields     []string      // keys to redact, matched case-insensitively
+	MaskKeepLast   int           // 0 → "***"; n → "***" followed by the last n chars
+	Transform      []Transformer // run in order on every entry
+}
+
+// newFieldFilter returns the filter of one output, with the transformers
+// cfg.Transformers lists for it.
+func newFieldFilter(cfg CofigLogger, output string) FieldFilter {
+	return FieldFilter{
+		MaxFieldLength: cfg.MaxFieldLength,
+		MaskFields:     cfg.MaskFields,
+		MaskKeepLast:   cfg.MaskKeepLast,
+		Transform:      cfg.Transformers[output],
+	}
+}
+
+// isMasked reports whether key is one of MaskFields.
+func (f FieldFilter) isMasked(key string) bool {
+	for _, m := range f.MaskFields {
+		if strings.EqualFold(m, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// mask redacts v, keeping the last MaskKeepLast characters when configured
+// and the value is long enough for that not to reveal most of it.
+func (f FieldFilter) mask(v interface{}) string {
+	if f.MaskKeepLast <= 0 {
+		return maskedValue
+	}
+	runes := []rune(fmt.Sprint(v))
+	if len(runes) <= f.MaskKeepLast*2 {
+		return maskedValue
+	}
+	return maskedValue + string(runes[len(runes)-f.MaskKeepLast:])
+}
+
+// Apply filters every non-standard field of entry in place, then replaces
+// its content with the result of the Transform chain. It reports whether
+// anything changed.
+func (f FieldFilter) Apply(entry map[string]interface{}) bool {
+	changed := f.filter(entry)
+	if f.transform(entry) {
+		changed = true
+	}
+	return changed
+}
+
+// filter masks and truncates the non-standard fields of entry in place.
+func (f FieldFilter) filter(entry map[string]interface{}) bool {
+	if f.MaxFieldLength <= 0 && len(f.MaskFields) == 0 {
+		return false
+	}
+	changed := false
+	for k, v := range entry {
+		if standardFields[k] {
+			continue
+		}
+		if f.isMasked(k) {
+			entry[k] = f.mask(v)
+			changed = true
+			continue
+		}
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if cut, truncated := truncateValue(str, f.MaxFieldLength); truncated {
+			entry[k] = cut
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ApplyJSON returns p with its fields filtered. The entry is only re-encoded
+// when something actually changed, so untouched lines keep their original
+// key order.
+func (f FieldFilter) ApplyJSON(p []byte) []byte {
+	if !f.mayChange(p) {
+		return p
+	}
+	dec := json.NewDecoder(strings.NewReader(string(p)))
+	dec.UseNumber()
+	var entry map[string]interface{}
+	if err := dec.Decode(&entry); err != nil {
+		return p
+	}
+	if !f.Apply(entry) {
+		return p
+	}
+	out, err := json.Marshal(entry)
+	if err != nil {
+		return p
+	}
+	return append(out, '\n')
+}
+
+// mayChange is a cheap pre-check that lets most lines skip JSON decoding.
+func (f FieldFilter) mayChange(p []byte) bool {
+	if len(f.Transform) > 0 {
+		return true
+	}
+	if f.MaxFieldLength > 0 && len(p) > f.MaxFieldLength {
+		return true
+	}
+	if len(f.MaskFields) == 0 {
+		return false
+	}
+	lower := bytes.ToLower(p)
+	for _, m := range f.MaskFields {
+		if bytes.Contains(lower, []byte(`"`+strings.ToLower(m)+`"`)) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateValue cuts s to at most max bytes (on a rune boundary) and appends
+// a "...(truncated N bytes)" suffix. It reports whether s was truncated.
+func truncateValue(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", s[:cut], len(s)-cut), true
 }
 
 // =============================
 // Run Separator
 // =============================
 
-func writeRunSeparator(lj *lumberjack.Logger) {
+func writeRunSeparator(lj *lumberjack.Logger, tmpl string) {
 	now := time.Now()
-	line1 := fmt.Sprintf("  Started : %s", now.Format("2006-01-02 15:04:05"))
+	lines := renderBanner(tmpl, RunBannerData{Time: now, File: lj.Filename}, []string{
+		"  ▶  PROGRAM STARTED",
+		fmt.Sprintf("  Started : %s", now.Format("2006-01-02 15:04:05")),
+	})
+	_, _ = lj.Write([]byte(drawBox(lines)))
+}
+
+// RunBannerData is passed to the CofigLogger.RunBanner template.
+type RunBannerData struct {
+	Time      time.Time
+	Restarted bool   // the daily file already existed
+	File      string // log file path
+}
+
+// renderBanner executes tmpl with data and returns its lines, or fallback
+// when tmpl is empty or invalid.
+func renderBanner(tmpl string, data RunBannerData, fallback []string) []string {
+	if tmpl == "" {
+		return fallback
+	}
+	t, err := template.New("banner").Parse(tmpl)
+	if err != nil {
+		return fallback
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return fallback
+	}
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+// drawBox frames lines in a box; the first line is the title. Widths are
+// measured in terminal columns so wide characters and emoji stay aligned.
+func drawBox(lines []string) string {
 	width := 50
-	if len(line1)+4 > width {
-		width = len(line1) + 4
+	for _, l := range lines {
+		width = max(width, displayWidth(l)+4)
+	}
+	row := func(l string) string {
+		return "│" + l + strings.Repeat(" ", width-displayWidth(l)) + "│\n"
+	}
+
+	var b strings.Builder
+	b.WriteString("\n┌" + strings.Repeat("─", width) + "┐\n")
+	for i, l := range lines {
+		if i == 1 {
+			b.WriteString("├" + strings.Repeat("─", width) + "┤\n")
+		}
+		b.WriteString(row(l))
+	}
+	b.WriteString("└" + strings.Repeat("─", width) + "┘\n\n")
+	return b.String()
+}
+
+// displayWidth returns how many terminal columns s takes.
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
 	}
-	top := "┌" + strings.Repeat("─", width) + "┐"
-	mid := "│" + fmt.Sprintf("%-*s", width, "  ▶  PROGRAM STARTED") + "│"
-	div := "├" + strings.Repeat("─", width) + "┤"
-	row1 := "│" + fmt.Sprintf("%-*s", width, line1) + "│"
-	bottom := "└" + strings.Repeat("─", width) + "┘"
+	return w
+}
 
-	banner := fmt.Sprintf("\n%s\n%s\n%s\n%s\n%s\n\n", top, mid, div, row1, bottom)
-	_, _ = lj.Write([]byte(banner))
+// runeWidth returns how many terminal columns r takes: combining marks and
+// zero-width characters take none, East Asian wide characters and emoji
+// take two.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200D || (r >= 0xFE00 && r <= 0xFE0F) ||
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case (r >= 0x1100 && r <= 0x115F) || (r >= 0x2E80 && r <= 0xA4CF) ||
+		(r >= 0xAC00 && r <= 0xD7A3) || (r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0xFE30 && r <= 0xFE4F) || (r >= 0xFF00 && r <= 0xFF60) ||
+		(r >= 0xFFE0 && r <= 0xFFE6) || (r >= 0x1F300 && r <= 0x1FAFF) ||
+		(r >= 0x20000 && r <= 0x3FFFD):
+		return 2
+	default:
+		return 1
+	}
 }
 
 // =============================
 // File Cleanup — count-based
 // =============================
 
-func deleteOldLogFiles(logDir string, maxFiles int) error {
+// deleteOldLogFiles keeps at most maxFiles .log / .log.gz files belonging to
+// this app (see isAppLogFile). Other services sharing logDir are left
+// alone. Rolled-over lumberjack backups count like any other file.
+// JSON siblings written by DualFileOutput ("…_json.log") are a family of
+// their own with the same limit, so neither family crowds out the other.
+func deleteOldLogFiles(logDir, prefix string, maxFiles int) error {
 	if maxFiles <= 0 {
 		return nil
 	}
@@ -189,15 +800,29 @@ func deleteOldLogFiles(logDir string, maxFiles int) error {
 		return err
 	}
 
-	var logFiles []os.DirEntry
+	var pretty, jsonFiles []os.DirEntry
 	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".log") {
-			logFiles = append(logFiles, entry)
+		name := entry.Name()
+		if entry.IsDir() || !isAppLogFile(name, prefix) {
+			continue
+		}
+		// lumberjack backups of the JSON file are named "…_json-<time>.log".
+		if strings.HasSuffix(name, jsonLogSuffix) || strings.Contains(name, "_json-") {
+			jsonFiles = append(jsonFiles, entry)
+		} else {
+			pretty = append(pretty, entry)
 		}
 	}
 
-	if len(logFiles) <= maxFiles {
-		return nil
+	removeOldest(logDir, pretty, maxFiles)
+	removeOldest(logDir, jsonFiles, maxFiles)
+	return nil
+}
+
+// removeOldest deletes all but the newest keep files of logFiles.
+func removeOldest(logDir string, logFiles []os.DirEntry, keep int) {
+	if len(logFiles) <= keep {
+		return
 	}
 
 	sort.Slice(logFiles, func(i, j int) bool {
@@ -206,17 +831,18 @@ func deleteOldLogFiles(logDir string, maxFiles int) error {
 		return infoI.ModTime().Before(infoJ.ModTime())
 	})
 
-	for _, file := range logFiles[:len(logFiles)-maxFiles] {
+	for _, file := range logFiles[:len(logFiles)-keep] {
 		_ = os.Remove(filepath.Join(logDir, file.Name()))
 	}
-	return nil
 }
 
 // =============================
 // File Cleanup — age-based
 // =============================
 
-func deleteAgedLogFiles(logDir string, maxAgeDays int) error {
+// deleteAgedLogFiles removes the log files of prefix (see isAppLogFile)
+// older than maxAgeDays. Other services sharing logDir are left alone.
+func deleteAgedLogFiles(logDir, prefix string, maxAgeDays int) error {
 	if maxAgeDays <= 0 {
 		return nil
 	}
@@ -228,7 +854,7 @@ func deleteAgedLogFiles(logDir string, maxAgeDays int) error {
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".log") {
+		if entry.IsDir() || !isAppLogFile(entry.Name(), prefix) {
 			continue
 		}
 		info, err := entry.Info()
@@ -246,7 +872,7 @@ func deleteAgedLogFiles(logDir string, maxAgeDays int) error {
 // File-name resolution
 // =============================
 
-// resolveLogFilename returns the log file path for the current run.
+// resolveLogFilename returns the log file path for a run started at now.
 //
 //   - RotationDaily  → <base>_DD-MM-YYYY.log
 //     The file is opened in append mode; if it already exists (e.g. after a
@@ -254,9 +880,7 @@ func deleteAgedLogFiles(logDir string, maxAgeDays int) error {
 //
 //   - RotationPerRun → <base>_DD-MM-YYYY_HHMMSS.log
 //     A unique name is generated at startup so every run gets its own file.
-func resolveLogFilename(cfg CofigLogger, logDir string) (string, bool) {
-	now := time.Now()
-
+func resolveLogFilename(cfg CofigLogger, logDir string, now time.Time) (string, bool) {
 	switch cfg.RotationMode {
 	case RotationDaily:
 		name := fmt.Sprintf("%s_%s.log",
@@ -283,56 +907,194 @@ func resolveLogFilename(cfg CofigLogger, logDir string) (string, bool) {
 // Init Logger
 // =============================
 
+// InitLogger configures the global logger (log.Logger) and replaces the one
+// set up by the previous call. Invalid settings fall back to defaults and
+// unavailable outputs are left out, both logged as warnings; use
+// InitLoggerE to get them as an error.
 func InitLogger(cfg CofigLogger) {
-	zerolog.TimeFieldFormat = "2006-01-02 15:04:05.000"
+	_ = InitLoggerE(cfg)
+}
+
+// InitLoggerE is InitLogger returning what cfg.Validate rejected and the
+// outputs that could not be opened, so callers can refuse to start on a bad
+// config. The logger is installed either way.
+func InitLoggerE(cfg CofigLogger) error {
+	setupZerolog()
+
+	invalid := cfg.Validate()
+
+	l, err := build(cfg)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = cfg
+	captureStack.Store(cfg.CaptureStack)
+	if global != nil {
+		_ = global.Close()
+	}
+	global = l
+	log.Logger = l.Logger
+
+	setGlobalLevel(cfg.LogLevel)
+
+	if invalid != nil {
+		log.Logger.Warn().
+			Err(invalid).
+			Msg("Logger config has invalid values, using defaults for them")
+	}
+	if err != nil {
+		log.Logger.Warn().
+			Err(err).
+			Msg("Log output unavailable, continuing without it")
+	}
+	return errors.Join(invalid, err)
+}
+
+// setupZerolog sets the zerolog globals shared by every astrolog logger.
+func setupZerolog() {
+	zerolog.TimeFieldFormat = logTimeFormat
 	zerolog.TimestampFunc = func() time.Time {
 		return time.Now().Local()
 	}
 	zerolog.CallerMarshalFunc = func(_ uintptr, file string, line int) string {
 		return fmt.Sprintf("%s:%d", stripCallerPath(file), line)
 	}
+}
 
+// build assembles the writers for cfg and starts their background
+// goroutines. Outputs that fail to open are left out and reported in err;
+// the returned Logger is always usable.
+func build(cfg CofigLogger) (*Logger, error) {
 	var writers []io.Writer
+	var errs []error
+	l := &Logger{}
+
+	if cfg.CaptureStack {
+		zerolog.ErrorStackMarshaler = marshalStack
+	}
 
 	// ── Console ──────────────────────────────────────────────────────────────
-	if cfg.Formatted {
-		writers = append(writers, os.Stderr) // raw JSON
+	if cfg.Formatted || cfg.ConsoleJSON {
+		writers = append(writers, JSONWriterWithLevel{ // raw JSON
+			Out:    os.Stderr,
+			Filter: newFieldFilter(cfg, OutputConsole),
+		})
 	} else {
-		writers = append(writers, buildConsoleWriter()) // pretty
+		cw := buildConsoleWriter(cfg) // pretty
+		if cfg.ConsoleFitWidth && stderrIsTerminal() {
+			ww := newWidthWriter(cw.Out, stderrWidth)
+			l.stopResize = notifyResize(ww.refresh)
+			cw.Out = ww
+		}
+		writers = append(writers, cw)
 	}
 
 	// ── File ─────────────────────────────────────────────────────────────────
+	var guard *diskGuard
 	if cfg.LogToFile {
-		if fw := buildFileWriter(cfg); fw != nil {
-			writers = append(writers, fw)
+		fws, err := buildFileWriters(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("log file: %w", err))
+		} else {
+			for _, fw := range fws {
+				writers = append(writers, fw)
+				l.closers = append(l.closers, fw)
+				l.files = append(l.files, fw)
+			}
+			guard = fws[0].guard
 		}
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
+	// ── Syslog ───────────────────────────────────────────────────────────────
+	if cfg.SyslogAddr != "" {
+		sw, err := buildSyslogWriter(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("syslog %s: %w", cfg.SyslogAddr, err))
+		} else {
+			writers = append(writers, sw)
+			l.closers = append(l.closers, sw)
+		}
+	}
+
+	// ── Async ────────────────────────────────────────────────────────────────
+	// The webhook keeps its own queue, so it stays outside.
+	if cfg.Async {
+		l.async = newAsyncWriter(zerolog.MultiLevelWriter(writers...), cfg)
+		writers = []io.Writer{l.async}
+	}
+
+	// ── Remote ───────────────────────────────────────────────────────────────
+	// Like the webhook it keeps its own queue, so it stays outside Async too.
+	if cfg.RemoteAddr != "" {
+		rw, err := newRemoteWriter(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("remote %s: %w", cfg.RemoteAddr, err))
+		} else {
+			l.remote = rw
+			writers = append(writers, rw)
+		}
+	}
 
-	log.Logger = zerolog.New(zerolog.MultiLevelWriter(writers...)).
+	// ── Webhook ──────────────────────────────────────────────────────────────
+	if cfg.WebhookURL != "" {
+		l.webhook = newWebhookWriter(cfg)
+		writers = append(writers, l.webhook)
+	}
+
+	// ── Disk guard ───────────────────────────────────────────────────────────
+	if guard != nil {
+		interval := cfg.DiskGuardInterval
+		if interval <= 0 {
+			interval = defaultDiskGuardInterval
+		}
+		l.guardStop = make(chan struct{})
+		go guard.run(interval, l.guardStop)
+	}
+
+	zctx := zerolog.New(newShutdownWriter(zerolog.MultiLevelWriter(writers...), cfg)).
 		With().
-		Timestamp().
-		Caller().
-		Logger()
+		Timestamp()
+	if cfg.CallerSkipFrames > 0 {
+		zctx = zctx.CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + cfg.CallerSkipFrames)
+	} else {
+		zctx = zctx.Caller()
+	}
+	l.Logger = zctx.Logger()
+	if cfg.Sequence {
+		l.Logger = l.Logger.Hook(sequenceHook{})
+	}
+	l.Logger = l.Logger.Hook(cfg.Hooks...)
 
-	UpdateLogLevel(cfg.LogLevel)
+	return l, errors.Join(errs...)
 }
 
 // =============================
 // Console Builder
 // =============================
 
-func buildConsoleWriter() ConsoleWriterWithLevel {
+func buildConsoleWriter(cfg CofigLogger) ConsoleWriterWithLevel {
+	filter := newFieldFilter(cfg, OutputConsole)
+	color := consoleColor(os.Getenv, stderrIsTerminal())
 	return ConsoleWriterWithLevel{
 		ConsoleWriter: zerolog.ConsoleWriter{
 			Out:        os.Stderr,
-			TimeFormat: "2006-01-02 15:04:05.000",
+			NoColor:    !color,
+			TimeFormat: logTimeFormat,
 			FormatCaller: func(i interface{}) string {
 				caller, _ := i.(string)
+				if !color {
+					return caller
+				}
 				return "\033[34m" + caller + "\033[0m"
 			},
+			FormatPrepare: func(entry map[string]interface{}) error {
+				filter.Apply(entry)
+				if stack, ok := entry[zerolog.ErrorStackFieldName]; ok {
+					entry[zerolog.ErrorStackFieldName] = summarizeStack(stack)
+				}
+				return nil
+			},
 		},
 	}
 }
@@ -341,56 +1103,156 @@ func buildConsoleWriter() ConsoleWriterWithLevel {
 // File Builder
 // =============================
 
-func buildFileWriter(cfg CofigLogger) *FileWriterWithLevel {
+// jsonLogSuffix ends the name of the JSON file written by DualFileOutput.
+const jsonLogSuffix = "_json.log"
+
+// buildFileWriters opens the log file for this run, plus its JSON sibling
+// when cfg.DualFileOutput is set. The first writer is the main file; all of
+// them share one disk guard.
+func buildFileWriters(cfg CofigLogger) ([]*FileWriterWithLevel, error) {
 	logDir := "./logs"
 	if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
-		return nil
+		return nil, err
 	}
 
 	// Run cleanup before opening/creating any file.
-	_ = deleteAgedLogFiles(logDir, cfg.MaxAgeDays)
-	_ = deleteOldLogFiles(logDir, cfg.MaxLogFiles)
+	cleanup := func() {
+		_ = deleteAgedLogFiles(logDir, cfg.LogFileName, cfg.MaxAgeDays)
+		_ = deleteOldLogFiles(logDir, cfg.LogFileName, cfg.MaxLogFiles)
+	}
+	cleanup()
+
+	now := time.Now()
+	fullPath, fileExists := resolveLogFilename(cfg, logDir, now)
+	// filePath returns the path of writer i for a run (or day) starting at t.
+	filePath := func(i int, t time.Time) string {
+		path, _ := resolveLogFilename(cfg, logDir, t)
+		if i == 1 {
+			path = strings.TrimSuffix(path, ".log") + jsonLogSuffix
+		}
+		return path
+	}
+	paths := []string{fullPath}
+	if cfg.DualFileOutput {
+		paths = append(paths, filePath(1, now))
+	}
+	_ = enforceTotalLogBytes(logDir, cfg.LogFileName, cfg.MaxTotalLogBytes, paths...)
 
-	fullPath, fileExists := resolveLogFilename(cfg, logDir)
+	var guard *diskGuard
+	if cfg.MaxTotalLogBytes > 0 {
+		guard = newDiskGuard(logDir, cfg.LogFileName, paths, cfg.MaxTotalLogBytes, cfg.MaxFileSize)
+	}
 
-	lj := &lumberjack.Logger{
-		Filename:   fullPath,
-		MaxSize:    cfg.MaxFileSize, // MB; 0 → lumberjack default (100 MB)
-		MaxBackups: 3,
-		MaxAge:     cfg.MaxAgeDays,
+	fws := make([]*FileWriterWithLevel, len(paths))
+	for i, path := range paths {
+		// In dual mode the main file is pretty and the sibling raw JSON.
+		formatted := cfg.Formatted
+		if cfg.DualFileOutput {
+			formatted = i == 1
+		}
+		fws[i] = &FileWriterWithLevel{
+			Logger:     newLumberjack(cfg, path),
+			Formatted:  formatted,
+			Filter:     newFieldFilter(cfg, OutputFile),
+			FieldOrder: cfg.FieldOrder,
+			Layout:     cfg.FieldsLayout,
+			FieldWidth: cfg.FieldWidth,
+			guard:      guard,
+			state:      &fileState{},
+		}
+		if cfg.DailyRotate {
+			i := i
+			fws[i].daily = newDailySwitch(fws[i].Logger, now, func(t time.Time) string {
+				return filePath(i, t)
+			}, func(path string) *lumberjack.Logger {
+				return newLumberjack(cfg, path)
+			}, cleanup)
+		}
 	}
 
-	// Write the run-separator banner.
+	// Write the run-separator banner, never into the JSON sibling.
 	// For daily mode, append a restart marker when the file already exists.
-	if fileExists {
-		writeRestartSeparator(lj)
-	} else {
-		writeRunSeparator(lj)
+	switch {
+	case cfg.DisableRunSeparator:
+	case fileExists:
+		writeRestartSeparator(fws[0].Logger, cfg.RunBanner)
+	default:
+		writeRunSeparator(fws[0].Logger, cfg.RunBanner)
+	}
+
+	return fws, nil
+}
+
+// newLumberjack returns a rotating writer for path with cfg's rotation settings.
+func newLumberjack(cfg CofigLogger, path string) *lumberjack.Logger {
+	maxBackups := cfg.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
 	}
 
-	return &FileWriterWithLevel{
-		Logger:    lj,
-		Formatted: cfg.Formatted,
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    cfg.MaxFileSize, // MB; 0 → lumberjack default (100 MB)
+		MaxBackups: maxBackups,
+		MaxAge:     cfg.MaxAgeDays, // days; 0 → no age limit
+		Compress:   cfg.Compress,
 	}
 }
 
 // writeRestartSeparator is written into an existing daily log file when the
 // process restarts within the same day.
-func writeRestartSeparator(lj *lumberjack.Logger) {
+func writeRestartSeparator(lj *lumberjack.Logger, tmpl string) {
 	now := time.Now()
-	line1 := fmt.Sprintf("  Restarted : %s", now.Format("2006-01-02 15:04:05"))
-	width := 50
-	if len(line1)+4 > width {
-		width = len(line1) + 4
+	lines := renderBanner(tmpl, RunBannerData{Time: now, Restarted: true, File: lj.Filename}, []string{
+		"  ↺  PROCESS RESTARTED",
+		fmt.Sprintf("  Restarted : %s", now.Format("2006-01-02 15:04:05")),
+	})
+	_, _ = lj.Write([]byte(drawBox(lines)))
+}
+
+// =============================
+// Shutdown
+// =============================
+
+// Close writes every entry still queued by the global logger's async,
+// remote and webhook writers, closes its log files and stops the background
+// goroutines started by InitLogger. Call it before the program exits, e.g.
+// with defer in main; calling it again does nothing. Entries logged after
+// Close still reach the console but are no longer written to the files or
+// sent to the webhook.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if global == nil {
+		return nil
+	}
+	return global.Close()
+}
+
+// Flush writes every entry queued by the global logger's async writer and
+// sends those queued for the remote and webhook outputs, without stopping
+// anything, e.g. before a CLI exits through os.Exit.
+func Flush() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if global != nil {
+		global.Flush()
 	}
-	top := "┌" + strings.Repeat("─", width) + "┐"
-	mid := "│" + fmt.Sprintf("%-*s", width, "  ↺  PROCESS RESTARTED") + "│"
-	div := "├" + strings.Repeat("─", width) + "┤"
-	row1 := "│" + fmt.Sprintf("%-*s", width, line1) + "│"
-	bottom := "└" + strings.Repeat("─", width) + "┘"
+}
+
+// Rotate closes the global logger's log files and starts fresh ones, e.g. to
+// cut a clean boundary before a test run without restarting the process.
+// It does nothing when InitLogger has not enabled file output.
+func Rotate() error {
+	mu.Lock()
+	defer mu.Unlock()
 
-	banner := fmt.Sprintf("\n%s\n%s\n%s\n%s\n%s\n\n", top, mid, div, row1, bottom)
-	_, _ = lj.Write([]byte(banner))
+	if global == nil {
+		return nil
+	}
+	return global.Rotate()
 }
 
 // =============================
@@ -403,9 +1265,18 @@ func GetLogger() zerolog.Logger {
 	return log.Logger
 }
 
+// UpdateLogLevel sets the global level; "" and unknown names fall back to info.
+// Safe to call concurrently with InitLogger and the level reloaders.
 func UpdateLogLevel(level string) {
+	mu.Lock()
+	defer mu.Unlock()
+	setGlobalLevel(level)
+}
+
+// setGlobalLevel is UpdateLogLevel for callers already holding mu.
+func setGlobalLevel(level string) {
 	parsed, err := zerolog.ParseLevel(strings.ToLower(level))
-	if err != nil {
+	if err != nil || level == "" {
 		parsed = zerolog.InfoLevel
 	}
 	zerolog.SetGlobalLevel(parsed)
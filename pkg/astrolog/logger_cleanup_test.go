@@ -0,0 +1,159 @@
+package astrolog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeleteAgedLogFilesScopedToPrefix(t *testing.T) {
+	dir := t.TempDir()
+	old := 10 * 24 * time.Hour
+	writeLog(t, dir, "app_01-03-2024_101500.log", 10, old)
+	writeLog(t, dir, "app_02-03-2024_101500-2024-03-02T12-00-00.000.log.gz", 10, old)
+	writeLog(t, dir, "app_03-03-2024_101500.log", 10, time.Hour)
+	writeLog(t, dir, "other_01-03-2024.log", 10, old)
+	writeLog(t, dir, "apps_01-03-2024.log", 10, old)
+	writeLog(t, dir, "app_edge_01-03-2024.log", 10, old)
+	writeLog(t, dir, "notes.txt", 10, old)
+
+	if err := deleteAgedLogFiles(dir, "app", 7); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app_03-03-2024_101500.log", "app_edge_01-03-2024.log", "apps_01-03-2024.log", "notes.txt", "other_01-03-2024.log"}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+}
+
+func TestDeleteAgedLogFilesDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "app_01-03-2024_101500.log", 10, 100*24*time.Hour)
+
+	if err := deleteAgedLogFiles(dir, "app", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := remaining(t, dir); len(got) != 1 {
+		t.Errorf("remaining = %v, want the file kept", got)
+	}
+}
+
+func TestDeleteOldLogFilesScopedToPrefix(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "app_01-03-2024_101500.log", 10, 3*time.Hour)
+	writeLog(t, dir, "app_02-03-2024_101500.log", 10, 2*time.Hour)
+	writeLog(t, dir, "app_03-03-2024_101500.log", 10, time.Hour)
+	writeLog(t, dir, "other_01-03-2024.log", 10, 4*time.Hour)
+
+	if err := deleteOldLogFiles(dir, "app", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app_02-03-2024_101500.log", "app_03-03-2024_101500.log", "other_01-03-2024.log"}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+}
+
+// rotateCompressed writes to a lumberjack file with Compress set, rotates it
+// n times and waits for the n .gz backups.
+func rotateCompressed(t *testing.T, dir, name string, n int) {
+	t.Helper()
+	lj := newLumberjack(CofigLogger{Compress: true}, filepath.Join(dir, name))
+	defer lj.Close()
+	for i := 0; i < n; i++ {
+		if _, err := lj.Write(bytes.Repeat([]byte("frame captured\n"), 1000)); err != nil {
+			t.Fatal(err)
+		}
+		if err := lj.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond) // backup names have millisecond precision
+	}
+	if _, err := lj.Write([]byte("current\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		gz, _ := filepath.Glob(filepath.Join(dir, "*.log.gz"))
+		plain, _ := filepath.Glob(filepath.Join(dir, "*-*T*.log"))
+		if len(gz) == n && len(plain) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("backups not compressed: %v", remaining(t, dir))
+		}
+	}
+}
+
+// ageByName gives the files of dir modification times in name order, one
+// hour apart, the last one being the newest.
+func ageByName(t *testing.T, dir string) {
+	t.Helper()
+	names := remaining(t, dir)
+	for i, name := range names {
+		mtime := time.Now().Add(-time.Duration(len(names)-i) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompressedBackupsCountedByCleanup(t *testing.T) {
+	dir := t.TempDir()
+	const active = "app_15-03-2024_101500.log"
+	rotateCompressed(t, dir, active, 3)
+	writeLog(t, dir, "other_15-03-2024_101500-2024-03-15T10-00-00.000.log.gz", 10, 10*time.Hour)
+
+	// Backup names sort by time, before the active file ("app_…-<time>.log.gz"
+	// < "app_….log").
+	ageByName(t, dir)
+	gz, _ := filepath.Glob(filepath.Join(dir, "app_*.log.gz"))
+	slices.Sort(gz)
+
+	// Count-based: the .gz backups are the oldest of the family.
+	if err := deleteOldLogFiles(dir, "app", 2); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Base(gz[2]), active, "other_15-03-2024_101500-2024-03-15T10-00-00.000.log.gz"}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("after deleteOldLogFiles: %v, want %v", got, want)
+	}
+
+	// Size-based: the last backup goes; the active file stays even when it
+	// alone is over the budget.
+	if err := enforceTotalLogBytes(dir, "app", 1, filepath.Join(dir, active)); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{active, "other_15-03-2024_101500-2024-03-15T10-00-00.000.log.gz"}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Errorf("after enforceTotalLogBytes: %v, want %v", got, want)
+	}
+}
+
+func TestCompressedJSONBackupsAreTheirOwnFamily(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, "app_15-03-2024-2024-03-15T08-00-00.000.log.gz", 10, 6*time.Hour)
+	writeLog(t, dir, "app_15-03-2024_json-2024-03-15T08-00-00.000.log.gz", 10, 5*time.Hour)
+	writeLog(t, dir, "app_15-03-2024-2024-03-15T09-00-00.000.log.gz", 10, 4*time.Hour)
+	writeLog(t, dir, "app_15-03-2024_json-2024-03-15T09-00-00.000.log.gz", 10, 3*time.Hour)
+	writeLog(t, dir, "app_15-03-2024.log", 10, 2*time.Hour)
+	writeLog(t, dir, "app_15-03-2024_json.log", 10, time.Hour)
+
+	if err := deleteOldLogFiles(dir, "app", 2); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"app_15-03-2024-2024-03-15T09-00-00.000.log.gz",
+		"app_15-03-2024.log",
+		"app_15-03-2024_json-2024-03-15T09-00-00.000.log.gz",
+		"app_15-03-2024_json.log",
+	}
+	if got := remaining(t, dir); !slices.Equal(got, want) {
+		t.Errorf("remaining = %v, want %v", got, want)
+	}
+}
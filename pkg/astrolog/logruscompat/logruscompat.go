@@ -0,0 +1,192 @@
+// ================ Version : V1.1.4 ===========
+
+// Package logruscompat mirrors the part of the logrus API used by legacy
+// call sites (WithField, WithFields, WithError, Debug/Info/Warn/Error and
+// their f variants, SetLevel) on top of the astrolog global logger, so code
+// can move over one file at a time. Entries go to the same outputs, with
+// the same format and rotation, as astrolog's own; callers are reported at
+// the legacy call site, not inside this package.
+//
+// Replace the import
+//
+//	log "github.com/sirupsen/logrus"
+//
+// with
+//
+//	log "github.com/Asteroidea-tn/asterogo/pkg/astrolog/logruscompat"
+package logruscompat
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+	"github.com/rs/zerolog"
+)
+
+// ErrorKey is the field WithError stores the error under, as in logrus.
+const ErrorKey = "error"
+
+// shimFrames is the number of frames between the legacy call site and
+// zerolog: the exported method and log.
+const shimFrames = 2
+
+// Fields is logrus.Fields.
+type Fields map[string]interface{}
+
+// =============================
+// Levels
+// =============================
+
+// Level is logrus.Level; the numbering matches logrus.
+type Level uint32
+
+const (
+	PanicLevel Level = iota
+	FatalLevel
+	ErrorLevel
+	WarnLevel
+	InfoLevel
+	DebugLevel
+	TraceLevel
+)
+
+var toZerolog = map[Level]zerolog.Level{
+	PanicLevel: zerolog.PanicLevel,
+	FatalLevel: zerolog.FatalLevel,
+	ErrorLevel: zerolog.ErrorLevel,
+	WarnLevel:  zerolog.WarnLevel,
+	InfoLevel:  zerolog.InfoLevel,
+	DebugLevel: zerolog.DebugLevel,
+	TraceLevel: zerolog.TraceLevel,
+}
+
+// String returns the level name as logrus spells it ("warning" for WarnLevel).
+func (l Level) String() string {
+	if l == WarnLevel {
+		return "warning"
+	}
+	if zl, ok := toZerolog[l]; ok {
+		return zl.String()
+	}
+	return "unknown"
+}
+
+// SetLevel sets the astrolog global level, which also applies to astrolog's
+// own call sites.
+func SetLevel(level Level) {
+	zl, ok := toZerolog[level]
+	if !ok {
+		zl = zerolog.InfoLevel
+	}
+	astrolog.UpdateLogLevel(zl.String())
+}
+
+// GetLevel returns the astrolog global level.
+func GetLevel() Level {
+	global := zerolog.GlobalLevel()
+	for l, zl := range toZerolog {
+		if zl == global {
+			return l
+		}
+	}
+	return InfoLevel
+}
+
+// =============================
+// Entry
+// =============================
+
+// Entry is logrus.Entry: a set of fields waiting to be logged. It is never
+// modified once built, so one Entry can be kept and logged from many times,
+// and every With* call returns a new Entry.
+type Entry struct {
+	Data Fields
+}
+
+// WithField returns an Entry holding key.
+func WithField(key string, value interface{}) *Entry {
+	return &Entry{Data: Fields{key: value}}
+}
+
+// WithFields returns an Entry holding fields.
+func WithFields(fields Fields) *Entry {
+	return &Entry{Data: maps.Clone(fields)}
+}
+
+// WithError returns an Entry holding err under ErrorKey.
+func WithError(err error) *Entry {
+	return &Entry{Data: Fields{ErrorKey: err}}
+}
+
+// WithField returns a copy of e with key added.
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+// WithFields returns a copy of e with fields added; they win over e's own.
+func (e *Entry) WithFields(fields Fields) *Entry {
+	data := make(Fields, len(e.Data)+len(fields))
+	maps.Copy(data, e.Data)
+	maps.Copy(data, fields)
+	return &Entry{Data: data}
+}
+
+// WithError returns a copy of e with err under ErrorKey.
+func (e *Entry) WithError(err error) *Entry {
+	return e.WithFields(Fields{ErrorKey: err})
+}
+
+func (e *Entry) Debug(args ...interface{}) { e.log(zerolog.DebugLevel, fmt.Sprint(args...)) }
+func (e *Entry) Info(args ...interface{})  { e.log(zerolog.InfoLevel, fmt.Sprint(args...)) }
+func (e *Entry) Warn(args ...interface{})  { e.log(zerolog.WarnLevel, fmt.Sprint(args...)) }
+func (e *Entry) Error(args ...interface{}) { e.log(zerolog.ErrorLevel, fmt.Sprint(args...)) }
+
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	e.log(zerolog.DebugLevel, fmt.Sprintf(format, args...))
+}
+func (e *Entry) Infof(format string, args ...interface{}) {
+	e.log(zerolog.InfoLevel, fmt.Sprintf(format, args...))
+}
+func (e *Entry) Warnf(format string, args ...interface{}) {
+	e.log(zerolog.WarnLevel, fmt.Sprintf(format, args...))
+}
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	e.log(zerolog.ErrorLevel, fmt.Sprintf(format, args...))
+}
+
+// log writes msg with e's fields through the astrolog global logger. It
+// must be called directly from an exported function or method, so the
+// caller is shimFrames up.
+func (e *Entry) log(level zerolog.Level, msg string) {
+	logger := astrolog.GetLogger()
+	event := logger.WithLevel(level)
+	if event == nil {
+		return
+	}
+	event.CallerSkipFrame(shimFrames).Fields(map[string]interface{}(e.Data)).Msg(msg)
+}
+
+// =============================
+// Package-level logging
+// =============================
+
+var std = &Entry{}
+
+func Debug(args ...interface{}) { std.log(zerolog.DebugLevel, fmt.Sprint(args...)) }
+func Info(args ...interface{})  { std.log(zerolog.InfoLevel, fmt.Sprint(args...)) }
+func Warn(args ...interface{})  { std.log(zerolog.WarnLevel, fmt.Sprint(args...)) }
+func Error(args ...interface{}) { std.log(zerolog.ErrorLevel, fmt.Sprint(args...)) }
+
+func Debugf(format string, args ...interface{}) {
+	std.log(zerolog.DebugLevel, fmt.Sprintf(format, args...))
+}
+func Infof(format string, args ...interface{}) {
+	std.log(zerolog.InfoLevel, fmt.Sprintf(format, args...))
+}
+func Warnf(format string, args ...interface{}) {
+	std.log(zerolog.WarnLevel, fmt.Sprintf(format, args...))
+}
+func Errorf(format string, args ...interface{}) {
+	std.log(zerolog.ErrorLevel, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,153 @@
+package logruscompat_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	log "github.com/Asteroidea-tn/asterogo/pkg/astrolog/logruscompat"
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// captureLogs sends the global logger, with callers, to a buffer for the
+// rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev, prevLevel := zlog.Logger, zerolog.GlobalLevel()
+	zlog.Logger = zerolog.New(&buf).With().Caller().Logger()
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		zlog.Logger = prev
+		zerolog.SetGlobalLevel(prevLevel)
+	})
+	return &buf
+}
+
+// entries decodes the lines of buf and empties it.
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		out = append(out, e)
+	}
+	buf.Reset()
+	return out
+}
+
+func TestWithFieldsAndError(t *testing.T) {
+	logs := captureLogs(t)
+
+	fields := log.Fields{"camera": "gate", "attempt": 2}
+	log.WithFields(fields).WithError(errors.New("timeout")).WithField("attempt", 3).Warnf("capture %s", "failed")
+	fields["camera"] = "changed after the call"
+
+	got := entries(t, logs)
+	if len(got) != 1 {
+		t.Fatalf("got %d entries, want 1", len(got))
+	}
+	e := got[0]
+	if e["level"] != "warn" || e["message"] != "capture failed" || e["camera"] != "gate" ||
+		e["attempt"] != float64(3) || e[log.ErrorKey] != "timeout" {
+		t.Errorf("entry = %v", e)
+	}
+}
+
+func TestEntryReuse(t *testing.T) {
+	logs := captureLogs(t)
+
+	base := log.WithField("camera", "gate")
+	a := base.WithField("frame", 1)
+	b := base.WithError(errors.New("boom"))
+	base.Info("base")
+	a.Info("a")
+	b.Error("b")
+	base.Info("base again")
+
+	got := entries(t, logs)
+	if len(got) != 4 {
+		t.Fatalf("got %d entries, want 4", len(got))
+	}
+	for _, i := range []int{0, 3} {
+		if _, ok := got[i]["frame"]; ok {
+			t.Errorf("entry %d: base Entry picked up a child's field: %v", i, got[i])
+		}
+		if _, ok := got[i][log.ErrorKey]; ok {
+			t.Errorf("entry %d: base Entry picked up a child's error: %v", i, got[i])
+		}
+	}
+	if got[1]["frame"] != float64(1) || got[1]["camera"] != "gate" {
+		t.Errorf("a = %v", got[1])
+	}
+	if _, ok := got[2]["frame"]; ok || got[2][log.ErrorKey] != "boom" {
+		t.Errorf("b = %v", got[2])
+	}
+	if len(base.Data) != 1 {
+		t.Errorf("base.Data = %v, want camera only", base.Data)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	logs := captureLogs(t)
+
+	log.SetLevel(log.WarnLevel)
+	if got := log.GetLevel(); got != log.WarnLevel {
+		t.Errorf("GetLevel() = %v, want warning", got)
+	}
+	if zerolog.GlobalLevel() != zerolog.WarnLevel {
+		t.Errorf("global level = %v, want warn", zerolog.GlobalLevel())
+	}
+	log.Debug("dropped")
+	log.Info("dropped")
+	log.Warn("kept")
+	log.WithField("k", "v").Error("kept")
+	if got := entries(t, logs); len(got) != 2 {
+		t.Errorf("got %d entries at warning level, want 2: %v", len(got), got)
+	}
+
+	log.SetLevel(log.DebugLevel)
+	log.Debugf("kept %d", 1)
+	if got := entries(t, logs); len(got) != 1 || got[0]["message"] != "kept 1" {
+		t.Errorf("debug entries = %v", got)
+	}
+
+	if log.WarnLevel.String() != "warning" || log.InfoLevel.String() != "info" {
+		t.Errorf("level names: %s, %s", log.WarnLevel, log.InfoLevel)
+	}
+}
+
+func TestCallerIsLegacyCallSite(t *testing.T) {
+	logs := captureLogs(t)
+
+	_, file, line, _ := runtime.Caller(0)
+	log.Info("package level")                           // line+1
+	log.WithField("k", "v").Infof("entry %s", "method") // line+2
+	entry := log.WithError(errors.New("x"))
+	entry.Error("kept entry") // line+4
+
+	want := filepath.Base(file)
+	got := entries(t, logs)
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want 3", len(got))
+	}
+	for i, e := range got {
+		caller, _ := e["caller"].(string)
+		wantLine := fmt.Sprintf("%s:%d", want, line+[]int{1, 2, 4}[i])
+		if !strings.HasSuffix(caller, wantLine) {
+			t.Errorf("entry %d: caller = %q, want …%s", i, caller, wantLine)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// global is the Logger installed by the last InitLogger call. Guarded by mu.
+var global *Logger
+
+// =============================
+// Named Logger
+// =============================
+
+// Logger is an independent logger built from its own CofigLogger: its own
+// file, level, filters and background writers. It never touches log.Logger.
+type Logger struct {
+	zerolog.Logger
+
+	async      *AsyncWriterWithLevel   // nil unless cfg.Async
+	webhook    *WebhookWriterWithLevel // nil unless cfg.WebhookURL
+	remote     *RemoteWriterWithLevel  // nil unless cfg.RemoteAddr
+	guardStop  chan struct{}           // nil unless cfg.MaxTotalLogBytes
+	stopResize func()                  // nil unless cfg.ConsoleFitWidth
+	closers    []io.Closer             // file and syslog outputs
+	files      []*FileWriterWithLevel  // file outputs, for Rotate
+	closeOnce  sync.Once
+}
+
+// New builds a Logger from cfg without changing the global logger, e.g. to
+// give a subsystem its own log file. Unlike InitLogger it fails when
+// cfg.Validate reports a problem or the file, syslog or remote output
+// can't be opened.
+//
+// cfg.LogLevel applies to this Logger only, but zerolog's global level
+// (set by InitLogger and UpdateLogLevel) still filters first.
+//
+// Two Loggers must not share a LogFileName in the same rotation mode, or
+// they will write to the same file. Call Close when done with it.
+func New(cfg CofigLogger) (*Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	setupZerolog()
+
+	l, err := build(cfg)
+	if err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	level, err := zerolog.ParseLevel(strings.ToLower(cfg.LogLevel))
+	if err != nil || cfg.LogLevel == "" {
+		level = zerolog.InfoLevel
+	}
+	l.Logger = l.Logger.Level(level)
+	return l, nil
+}
+
+// Close flushes the async, remote and webhook writers, stops the background
+// goroutines of l and closes its file and syslog outputs. It is safe to call
+// more than once.
+func (l *Logger) Close() error {
+	l.closeOnce.Do(func() {
+		if l.async != nil {
+			_ = l.async.Close()
+		}
+		if l.remote != nil {
+			_ = l.remote.Close()
+		}
+		if l.webhook != nil {
+			_ = l.webhook.Close()
+		}
+		if l.guardStop != nil {
+			close(l.guardStop)
+		}
+		if l.stopResize != nil {
+			l.stopResize()
+		}
+		for _, c := range l.closers {
+			_ = c.Close()
+		}
+	})
+	return nil
+}
+
+// Flush writes every entry l's async writer has queued and sends those
+// queued for its remote and webhook outputs. It does nothing after Close.
+func (l *Logger) Flush() {
+	if l.async != nil {
+		l.async.Flush()
+	}
+	if l.remote != nil {
+		l.remote.Flush()
+	}
+	if l.webhook != nil {
+		l.webhook.Flush()
+	}
+}
+
+// Rotate closes l's log files and starts fresh ones, the old files being
+// renamed like any lumberjack backup. Queued async entries are written
+// first so they land before the boundary. A Logger without file output
+// does nothing.
+func (l *Logger) Rotate() error {
+	if l.async != nil {
+		l.async.Flush()
+	}
+	var errs []error
+	for _, fw := range l.files {
+		if err := fw.rotate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,236 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultRemoteQueueSize  = 10000
+	defaultRemoteMaxBackoff = 30 * time.Second
+	remoteMinBackoff        = 100 * time.Millisecond
+	remoteDialTimeout       = 5 * time.Second
+	remoteWriteTimeout      = 5 * time.Second
+)
+
+// =============================
+// Remote Writer
+// =============================
+
+// RemoteWriterWithLevel sends raw JSON entries, one per line, to a TCP or
+// UDP collector such as rsyslog's imtcp/imudp. Entries go through a bounded
+// queue drained by a background goroutine: when the socket is slow or down
+// new entries are dropped (and counted) so logging never blocks. A lost
+// connection is re-dialled with exponential backoff; the entry being sent
+// is kept until it goes through. Fatal and panic entries wait for the queue
+// to drain because the process is about to die.
+type RemoteWriterWithLevel struct {
+	Network string // "tcp" or "udp"
+	Address string // host:port
+	Filter  FieldFilter
+
+	queue      chan []byte
+	flush      chan chan struct{}
+	maxBackoff time.Duration
+	conn       net.Conn // owned by run
+	gaveUp     bool     // owned by run; set when sending fails after Close
+	dropped    atomic.Uint64
+	stop       chan struct{}
+	stopOnce   sync.Once
+	done       chan struct{}
+}
+
+// newRemoteWriter dials cfg.RemoteAddr once; a collector that can't be
+// reached at init leaves the output out instead of queueing forever.
+func newRemoteWriter(cfg CofigLogger) (*RemoteWriterWithLevel, error) {
+	network, addr, err := parseRemoteAddr(cfg.RemoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, addr, remoteDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	queueSize := cfg.RemoteQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultRemoteQueueSize
+	}
+	maxBackoff := cfg.RemoteMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRemoteMaxBackoff
+	}
+
+	w := &RemoteWriterWithLevel{
+		Network:    network,
+		Address:    addr,
+		Filter:     newFieldFilter(cfg, OutputRemote),
+		queue:      make(chan []byte, queueSize),
+		flush:      make(chan chan struct{}),
+		maxBackoff: maxBackoff,
+		conn:       conn,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// parseRemoteAddr splits a RemoteAddr ("tcp://host:port" or
+// "udp://host:port") into the network and address expected by net.Dial.
+func parseRemoteAddr(raw string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid RemoteAddr %q: %w", raw, err)
+	}
+	if (u.Scheme != "tcp" && u.Scheme != "udp") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid RemoteAddr %q: use tcp://host:port or udp://host:port", raw)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+func (w *RemoteWriterWithLevel) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *RemoteWriterWithLevel) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	// zerolog reuses p after Write returns.
+	entry := bytes.TrimRight(w.Filter.ApplyJSON(p), "\n")
+	entry = append(append([]byte(nil), entry...), '\n')
+
+	if level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		select {
+		case w.queue <- entry:
+			w.Flush()
+		case <-w.done:
+		}
+		return len(p), nil
+	}
+
+	select {
+	case <-w.done:
+		w.dropped.Add(1)
+	case w.queue <- entry:
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Flush blocks until every entry queued before the call has been sent or
+// given up on.
+func (w *RemoteWriterWithLevel) Flush() {
+	ack := make(chan struct{})
+	select {
+	case w.flush <- ack:
+		<-ack
+	case <-w.done:
+	}
+}
+
+// Dropped returns how many entries were discarded because the queue was full
+// or the collector was still unreachable at Close.
+func (w *RemoteWriterWithLevel) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close stops the background loop after sending what is still queued and
+// closes the connection. Entries that can't be sent without waiting for a
+// reconnect are dropped.
+func (w *RemoteWriterWithLevel) Close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+	return nil
+}
+
+func (w *RemoteWriterWithLevel) run() {
+	defer close(w.done)
+	defer func() {
+		if w.conn != nil {
+			_ = w.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case entry := <-w.queue:
+			w.send(entry)
+		case ack := <-w.flush:
+			w.drain()
+			close(ack)
+		case <-w.stop:
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain sends every queued entry without blocking for new ones.
+func (w *RemoteWriterWithLevel) drain() {
+	for {
+		select {
+		case entry := <-w.queue:
+			w.send(entry)
+		default:
+			return
+		}
+	}
+}
+
+// send writes entry, reconnecting with backoff until it goes through. Once
+// Close has been called a failed attempt is not retried: entry and the rest
+// of the queue are dropped so Close does not hang on a dead collector.
+func (w *RemoteWriterWithLevel) send(entry []byte) {
+	if w.gaveUp {
+		w.dropped.Add(1)
+		return
+	}
+
+	backoff := remoteMinBackoff
+	for stopping := false; ; {
+		if w.conn == nil {
+			if conn, err := net.DialTimeout(w.Network, w.Address, remoteDialTimeout); err == nil {
+				w.conn = conn
+			}
+		}
+		if w.conn != nil {
+			_ = w.conn.SetWriteDeadline(time.Now().Add(remoteWriteTimeout))
+			if _, err := w.conn.Write(entry); err == nil {
+				return
+			}
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+
+		if stopping {
+			w.gaveUp = true
+			w.dropped.Add(1)
+			return
+		}
+		select {
+		case <-w.stop:
+			stopping = true
+		case <-time.After(backoff):
+			backoff = min(2*backoff, w.maxBackoff)
+		}
+	}
+}
+
+// RemoteDropped returns how many entries the current remote writer has
+// dropped, or 0 when no RemoteAddr is configured.
+func RemoteDropped() uint64 {
+	mu.Lock()
+	defer mu.Unlock()
+	if global == nil || global.remote == nil {
+		return 0
+	}
+	return global.remote.Dropped()
+}
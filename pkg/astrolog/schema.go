@@ -0,0 +1,212 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// current is the configuration of the last InitLogger call. Guarded by mu.
+var current CofigLogger
+
+// ErrInvalidEntry is returned by ValidateEntry for lines that break the schema.
+var ErrInvalidEntry = errors.New("log entry does not match schema")
+
+// =============================
+// Schema
+// =============================
+
+// schemaProperty is the subset of JSON Schema used to describe one field.
+type schemaProperty struct {
+	Type        string   `json:"type,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+type schemaDocument struct {
+	Schema               string                    `json:"$schema"`
+	Title                string                    `json:"title"`
+	Type                 string                    `json:"type"`
+	Required             []string                  `json:"required"`
+	Properties           map[string]schemaProperty `json:"properties"`
+	AdditionalProperties bool                      `json:"additionalProperties"`
+	FieldOrder           []string                  `json:"x-field-order,omitempty"`
+}
+
+// Schema returns a JSON Schema (draft 2020-12) describing the JSON lines
+// emitted with the configuration of the last InitLogger call: the standard
+// fields, masked fields and the pinned field order of formatted output.
+// Call it again after InitLogger to get the updated contract.
+func Schema() []byte {
+	mu.Lock()
+	cfg := current
+	mu.Unlock()
+
+	out, _ := json.MarshalIndent(buildSchema(cfg), "", "  ")
+	return out
+}
+
+// ValidateEntry checks one emitted JSON line against Schema. The returned
+// error wraps ErrInvalidEntry and lists every violation.
+func ValidateEntry(p []byte) error {
+	mu.Lock()
+	cfg := current
+	mu.Unlock()
+
+	return validateEntry(buildSchema(cfg), p)
+}
+
+func buildSchema(cfg CofigLogger) schemaDocument {
+	doc := schemaDocument{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Title:  "astrolog entry",
+		Type:   "object",
+		Required: []string{
+			zerolog.TimestampFieldName,
+			zerolog.LevelFieldName,
+			zerolog.CallerFieldName,
+		},
+		AdditionalProperties: true,
+		Properties: map[string]schemaProperty{
+			zerolog.TimestampFieldName: {
+				Type:        "string",
+				Pattern:     `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}$`,
+				Description: "local time, " + logTimeFormat,
+			},
+			zerolog.LevelFieldName: {
+				Type: "string",
+				Enum: []string{"trace", "debug", "info", "warn", "error", "fatal", "panic"},
+			},
+			zerolog.MessageFieldName: {
+				Type:        "string",
+				Description: "omitted when empty",
+			},
+			zerolog.CallerFieldName: {
+				Type:        "string",
+				Pattern:     `^.+:\d+$`,
+				Description: "file:line, relative to the module",
+			},
+			zerolog.ErrorFieldName: {
+				Type: "string",
+			},
+			CorrelationIDField: {
+				Type:        "string",
+				Pattern:     `^.+$`,
+				Description: "groups the entries of one logical operation; NewID format unless set by the caller",
+			},
+		},
+	}
+
+	if cfg.Sequence {
+		doc.Required = append(doc.Required, SequenceField)
+		doc.Properties[SequenceField] = schemaProperty{
+			Type:        "integer",
+			Description: "per-process entry number, gaps mean lost lines",
+		}
+	}
+
+	for _, key := range cfg.FieldOrder {
+		if _, ok := doc.Properties[key]; !ok {
+			doc.Properties[key] = schemaProperty{Description: "pinned by FieldOrder"}
+		}
+	}
+	if len(cfg.FieldOrder) > 0 {
+		doc.FieldOrder = append([]string{CorrelationIDField}, cfg.FieldOrder...)
+	}
+
+	for _, key := range cfg.MaskFields {
+		doc.Properties[strings.ToLower(key)] = schemaProperty{
+			Type:        "string",
+			Pattern:     `^\*\*\*`,
+			Description: "masked by MaskFields",
+		}
+	}
+
+	if cfg.MaxFieldLength > 0 {
+		doc.Properties[zerolog.MessageFieldName] = schemaProperty{
+			Type:        "string",
+			Description: fmt.Sprintf("omitted when empty; other string fields are cut at %d bytes", cfg.MaxFieldLength),
+		}
+	}
+
+	return doc
+}
+
+// validateEntry applies the subset of JSON Schema emitted by buildSchema.
+func validateEntry(doc schemaDocument, p []byte) error {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(p, &entry); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEntry, err)
+	}
+
+	var problems []string
+	for _, key := range doc.Required {
+		if _, ok := entry[key]; !ok {
+			problems = append(problems, fmt.Sprintf("missing %q", key))
+		}
+	}
+
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		prop, ok := doc.Properties[key]
+		if !ok {
+			// Masked keys are matched case-insensitively, like FieldFilter.
+			prop, ok = doc.Properties[strings.ToLower(key)]
+		}
+		if !ok {
+			continue
+		}
+		if msg := checkProperty(prop, entry[key]); msg != "" {
+			problems = append(problems, fmt.Sprintf("%q %s", key, msg))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidEntry, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func checkProperty(prop schemaProperty, value interface{}) string {
+	if prop.Type == "" {
+		return ""
+	}
+	if prop.Type == "integer" {
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Sprintf("must be an integer, got %v", value)
+		}
+		return ""
+	}
+	s, isString := value.(string)
+	if prop.Type == "string" && !isString {
+		return fmt.Sprintf("must be a string, got %T", value)
+	}
+	if len(prop.Enum) > 0 {
+		found := false
+		for _, allowed := range prop.Enum {
+			if s == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("must be one of %v, got %q", prop.Enum, s)
+		}
+	}
+	if prop.Pattern != "" && !regexp.MustCompile(prop.Pattern).MatchString(s) {
+		return fmt.Sprintf("must match %s, got %q", prop.Pattern, s)
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package astrolog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// initTestLogger runs InitLogger with cfg from a temporary working
+// directory, so the files land in its ./logs, and closes the logger at the
+// end of the test. Console output is discarded.
+func initTestLogger(t *testing.T, cfg CofigLogger) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = devNull
+	level := zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		_ = Close()
+		zerolog.SetGlobalLevel(level)
+		os.Stderr = stderr
+		devNull.Close()
+		_ = os.Chdir(wd)
+	})
+
+	if err := InitLoggerE(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "logs")
+}
+
+// logLines returns the lines of the log files in logDir.
+func logLines(t *testing.T, logDir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(logDir, "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		f.Close()
+	}
+	return lines
+}
+
+func TestSchemaValidatesEmittedEntries(t *testing.T) {
+	logDir := initTestLogger(t, CofigLogger{
+		LogLevel:    "debug",
+		LogToFile:   true,
+		LogFileName: "app",
+		Formatted:   true,
+		Sequence:    true,
+		MaskFields:  []string{"password"},
+		FieldOrder:  []string{"camera_id"},
+		// The banner is for humans; machine-read files go without it.
+		DisableRunSeparator: true,
+	})
+
+	log.Debug().Str("camera_id", "cam1").Msg("capture started")
+	log.Info().Str("password", "hunter22").Msg("login")
+	log.Error().Err(errors.New("boom")).Int("attempt", 2).Send()
+	logger := FromContext(WithCorrelationID(context.Background(), NewID()))
+	logger.Warn().Msg("retrying")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := logLines(t, logDir)
+	if len(lines) < 4 {
+		t.Fatalf("got %d lines, want at least 4: %q", len(lines), lines)
+	}
+	for _, line := range lines {
+		if err := ValidateEntry([]byte(line)); err != nil {
+			t.Errorf("%s: %v", line, err)
+		}
+	}
+}
+
+func TestValidateEntryRejectsMalformedEntry(t *testing.T) {
+	initTestLogger(t, CofigLogger{
+		LogLevel:   "info",
+		Formatted:  true,
+		Sequence:   true,
+		MaskFields: []string{"password"},
+	})
+
+	entry := `{"level":"loud","time":"2024-01-02T03:04:05Z","seq":"7","password":"hunter22","message":"x"}`
+	err := ValidateEntry([]byte(entry))
+	if !errors.Is(err, ErrInvalidEntry) {
+		t.Fatalf("err = %v, want ErrInvalidEntry", err)
+	}
+	for _, want := range []string{`missing "caller"`, `"level"`, `"time"`, `"seq"`, `"password"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %s", err, want)
+		}
+	}
+
+	if err := ValidateEntry([]byte("not json")); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("err = %v for non-JSON input, want ErrInvalidEntry", err)
+	}
+}
+
+func TestSchemaFollowsConfiguration(t *testing.T) {
+	initTestLogger(t, CofigLogger{LogLevel: "info", Formatted: true})
+	before := string(Schema())
+	if strings.Contains(before, `"seq"`) || strings.Contains(before, `"password"`) {
+		t.Fatalf("schema lists fields that are not configured:\n%s", before)
+	}
+
+	if err := InitLoggerE(CofigLogger{
+		LogLevel:   "info",
+		Formatted:  true,
+		Sequence:   true,
+		MaskFields: []string{"password"},
+		FieldOrder: []string{"camera_id"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	after := string(Schema())
+	for _, want := range []string{`"seq"`, `"password"`, `"x-field-order"`, `"camera_id"`} {
+		if !strings.Contains(after, want) {
+			t.Errorf("schema lacks %s after InitLogger:\n%s", want, after)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// SequenceField carries the per-process entry number when
+// CofigLogger.Sequence is on.
+const SequenceField = "seq"
+
+// sequence numbers entries for the whole process; InitLogger never resets it.
+var sequence atomic.Uint64
+
+// sequenceHook stamps every entry with the next sequence number, so a
+// consumer can spot lost lines as gaps.
+type sequenceHook struct{}
+
+func (sequenceHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	e.Uint64(SequenceField, sequence.Add(1))
+}
@@ -0,0 +1,113 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// defaultShutdownTimeout is used when CofigLogger.ShutdownTimeout is 0.
+const defaultShutdownTimeout = 5 * time.Second
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func(ctx context.Context)
+	shutdownOnce  sync.Once
+)
+
+// =============================
+// Shutdown hooks
+// =============================
+
+// RegisterShutdownHook adds fn to the hooks run when a fatal or panic entry
+// is logged, after the entry and everything queued before it has been
+// written, and before the process exits or panics. Hooks run once per
+// process, last registered first, sharing a ctx that expires after
+// CofigLogger.ShutdownTimeout: a hook still running then is abandoned
+// along with the ones after it. A panicking hook is skipped.
+func RegisterShutdownHook(fn func(ctx context.Context)) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, fn)
+}
+
+// runShutdownHooks runs the registered hooks in LIFO order within timeout.
+// Later calls wait for the first one to finish and do nothing.
+func runShutdownHooks(timeout time.Duration) {
+	shutdownOnce.Do(func() {
+		shutdownMu.Lock()
+		hooks := make([]func(context.Context), len(shutdownHooks))
+		copy(hooks, shutdownHooks)
+		shutdownMu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		for i := len(hooks) - 1; i >= 0; i-- {
+			done := make(chan struct{})
+			go func(fn func(context.Context)) {
+				defer close(done)
+				defer func() { _ = recover() }()
+				fn(ctx)
+			}(hooks[i])
+
+			select {
+			case <-done:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// shutdownWriter is the outermost writer of a Logger. Fatal and panic
+// entries are written through Out first (async, remote and webhook outputs
+// flush synchronously for them), then the shutdown hooks run. For fatal
+// entries the outputs are closed and exit is called, instead of zerolog's
+// own os.Exit.
+type shutdownWriter struct {
+	Out     zerolog.LevelWriter
+	timeout time.Duration
+	exit    func(code int)
+}
+
+func newShutdownWriter(out zerolog.LevelWriter, cfg CofigLogger) shutdownWriter {
+	timeout := cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	exit := cfg.ExitFunc
+	if exit == nil {
+		exit = os.Exit
+	}
+	return shutdownWriter{Out: out, timeout: timeout, exit: exit}
+}
+
+func (w shutdownWriter) Write(p []byte) (int, error) {
+	return w.Out.Write(p)
+}
+
+func (w shutdownWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	n, err := w.Out.WriteLevel(level, p)
+	switch level {
+	case zerolog.FatalLevel:
+		runShutdownHooks(w.timeout)
+		_ = w.Close()
+		w.exit(1)
+	case zerolog.PanicLevel:
+		runShutdownHooks(w.timeout)
+	}
+	return n, err
+}
+
+// Close closes Out, which zerolog also does before its own os.Exit.
+func (w shutdownWriter) Close() error {
+	if c, ok := w.Out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package astrolog
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// resetShutdownHooks clears the registered hooks and lets them run again.
+func resetShutdownHooks(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		shutdownMu.Lock()
+		shutdownHooks = nil
+		shutdownOnce = sync.Once{}
+		shutdownMu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// fakeExit records the exit code and what had happened by then, then parks
+// the logging goroutine: zerolog calls os.Exit once exit returns, even
+// through runtime.Goexit.
+type fakeExit struct {
+	mu     sync.Mutex
+	code   int
+	events []string
+	done   chan struct{}
+}
+
+func newFakeExit() *fakeExit {
+	return &fakeExit{code: -1, done: make(chan struct{})}
+}
+
+func (f *fakeExit) record(event string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+func (f *fakeExit) exit(code int) {
+	f.mu.Lock()
+	f.code = code
+	f.events = append(f.events, "exit")
+	f.mu.Unlock()
+	close(f.done)
+	select {}
+}
+
+// fatal logs a fatal entry on its own goroutine and waits for the exit.
+func (f *fakeExit) fatal(t *testing.T, msg string) {
+	t.Helper()
+	go log.Fatal().Msg(msg)
+	select {
+	case <-f.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("exit was not called")
+	}
+}
+
+func TestFatalRunsHooksBeforeExit(t *testing.T) {
+	resetShutdownHooks(t)
+	exit := newFakeExit()
+	logDir := initTestLogger(t, CofigLogger{
+		LogLevel:            "info",
+		LogToFile:           true,
+		LogFileName:         "app",
+		Formatted:           true,
+		Async:               true,
+		DisableRunSeparator: true,
+		ExitFunc:            exit.exit,
+	})
+	RegisterShutdownHook(func(context.Context) { exit.record("first") })
+	RegisterShutdownHook(func(context.Context) {
+		time.Sleep(20 * time.Millisecond) // slow, but within the deadline
+		exit.record("second")
+	})
+
+	log.Info().Msg("queued")
+	exit.fatal(t, "boom")
+
+	if want := []string{"second", "first", "exit"}; !slices.Equal(exit.events, want) {
+		t.Errorf("events = %v, want %v (hooks LIFO, then exit)", exit.events, want)
+	}
+	if exit.code != 1 {
+		t.Errorf("exit code = %d, want 1", exit.code)
+	}
+
+	// The fatal entry and those queued before it were written before exit.
+	lines := logLines(t, logDir)
+	if len(lines) != 2 || !strings.Contains(lines[0], "queued") || !strings.Contains(lines[1], "boom") {
+		t.Errorf("file lines = %q", lines)
+	}
+}
+
+func TestShutdownDeadlineOnHangingHook(t *testing.T) {
+	resetShutdownHooks(t)
+	exit := newFakeExit()
+	initTestLogger(t, CofigLogger{
+		LogLevel:        "info",
+		ShutdownTimeout: 50 * time.Millisecond,
+		ExitFunc:        exit.exit,
+	})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	RegisterShutdownHook(func(context.Context) { exit.record("abandoned") })
+	RegisterShutdownHook(func(context.Context) { <-release }) // ignores ctx
+	RegisterShutdownHook(func(context.Context) { exit.record("last") })
+
+	start := time.Now()
+	exit.fatal(t, "boom")
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("exit after %v, want the 50ms deadline enforced", elapsed)
+	}
+	if want := []string{"last", "exit"}; !slices.Equal(exit.events, want) {
+		t.Errorf("events = %v, want %v", exit.events, want)
+	}
+}
+
+func TestPanickingHookIsSkipped(t *testing.T) {
+	resetShutdownHooks(t)
+	exit := newFakeExit()
+	initTestLogger(t, CofigLogger{LogLevel: "info", ExitFunc: exit.exit})
+
+	RegisterShutdownHook(func(context.Context) { exit.record("ran") })
+	RegisterShutdownHook(func(context.Context) { panic("hook bug") })
+	exit.fatal(t, "boom")
+
+	if want := []string{"ran", "exit"}; !slices.Equal(exit.events, want) {
+		t.Errorf("events = %v, want %v", exit.events, want)
+	}
+}
+
+func TestPanicEntryRunsHooks(t *testing.T) {
+	resetShutdownHooks(t)
+	initTestLogger(t, CofigLogger{LogLevel: "info"})
+	ran := false
+	RegisterShutdownHook(func(context.Context) { ran = true })
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("log.Panic did not panic")
+			}
+		}()
+		log.Panic().Msg("boom")
+	}()
+	if !ran {
+		t.Error("hook did not run before the panic")
+	}
+}
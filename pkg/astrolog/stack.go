@@ -0,0 +1,112 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// maxStackDepth bounds the frames recorded by WithStack and LogError.
+const maxStackDepth = 32
+
+// captureStack mirrors CofigLogger.CaptureStack of the last InitLogger.
+var captureStack atomic.Bool
+
+// =============================
+// Stack Errors
+// =============================
+
+// stackError is an error carrying the call stack it was wrapped at.
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+func (e *stackError) Unwrap() error { return e.err }
+
+// WithStack returns err annotated with the current call stack, which
+// entries logged with .Stack().Err(err) (and LogError) print in the "stack"
+// field when CaptureStack is set. An error that already carries a stack is
+// returned as-is, so the innermost stack wins. nil stays nil.
+func WithStack(err error) error {
+	return withStack(err, 1)
+}
+
+// withStack is WithStack recording the stack from skip frames above its
+// caller.
+func withStack(err error, skip int) error {
+	if err == nil {
+		return nil
+	}
+	var st *stackError
+	if errors.As(err, &st) {
+		return err
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+// LogError logs err at error level through the global logger. With
+// CaptureStack the entry gets a "stack" field: the stack err was wrapped
+// with by WithStack, else the stack of LogError's caller.
+func LogError(err error, msg string) {
+	logger := GetLogger()
+	event := logger.Error().CallerSkipFrame(1)
+	if captureStack.Load() {
+		event = event.Stack()
+		err = withStack(err, 1)
+	}
+	event.Err(err).Msg(msg)
+}
+
+// marshalStack is the zerolog.ErrorStackMarshaler installed by CaptureStack.
+// Frames use the keys of zerolog's pkgerrors marshaler.
+func marshalStack(err error) interface{} {
+	var st *stackError
+	if !errors.As(err, &st) {
+		return nil
+	}
+	var out []map[string]string
+	frames := runtime.CallersFrames(st.pcs)
+	for {
+		f, more := frames.Next()
+		out = append(out, map[string]string{
+			"func":   f.Function,
+			"source": filepath.Base(f.File),
+			"line":   strconv.Itoa(f.Line),
+		})
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// =============================
+// Stack Summary
+// =============================
+
+// summarizeStack renders a "stack" field decoded from JSON as its top frame,
+// "pkg.Func (file.go:42) +N frames". Values of another shape are returned
+// unchanged.
+func summarizeStack(v interface{}) interface{} {
+	frames, ok := v.([]interface{})
+	if !ok || len(frames) == 0 {
+		return v
+	}
+	top, ok := frames[0].(map[string]interface{})
+	if !ok {
+		return v
+	}
+	s := fmt.Sprintf("%v (%v:%v)", top["func"], top["source"], top["line"])
+	if len(frames) > 1 {
+		s += fmt.Sprintf(" +%d frames", len(frames)-1)
+	}
+	return s
+}
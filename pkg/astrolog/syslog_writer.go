@@ -0,0 +1,97 @@
+//go:build !windows && !plan9
+
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"bytes"
+	"fmt"
+	"log/syslog"
+	"net/url"
+
+	"github.com/rs/zerolog"
+)
+
+// =============================
+// Syslog Writer
+// =============================
+
+// SyslogWriterWithLevel forwards raw JSON entries to syslog, mapping zerolog
+// levels to syslog severities.
+type SyslogWriterWithLevel struct {
+	*syslog.Writer
+	Filter FieldFilter
+}
+
+func (s SyslogWriterWithLevel) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (s SyslogWriterWithLevel) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	msg := string(bytes.TrimRight(s.Filter.ApplyJSON(p), "\n"))
+
+	var err error
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		err = s.Writer.Debug(msg)
+	case zerolog.WarnLevel:
+		err = s.Writer.Warning(msg)
+	case zerolog.ErrorLevel:
+		err = s.Writer.Err(msg)
+	case zerolog.FatalLevel:
+		err = s.Writer.Crit(msg)
+	case zerolog.PanicLevel:
+		err = s.Writer.Alert(msg)
+	default:
+		err = s.Writer.Info(msg)
+	}
+	return len(p), err
+}
+
+// buildSyslogWriter dials the syslog daemon described by cfg.SyslogAddr.
+func buildSyslogWriter(cfg CofigLogger) (*SyslogWriterWithLevel, error) {
+	network, addr, err := parseSyslogAddr(cfg.SyslogAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := cfg.LogFileName
+	if tag == "" {
+		tag = "astrolog"
+	}
+
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogWriterWithLevel{
+		Writer: w,
+		Filter: newFieldFilter(cfg, OutputSyslog),
+	}, nil
+}
+
+// parseSyslogAddr splits a SyslogAddr into the network and address expected
+// by syslog.Dial.
+//
+//	"local"                 → local syslog daemon (/dev/log)
+//	"unix:///var/run/log"   → unix socket at that path
+//	"udp://host:514"        → remote over UDP
+//	"tcp://host:514"        → remote over TCP
+func parseSyslogAddr(raw string) (string, string, error) {
+	if raw == "local" {
+		return "", "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid SyslogAddr %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "tcp", "udp":
+		return u.Scheme, u.Host, nil
+	case "unix", "unixgram":
+		return u.Scheme, u.Path, nil
+	default:
+		return "", "", fmt.Errorf("invalid SyslogAddr %q: use local, unix://, udp:// or tcp://", raw)
+	}
+}
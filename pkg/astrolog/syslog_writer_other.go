@@ -0,0 +1,16 @@
+//go:build windows || plan9
+
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import "errors"
+
+// buildSyslogWriter always fails: log/syslog is not available on this platform.
+func buildSyslogWriter(cfg CofigLogger) (*nopLevelWriter, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+type nopLevelWriter struct{}
+
+func (nopLevelWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (nopLevelWriter) Close() error                { return nil }
@@ -0,0 +1,164 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"reflect"
+
+	"github.com/rs/zerolog"
+)
+
+// Output names, the keys of CofigLogger.Transformers.
+const (
+	OutputConsole = "console"
+	OutputFile    = "file" // both files with DualFileOutput
+	OutputSyslog  = "syslog"
+	OutputRemote  = "remote"
+	OutputWebhook = "webhook"
+)
+
+// Transformer reshapes a decoded entry for one output, e.g. renaming fields
+// for a collector. It must not modify entry, which the next transformer and
+// the output still read: return entry itself when nothing changes, or a new
+// map. Pretty outputs need "time", "level" and "message" under those names.
+type Transformer func(entry map[string]interface{}) map[string]interface{}
+
+// transform runs the Transform chain on entry and replaces its content with
+// the result. It reports whether anything changed.
+func (f FieldFilter) transform(entry map[string]interface{}) bool {
+	if len(f.Transform) == 0 {
+		return false
+	}
+	out := entry
+	for _, t := range f.Transform {
+		if next := t(out); next != nil {
+			out = next
+		}
+	}
+	if sameMap(out, entry) {
+		return false
+	}
+	clear(entry)
+	for k, v := range out {
+		entry[k] = v
+	}
+	return true
+}
+
+// cloneEntry returns a shallow copy of entry, for copy-on-write.
+func cloneEntry(entry map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(entry))
+	for k, v := range entry {
+		out[k] = v
+	}
+	return out
+}
+
+// =============================
+// Built-in transformers
+// =============================
+
+// ecsVersion is the ECS version ECSFields declares.
+const ecsVersion = "8.11.0"
+
+// ecsNames maps zerolog field names to their Elastic Common Schema names.
+var ecsNames = map[string]string{
+	zerolog.TimestampFieldName:  "@timestamp",
+	zerolog.LevelFieldName:      "log.level",
+	zerolog.CallerFieldName:     "log.origin.file.name",
+	zerolog.ErrorFieldName:      "error.message",
+	zerolog.ErrorStackFieldName: "error.stack_trace",
+	CorrelationIDField:          "trace.id",
+}
+
+// ECSFields renames the standard fields to Elastic Common Schema names
+// (time → @timestamp, level → log.level, error → error.message, …) and adds
+// ecs.version, for collectors that expect ECS. Use it on JSON outputs.
+func ECSFields() Transformer {
+	rename := RenameFields(ecsNames)
+	return func(entry map[string]interface{}) map[string]interface{} {
+		out := rename(entry)
+		if _, ok := out["ecs.version"]; ok {
+			return out
+		}
+		if sameMap(out, entry) {
+			out = cloneEntry(entry)
+		}
+		out["ecs.version"] = ecsVersion
+		return out
+	}
+}
+
+// RenameFields renames the keys of names (old → new). A new name already in
+// the entry is overwritten.
+func RenameFields(names map[string]string) Transformer {
+	return func(entry map[string]interface{}) map[string]interface{} {
+		var out map[string]interface{}
+		for from, to := range names {
+			v, ok := entry[from]
+			if !ok || from == to {
+				continue
+			}
+			if out == nil {
+				out = cloneEntry(entry)
+			}
+			delete(out, from)
+			out[to] = v
+		}
+		if out == nil {
+			return entry
+		}
+		return out
+	}
+}
+
+// DropFields removes keys from the entry, e.g. internal IDs a third-party
+// sink must not receive.
+func DropFields(keys ...string) Transformer {
+	return func(entry map[string]interface{}) map[string]interface{} {
+		var out map[string]interface{}
+		for _, k := range keys {
+			if _, ok := entry[k]; !ok {
+				continue
+			}
+			if out == nil {
+				out = cloneEntry(entry)
+			}
+			delete(out, k)
+		}
+		if out == nil {
+			return entry
+		}
+		return out
+	}
+}
+
+// KeepFields removes every key but keys and the standard "time", "level",
+// "message" and "caller" fields. Put it before renaming transformers such
+// as ECSFields, which change those names.
+func KeepFields(keys ...string) Transformer {
+	keep := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		keep[k] = true
+	}
+	return func(entry map[string]interface{}) map[string]interface{} {
+		var out map[string]interface{}
+		for k := range entry {
+			if keep[k] || standardFields[k] {
+				continue
+			}
+			if out == nil {
+				out = cloneEntry(entry)
+			}
+			delete(out, k)
+		}
+		if out == nil {
+			return entry
+		}
+		return out
+	}
+}
+
+// sameMap reports whether a and b are the same map.
+func sameMap(a, b map[string]interface{}) bool {
+	return reflect.ValueOf(a).UnsafePointer() == reflect.ValueOf(b).UnsafePointer()
+}
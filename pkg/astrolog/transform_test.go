@@ -0,0 +1,120 @@
+package astrolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// decodeLine decodes one JSON log line.
+func decodeLine(t *testing.T, p []byte) map[string]interface{} {
+	t.Helper()
+	var entry map[string]interface{}
+	if err := json.Unmarshal(p, &entry); err != nil {
+		t.Fatalf("%v: %s", err, p)
+	}
+	return entry
+}
+
+func TestTransformersDivergePerWriter(t *testing.T) {
+	var remote, file, plain bytes.Buffer
+	logger := zerolog.New(zerolog.MultiLevelWriter(
+		JSONWriterWithLevel{Out: &remote, Filter: FieldFilter{Transform: []Transformer{ECSFields(), DropFields("user_id")}}},
+		JSONWriterWithLevel{Out: &file, Filter: FieldFilter{Transform: []Transformer{KeepFields("camera")}}},
+		JSONWriterWithLevel{Out: &plain},
+	)).With().Timestamp().Logger()
+
+	logger.Info().Str("camera", "gate").Str("user_id", "u-42").Msg("frame captured")
+
+	got := decodeLine(t, remote.Bytes())
+	for _, key := range []string{"@timestamp", "log.level", "ecs.version", "camera", "message"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("remote output lacks %q: %s", key, remote.Bytes())
+		}
+	}
+	for _, key := range []string{"time", "level", "user_id"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("remote output still has %q: %s", key, remote.Bytes())
+		}
+	}
+
+	got = decodeLine(t, file.Bytes())
+	want := []string{"camera", "level", "message", "time"}
+	if keys := sortedKeys(got); !reflect.DeepEqual(keys, want) {
+		t.Errorf("file output keys = %v, want %v", keys, want)
+	}
+
+	// A writer without transformers gets the entry as logged, untouched by
+	// the others.
+	got = decodeLine(t, plain.Bytes())
+	if got["user_id"] != "u-42" || got["level"] != "info" || got["time"] == nil {
+		t.Errorf("plain output = %s", plain.Bytes())
+	}
+}
+
+func TestTransformersCopyOnWrite(t *testing.T) {
+	entry := map[string]interface{}{
+		"time": "2024-01-01T00:00:00Z", "level": "error", "message": "m",
+		"error": "boom", "user_id": "u-42", "camera": "gate",
+	}
+	orig := cloneEntry(entry)
+
+	for name, tr := range map[string]Transformer{
+		"ECSFields":    ECSFields(),
+		"RenameFields": RenameFields(map[string]string{"camera": "camera.id"}),
+		"DropFields":   DropFields("user_id"),
+		"KeepFields":   KeepFields("camera"),
+	} {
+		out := tr(entry)
+		if !reflect.DeepEqual(entry, orig) {
+			t.Fatalf("%s modified its input: %v", name, entry)
+		}
+		if sameMap(out, entry) {
+			t.Errorf("%s changed nothing", name)
+		}
+	}
+
+	// Nothing to change → the entry itself, no copy.
+	for name, tr := range map[string]Transformer{
+		"RenameFields": RenameFields(map[string]string{"absent": "x"}),
+		"DropFields":   DropFields("absent"),
+		"KeepFields":   KeepFields("error", "user_id", "camera"),
+	} {
+		if out := tr(entry); !sameMap(out, entry) {
+			t.Errorf("%s copied an entry it left alone", name)
+		}
+	}
+}
+
+func TestFieldFilterTransformChain(t *testing.T) {
+	f := FieldFilter{Transform: []Transformer{
+		RenameFields(map[string]string{"camera": "cam"}),
+		func(map[string]interface{}) map[string]interface{} { return nil }, // nil → unchanged
+		DropFields("user_id"),
+	}}
+	entry := map[string]interface{}{"level": "info", "camera": "gate", "user_id": "u-42"}
+	if !f.Apply(entry) {
+		t.Fatal("Apply reported no change")
+	}
+	want := map[string]interface{}{"level": "info", "cam": "gate"}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("entry = %v, want %v", entry, want)
+	}
+
+	if (FieldFilter{Transform: []Transformer{DropFields("absent")}}).Apply(entry) {
+		t.Error("Apply reported a change for a no-op chain")
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
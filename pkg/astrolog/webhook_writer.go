@@ -0,0 +1,184 @@
+// ================ Version : V1.1.4 ===========
+package astrolog
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultWebhookFlushInterval = 5 * time.Second
+	defaultWebhookQueueSize     = 1000
+	webhookTimeout              = 5 * time.Second
+)
+
+// =============================
+// Webhook Writer
+// =============================
+
+// WebhookWriterWithLevel POSTs entries at or above MinLevel to a URL as a
+// JSON array, batched every flush interval. Entries go through a bounded
+// queue: when the endpoint is too slow new entries are dropped (and counted)
+// so logging never blocks. Fatal and panic entries are sent synchronously
+// because the process is about to die.
+type WebhookWriterWithLevel struct {
+	URL      string
+	MinLevel zerolog.Level
+	Filter   FieldFilter
+
+	client   *http.Client
+	queue    chan []byte
+	interval time.Duration
+	dropped  atomic.Uint64
+	sendMu   sync.Mutex // serialises POSTs between the loop and fatal flushes
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newWebhookWriter(cfg CofigLogger) *WebhookWriterWithLevel {
+	minLevel, err := zerolog.ParseLevel(cfg.WebhookLevel)
+	if err != nil || cfg.WebhookLevel == "" {
+		minLevel = zerolog.ErrorLevel
+	}
+	interval := cfg.WebhookFlushInterval
+	if interval <= 0 {
+		interval = defaultWebhookFlushInterval
+	}
+	queueSize := cfg.WebhookQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultWebhookQueueSize
+	}
+
+	w := &WebhookWriterWithLevel{
+		URL:      cfg.WebhookURL,
+		MinLevel: minLevel,
+		Filter:   newFieldFilter(cfg, OutputWebhook),
+		client:   &http.Client{Timeout: webhookTimeout},
+		queue:    make(chan []byte, queueSize),
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write drops entries without a level; only leveled entries are forwarded.
+func (w *WebhookWriterWithLevel) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *WebhookWriterWithLevel) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < w.MinLevel || level == zerolog.NoLevel {
+		return len(p), nil
+	}
+
+	// zerolog reuses p after Write returns.
+	entry := bytes.TrimRight(w.Filter.ApplyJSON(p), "\n")
+	entry = append([]byte(nil), entry...)
+
+	if level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		w.send(append(w.drain(), entry))
+		return len(p), nil
+	}
+
+	select {
+	case w.queue <- entry:
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many entries were discarded because the queue was full.
+func (w *WebhookWriterWithLevel) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Flush sends what is queued now instead of waiting for the next tick.
+// It does nothing after Close.
+func (w *WebhookWriterWithLevel) Flush() {
+	select {
+	case <-w.done:
+	default:
+		w.send(w.drain())
+	}
+}
+
+// Close stops the background loop after sending what is still queued.
+func (w *WebhookWriterWithLevel) Close() error {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+	return nil
+}
+
+func (w *WebhookWriterWithLevel) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			w.send(w.drain())
+			return
+		case <-ticker.C:
+			w.send(w.drain())
+		}
+	}
+}
+
+// drain empties the queue without blocking.
+func (w *WebhookWriterWithLevel) drain() [][]byte {
+	var batch [][]byte
+	for {
+		select {
+		case entry := <-w.queue:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+}
+
+// send POSTs batch as one JSON array. Failures are counted as drops.
+func (w *WebhookWriterWithLevel) send(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	body := append([]byte("["), bytes.Join(batch, []byte(","))...)
+	body = append(body, ']')
+
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	resp, err := w.client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		w.dropped.Add(uint64(len(batch)))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		w.dropped.Add(uint64(len(batch)))
+	}
+}
+
+// WebhookDropped returns how many entries the current webhook writer has
+// dropped, or 0 when no WebhookURL is configured.
+func WebhookDropped() uint64 {
+	mu.Lock()
+	defer mu.Unlock()
+	if global == nil || global.webhook == nil {
+		return 0
+	}
+	return global.webhook.Dropped()
+}
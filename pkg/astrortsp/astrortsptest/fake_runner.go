@@ -0,0 +1,176 @@
+// ================ Version : V1.1.0 ===========
+package astrortsptest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrExit stands in for ffmpeg exiting with a non-zero status.
+var ErrExit = errors.New("exit status 1")
+
+// Response scripts what one fake ffmpeg run does.
+type Response struct {
+	Output []byte        // written to stdout when the run has one, else to the output file
+	Stderr string        // written to stderr
+	Delay  time.Duration // simulated run time; cut short by context cancellation
+	Err    error         // returned once the output has been written
+}
+
+// Success writes the golden JPEG.
+func Success() Response {
+	return Response{Output: GoldenJPEG}
+}
+
+// Timeout blocks until the context gives up, like an unresponsive camera.
+func Timeout() Response {
+	return Response{Delay: 24 * time.Hour}
+}
+
+// AuthFailure mimics a camera rejecting the credentials.
+func AuthFailure() Response {
+	return Response{
+		Stderr: "[rtsp @ 0x0] method DESCRIBE failed: 401 Unauthorized\n",
+		Err:    ErrExit,
+	}
+}
+
+// Unreachable mimics a camera that refuses the connection.
+func Unreachable() Response {
+	return Response{
+		Stderr: "[tcp @ 0x0] Connection to tcp://127.0.0.1:554 failed: Connection refused\n",
+		Err:    ErrExit,
+	}
+}
+
+// PartialOutput writes half of the golden JPEG and then fails.
+func PartialOutput() Response {
+	return Response{
+		Output: GoldenJPEG[:len(GoldenJPEG)/2],
+		Stderr: "Error while decoding stream #0:0\n",
+		Err:    ErrExit,
+	}
+}
+
+// Probe answers an ffprobe run with one video stream of the given shape.
+func Probe(width, height int, codec string, fps int) Response {
+	return Response{Output: []byte(fmt.Sprintf(
+		`{"streams":[{"codec_type":"video","codec_name":%q,"width":%d,"height":%d,"pix_fmt":"yuvj420p","avg_frame_rate":"%d/1"}]}`,
+		codec, width, height, fps,
+	))}
+}
+
+// Matcher selects the runs a Response applies to.
+type Matcher func(args []string) bool
+
+// Any matches every run.
+func Any() Matcher {
+	return func([]string) bool { return true }
+}
+
+// ArgContains matches runs where one argument contains substr,
+// e.g. ArgContains("crop=").
+func ArgContains(substr string) Matcher {
+	return func(args []string) bool {
+		for _, a := range args {
+			if strings.Contains(a, substr) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type rule struct {
+	match    Matcher
+	response Response
+}
+
+// FakeRunner is an astrortsp.Runner that never starts ffmpeg. Rules are
+// checked in the order they were added; Default answers everything else.
+type FakeRunner struct {
+	Default Response
+
+	mu    sync.Mutex
+	rules []rule
+	calls [][]string
+}
+
+// NewFakeRunner returns a runner that answers every run with Success().
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{Default: Success()}
+}
+
+// On answers runs matching m with r. It returns f for chaining.
+func (f *FakeRunner) On(m Matcher, r Response) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rule{match: m, response: r})
+	return f
+}
+
+// Calls returns the arguments of every run so far.
+func (f *FakeRunner) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([][]string, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+// Run implements astrortsp.Runner.
+func (f *FakeRunner) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	resp := f.record(args)
+
+	if resp.Delay > 0 {
+		timer := time.NewTimer(resp.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if stderr != nil && resp.Stderr != "" {
+		_, _ = io.WriteString(stderr, resp.Stderr)
+	}
+
+	if len(resp.Output) > 0 && len(args) > 0 {
+		out := args[len(args)-1]
+		switch {
+		case stdout != nil:
+			if _, err := stdout.Write(resp.Output); err != nil {
+				return err
+			}
+		case out == "pipe:1" || out == "-":
+		default:
+			if err := os.WriteFile(out, resp.Output, 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	return resp.Err
+}
+
+func (f *FakeRunner) record(args []string) Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, append([]string(nil), args...))
+	for _, r := range f.rules {
+		if r.match(args) {
+			return r.response
+		}
+	}
+	return f.Default
+}
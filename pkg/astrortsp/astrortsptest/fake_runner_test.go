@@ -0,0 +1,161 @@
+package astrortsptest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGoldenImagesDecode(t *testing.T) {
+	img, err := jpeg.Decode(bytes.NewReader(GoldenJPEG))
+	if err != nil {
+		t.Fatalf("GoldenJPEG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != GoldenWidth || b.Dy() != GoldenHeight {
+		t.Errorf("GoldenJPEG is %dx%d, want %dx%d", b.Dx(), b.Dy(), GoldenWidth, GoldenHeight)
+	}
+
+	img, err = png.Decode(bytes.NewReader(GoldenPNG))
+	if err != nil {
+		t.Fatalf("GoldenPNG: %v", err)
+	}
+	want := GoldenImage()
+	for y := 0; y < GoldenHeight; y++ {
+		for x := 0; x < GoldenWidth; x++ {
+			r1, g1, b1, a1 := img.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				t.Fatalf("GoldenPNG pixel (%d,%d) differs from GoldenImage", x, y)
+			}
+		}
+	}
+}
+
+func TestFakeRunnerWritesOutputFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "frame.jpg")
+	f := NewFakeRunner()
+
+	if err := f.Run(context.Background(), []string{"-i", "rtsp://cam", out}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, GoldenJPEG) {
+		t.Error("output file is not GoldenJPEG")
+	}
+}
+
+func TestFakeRunnerWritesStdout(t *testing.T) {
+	var stdout bytes.Buffer
+	f := NewFakeRunner()
+
+	if err := f.Run(context.Background(), []string{"-i", "rtsp://cam", "pipe:1"}, &stdout, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stdout.Bytes(), GoldenJPEG) {
+		t.Error("stdout is not GoldenJPEG")
+	}
+}
+
+func TestFakeRunnerScriptedResponses(t *testing.T) {
+	f := NewFakeRunner().
+		On(ArgContains("crop="), AuthFailure()).
+		On(ArgContains("crop="), Unreachable()) // shadowed: first match wins
+	dir := t.TempDir()
+
+	var stderr bytes.Buffer
+	err := f.Run(context.Background(), []string{"-vf", "crop=1:1:0:0", filepath.Join(dir, "a.jpg")}, nil, &stderr)
+	if !errors.Is(err, ErrExit) {
+		t.Fatalf("err = %v, want ErrExit", err)
+	}
+	if !bytes.Contains(stderr.Bytes(), []byte("401 Unauthorized")) {
+		t.Errorf("stderr = %q, want the auth failure", stderr.String())
+	}
+
+	// Unmatched runs get Default.
+	if err := f.Run(context.Background(), []string{filepath.Join(dir, "b.jpg")}, nil, nil); err != nil {
+		t.Fatalf("default run: %v", err)
+	}
+
+	calls := f.Calls()
+	if len(calls) != 2 || calls[0][1] != "crop=1:1:0:0" {
+		t.Errorf("Calls() = %v", calls)
+	}
+}
+
+func TestFakeRunnerPartialOutput(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "frame.jpg")
+	f := &FakeRunner{Default: PartialOutput()}
+
+	if err := f.Run(context.Background(), []string{out}, nil, nil); !errors.Is(err, ErrExit) {
+		t.Fatalf("err = %v, want ErrExit", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || len(got) >= len(GoldenJPEG) {
+		t.Errorf("wrote %d bytes, want a truncated JPEG", len(got))
+	}
+}
+
+func TestFakeRunnerDelay(t *testing.T) {
+	f := &FakeRunner{Default: Response{Delay: 50 * time.Millisecond}}
+
+	start := time.Now()
+	if err := f.Run(context.Background(), []string{"-"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("run took %v, want at least the 50ms delay", elapsed)
+	}
+}
+
+func TestFakeRunnerTimeoutHonorsContext(t *testing.T) {
+	f := &FakeRunner{Default: Timeout()}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := f.Run(ctx, []string{"-"}, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("run took %v after the context expired", elapsed)
+	}
+}
+
+func TestFakeRunnerCancelledBeforeRun(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "frame.jpg")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := NewFakeRunner().Run(ctx, []string{out}, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("a cancelled run wrote its output")
+	}
+}
+
+func TestProbeResponse(t *testing.T) {
+	s, runner := NewTestService(t)
+	runner.On(ArgContains("-show_streams"), Probe(1920, 1080, "h264", 25))
+
+	info, err := s.ProbeStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Width != 1920 || info.Height != 1080 {
+		t.Errorf("probed %dx%d, want 1920x1080", info.Width, info.Height)
+	}
+}
@@ -0,0 +1,55 @@
+// ================ Version : V1.1.0 ===========
+package astrortsptest
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+)
+
+// GoldenWidth and GoldenHeight are the dimensions of the golden images.
+const (
+	GoldenWidth  = 16
+	GoldenHeight = 8
+)
+
+// GoldenJPEG and GoldenPNG are tiny gradient images generated at init. They
+// are what FakeRunner writes for successful captures.
+var (
+	GoldenJPEG []byte
+	GoldenPNG  []byte
+)
+
+func init() {
+	img := GoldenImage()
+
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, img, &jpeg.Options{Quality: 90}); err != nil {
+		panic("astrortsptest: encoding golden JPEG: " + err.Error())
+	}
+	GoldenJPEG = jpg.Bytes()
+
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, img); err != nil {
+		panic("astrortsptest: encoding golden PNG: " + err.Error())
+	}
+	GoldenPNG = pngBuf.Bytes()
+}
+
+// GoldenImage returns the deterministic gradient the golden files encode.
+func GoldenImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, GoldenWidth, GoldenHeight))
+	for y := 0; y < GoldenHeight; y++ {
+		for x := 0; x < GoldenWidth; x++ {
+			img.Set(x, y, color.RGBA{
+				R: uint8(x * 255 / (GoldenWidth - 1)),
+				G: uint8(y * 255 / (GoldenHeight - 1)),
+				B: 128,
+				A: 255,
+			})
+		}
+	}
+	return img
+}
@@ -0,0 +1,30 @@
+package astrortsp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpBackoff(t *testing.T) {
+	base := 500 * time.Millisecond
+	for _, c := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 500 * time.Millisecond},
+		{1, time.Second},
+		{3, 4 * time.Second},
+		{6, 30 * time.Second},
+		{63, 30 * time.Second}, // a bare shift would wrap to 0 here
+		{1000, 30 * time.Second},
+	} {
+		if got := expBackoff(base, c.attempt); got != c.want {
+			t.Errorf("expBackoff(%v, %d) = %v, want %v", base, c.attempt, got, c.want)
+		}
+	}
+
+	// A first delay above the cap is kept, not shortened.
+	if got := expBackoff(time.Minute, 5); got != time.Minute {
+		t.Errorf("expBackoff(1m, 5) = %v, want 1m", got)
+	}
+}
@@ -0,0 +1,127 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+)
+
+// defaultCaptureConcurrency is used when MultiSnapshotService.Concurrency is 0.
+const defaultCaptureConcurrency = 8
+
+// Result is the outcome of one camera's capture in a multi-camera run.
+type Result struct {
+	Index    int // position of the camera in the input
+	CameraID string
+	Path     string // saved image, "" on error
+	Err      error
+	Duration time.Duration
+}
+
+// MultiSnapshotService captures one image from each of several cameras in
+// parallel, at most Concurrency at a time. Each capture keeps its own
+// camera's Timeout, so a slow camera only holds up its own worker.
+type MultiSnapshotService struct {
+	Services    []*SnapshotService
+	Concurrency int // 0 → 8
+}
+
+// NewMultiSnapshotService builds a SnapshotService for each config.
+func NewMultiSnapshotService(configs []RtspConfig, concurrency int) *MultiSnapshotService {
+	m := &MultiSnapshotService{Concurrency: concurrency}
+	for _, cfg := range configs {
+		m.Services = append(m.Services, NewSnapshotService(cfg))
+	}
+	return m
+}
+
+// CaptureAll captures every camera in configs once; see
+// MultiSnapshotService.CaptureAll.
+func CaptureAll(ctx context.Context, configs []RtspConfig, concurrency int) []Result {
+	return NewMultiSnapshotService(configs, concurrency).CaptureAll(ctx)
+}
+
+// CaptureAll captures every camera once and returns the results in input
+// order. Cancelling ctx kills the ffmpeg processes still running and fails
+// the captures not started yet.
+func (m *MultiSnapshotService) CaptureAll(ctx context.Context) []Result {
+	results := make([]Result, len(m.Services))
+	for r := range m.Stream(ctx) {
+		results[r.Index] = r
+	}
+	return results
+}
+
+// Stream is CaptureAll delivering each Result as soon as its capture ends.
+// The channel is closed once every camera has reported.
+func (m *MultiSnapshotService) Stream(ctx context.Context) <-chan Result {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	workers := m.Concurrency
+	if workers <= 0 {
+		workers = defaultCaptureConcurrency
+	}
+	workers = min(workers, len(m.Services))
+
+	names := fileNames(m.Services)
+	jobs := make(chan int)
+	out := make(chan Result, len(m.Services))
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out <- m.capture(ctx, i, names[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range m.Services {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// capture runs camera i, with its own correlation ID.
+func (m *MultiSnapshotService) capture(ctx context.Context, i int, name string) Result {
+	s := m.Services[i]
+	res := Result{Index: i, CameraID: s.RtspCamera.ID}
+	if err := ctx.Err(); err != nil {
+		res.Err = err
+		return res
+	}
+
+	start := time.Now()
+	res.Path, res.Err = s.captureImgAs(astrolog.WithCorrelationID(ctx, astrolog.NewID()), name)
+	res.Duration = time.Since(start)
+	return res
+}
+
+// fileNames returns the name each service uses in its file name: its camera
+// ID, suffixed with _2, _3… for repeated IDs writing to the same directory,
+// so duplicates never overwrite each other.
+func fileNames(services []*SnapshotService) []string {
+	names := make([]string, len(services))
+	seen := make(map[string]int)
+	for i, s := range services {
+		key := filepath.Join(filepath.Clean(s.RtspCamera.OutputDir), s.RtspCamera.ID)
+		seen[key]++
+		names[i] = s.RtspCamera.ID
+		if n := seen[key]; n > 1 {
+			names[i] = fmt.Sprintf("%s_%d", s.RtspCamera.ID, n)
+		}
+	}
+	return names
+}
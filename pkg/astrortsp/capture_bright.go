@@ -0,0 +1,68 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrTooDark   = errors.New("frame too dark")
+	ErrTooBright = errors.New("frame too bright")
+)
+
+// brightnessSamples is roughly how many pixels along the longest side are
+// sampled to measure the mean luminance.
+const brightnessSamples = 320
+
+// CaptureIfBright captures an image and saves it only when its mean
+// luminance (0–255) is within [lo, hi], e.g. to skip black frames from
+// poorly-lit cameras at night. Otherwise nothing is written and ErrTooDark or
+// ErrTooBright is returned, wrapped with the measured value.
+func (s *SnapshotService) CaptureIfBright(lo, hi float64) (string, error) {
+	data, err := s.CaptureImgBytes(s.baseContext())
+	if err != nil {
+		return "", err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decode capture: %w", err)
+	}
+
+	mean := meanLuminance(img)
+	switch {
+	case mean < lo:
+		return "", fmt.Errorf("%w: mean luminance %.1f < %.1f", ErrTooDark, mean, lo)
+	case mean > hi:
+		return "", fmt.Errorf("%w: mean luminance %.1f > %.1f", ErrTooBright, mean, hi)
+	}
+
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_%s.jpg", s.RtspCamera.ID, time.Now().Format("2006-01-02_15-04-05")))
+	if err := s.SaveImg(data, outFile); err != nil {
+		return "", err
+	}
+	return outFile, nil
+}
+
+// meanLuminance returns the average 0–255 luma of img, sampled on a grid.
+func meanLuminance(img image.Image) float64 {
+	b := img.Bounds()
+	step := max(1, max(b.Dx(), b.Dy())/brightnessSamples)
+
+	var sum, n int
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			sum += luminance(img, x, y)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(sum) / float64(n)
+}
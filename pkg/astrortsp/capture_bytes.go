@@ -0,0 +1,36 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// ErrEmptyCapture is returned when ffmpeg exits cleanly without producing an image.
+var ErrEmptyCapture = errors.New("ffmpeg produced no image data")
+
+// CaptureImgBytes captures a single image from the RTSP stream and returns
+// the JPEG bytes without writing anything to disk.
+func (s *SnapshotService) CaptureImgBytes(ctx context.Context) ([]byte, error) {
+	return s.CaptureImgBytesWithFilter(ctx, "")
+}
+
+// CaptureImgBytesWithFilter is CaptureImgBytes with a video filter (vf)
+// applied, e.g. "crop=w:h:x:y". Empty string = no filter. Transient
+// failures are retried following RtspConfig.Retry.
+func (s *SnapshotService) CaptureImgBytesWithFilter(ctx context.Context, vf string) ([]byte, error) {
+	var stdout bytes.Buffer
+	err := s.retryCapture(ctx, "", func(ctx context.Context) error {
+		stdout.Reset()
+		return s.runFFmpeg(ctx, s.frameArgs(vf, "-f", "image2", "-c:v", "mjpeg", "pipe:1"), &stdout)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if stdout.Len() == 0 {
+		return nil, ErrEmptyCapture
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ChromaSubsampling selects the JPEG chroma subsampling of a capture.
+//
+// Chroma420 is ffmpeg's default and gives the smallest files. Chroma444 keeps
+// full colour resolution, which stops coloured text from smearing (better OCR)
+// at the cost of files roughly 30–50% larger; Chroma422 sits in between.
+type ChromaSubsampling string
+
+const (
+	Chroma420 ChromaSubsampling = "4:2:0"
+	Chroma422 ChromaSubsampling = "4:2:2"
+	Chroma444 ChromaSubsampling = "4:4:4"
+)
+
+// ErrUnsupportedChroma is returned for a ChromaSubsampling ffmpeg can't encode to JPEG.
+var ErrUnsupportedChroma = errors.New("unsupported chroma subsampling")
+
+// chromaPixFmt maps each subsampling to the full-range pixel format used by
+// ffmpeg's mjpeg encoder.
+var chromaPixFmt = map[ChromaSubsampling]string{
+	Chroma420: "yuvj420p",
+	Chroma422: "yuvj422p",
+	Chroma444: "yuvj444p",
+}
+
+// CaptureImgWithChroma captures a single image with the given chroma subsampling and saves it to a file
+func (s *SnapshotService) CaptureImgWithChroma(chroma ChromaSubsampling) (string, error) {
+	pixFmt, ok := chromaPixFmt[chroma]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedChroma, chroma)
+	}
+
+	ts := time.Now().Format("2006-01-02_15-04-05")
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_%s.jpg", s.RtspCamera.ID, ts))
+
+	err := s.captureToFile(s.baseContext(), outFile, "", func() []string {
+		return s.frameArgs("", "-pix_fmt", pixFmt, outFile)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return outFile, nil
+}
@@ -0,0 +1,67 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// defaultClipGrace is added to the clip duration when RtspConfig.Timeout is 0.
+const defaultClipGrace = 10 * time.Second
+
+// ClipOptions tunes CaptureClipWithOptions.
+type ClipOptions struct {
+	// Bitrate re-encodes the clip with libx264 at this target, e.g. "2M",
+	// for cameras whose codec can't be copied into MP4.
+	// "" → stream copy (-c copy), no re-encoding.
+	Bitrate string
+}
+
+// CaptureClip records duration of the RTSP stream into an MP4 in OutputDir,
+// copying the stream without re-encoding. ffmpeg gets duration plus
+// RtspConfig.Timeout to finish; on failure or cancellation the partial file
+// is removed and no path is returned.
+func (s *SnapshotService) CaptureClip(ctx context.Context, duration time.Duration) (string, error) {
+	return s.CaptureClipWithOptions(ctx, duration, ClipOptions{})
+}
+
+// CaptureClipWithOptions is CaptureClip with encoding options.
+func (s *SnapshotService) CaptureClipWithOptions(ctx context.Context, duration time.Duration, opts ClipOptions) (string, error) {
+	if duration <= 0 {
+		return "", errors.New("clip duration must be positive")
+	}
+
+	ts := time.Now().Format("2006-01-02_15-04-05")
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_%s.mp4", s.RtspCamera.ID, ts))
+	if err := os.MkdirAll(s.RtspCamera.OutputDir, 0755); err != nil {
+		return "", err
+	}
+
+	args := []string{
+		"-rtsp_transport", "tcp",
+		"-i", s.RtspCamera.RTSPUrl,
+		"-t", strconv.FormatFloat(duration.Seconds(), 'f', -1, 64),
+	}
+	if opts.Bitrate == "" {
+		args = append(args, "-c", "copy")
+	} else {
+		args = append(args, "-c:v", "libx264", "-b:v", opts.Bitrate, "-c:a", "aac")
+	}
+	args = append(args, "-movflags", "+faststart", "-f", "mp4", outFile)
+
+	grace := s.RtspCamera.Timeout
+	if grace <= 0 {
+		grace = defaultClipGrace
+	}
+
+	if err := s.runWith(ctx, s.runner(), "ffmpeg clip", duration+grace, args, nil); err != nil {
+		_ = os.Remove(outFile)
+		return "", err
+	}
+	return outFile, nil
+}
@@ -0,0 +1,80 @@
+package astrortsp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp"
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp/astrortsptest"
+)
+
+// slowService returns a test service whose captures take delay and whose
+// parent context is parent.
+func slowService(t *testing.T, parent context.Context, delay time.Duration) *astrortsp.SnapshotService {
+	t.Helper()
+	s, runner := astrortsptest.NewTestService(t)
+	s.RtspCamera.Context = parent
+	runner.Default = astrortsptest.Response{Output: astrortsptest.GoldenJPEG, Delay: delay}
+	return s
+}
+
+func TestCancelCaptureSparesSiblingService(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	a := slowService(t, parent, 200*time.Millisecond)
+	b := slowService(t, parent, 200*time.Millisecond)
+
+	ctxA, cancelA := context.WithCancel(parent)
+	errA := make(chan error, 1)
+	go func() {
+		_, err := a.CaptureImgBytes(ctxA)
+		errA <- err
+	}()
+	errB := make(chan error, 1)
+	go func() {
+		_, err := b.CaptureImg()
+		errB <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancelA()
+
+	if err := <-errA; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled capture: err = %v, want context.Canceled", err)
+	}
+	if err := <-errB; err != nil {
+		t.Errorf("sibling capture failed: %v", err)
+	}
+	if parent.Err() != nil {
+		t.Error("cancelling one capture cancelled the shared parent")
+	}
+}
+
+func TestCancelParentStopsEveryService(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	a := slowService(t, parent, time.Hour)
+	b := slowService(t, parent, time.Hour)
+
+	errs := make(chan error, 2)
+	for _, s := range []*astrortsp.SnapshotService{a, b} {
+		go func() {
+			_, err := s.CaptureImg()
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancelParent()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("err = %v, want context.Canceled", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("capture kept running after the parent was cancelled")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+)
+
+var (
+	// ErrCaptureSkipped is passed to the handler when a tick fires while the
+	// previous capture is still running.
+	ErrCaptureSkipped = errors.New("capture skipped: previous capture still running")
+	// ErrTooManyFailures is returned when CaptureInterval hits maxFailures.
+	ErrTooManyFailures = errors.New("too many consecutive capture failures")
+)
+
+// IntervalStats counts the outcomes of a CaptureInterval run.
+type IntervalStats struct {
+	Succeeded int
+	Failed    int
+	Skipped   int
+}
+
+type captureResult struct {
+	path string
+	err  error
+}
+
+// CaptureInterval captures an image right away and then every `every`,
+// calling handler with each result, until ctx is cancelled.
+//
+// Captures never overlap: a tick that fires while ffmpeg is still running is
+// skipped and reported to the handler with ErrCaptureSkipped.
+// maxFailures > 0 aborts the loop with ErrTooManyFailures after that many
+// consecutive failed captures; 0 → never abort.
+//
+// Cancelling ctx stops the loop cleanly and returns a nil error. The handler
+// is always called from the goroutine running CaptureInterval.
+func (s *SnapshotService) CaptureInterval(ctx context.Context, every time.Duration, maxFailures int, handler func(path string, err error)) (IntervalStats, error) {
+	var stats IntervalStats
+	if every <= 0 {
+		return stats, errors.New("capture interval must be positive")
+	}
+
+	results := make(chan captureResult, 1)
+	busy := false
+	start := func() {
+		busy = true
+		go func() {
+			// Every tick is its own capture with its own correlation ID.
+			path, err := s.captureImg(astrolog.WithCorrelationID(ctx, astrolog.NewID()))
+			results <- captureResult{path: path, err: err}
+		}()
+	}
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	consecutive := 0
+	start()
+	for {
+		select {
+		case <-ctx.Done():
+			if busy {
+				<-results // in-flight ffmpeg is killed by ctx; don't leak it
+			}
+			return stats, nil
+
+		case <-ticker.C:
+			if busy {
+				stats.Skipped++
+				handler("", ErrCaptureSkipped)
+				continue
+			}
+			start()
+
+		case res := <-results:
+			busy = false
+			if res.err != nil {
+				stats.Failed++
+				consecutive++
+			} else {
+				stats.Succeeded++
+				consecutive = 0
+			}
+			handler(res.path, res.err)
+
+			if maxFailures > 0 && consecutive >= maxFailures {
+				return stats, ErrTooManyFailures
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+)
+
+const mjpegBoundary = "astroframe"
+
+// MJPEGHandler returns an HTTP handler that streams the camera as MJPEG
+// (multipart/x-mixed-replace). ffmpeg runs for as long as the client stays
+// connected and is stopped when the request context is cancelled.
+func (s *SnapshotService) MJPEGHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(astrolog.EnsureCorrelationID(r.Context()))
+
+		args := []string{
+			"-rtsp_transport", "tcp",
+			"-i", s.RtspCamera.RTSPUrl,
+			"-f", "mjpeg",
+			"-q:v", "5",
+			"pipe:1",
+		}
+
+		pr, pw := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			err := s.runner().Run(ctx, args, pw, nil)
+			_ = pw.CloseWithError(err)
+			done <- err
+		}()
+		// Deferred in this order so ffmpeg is stopped before we wait on it.
+		defer func() { <-done }()
+		defer pr.Close()
+		defer cancel()
+
+		flusher, _ := w.(http.Flusher)
+		headerSent := false
+
+		reader := bufio.NewReaderSize(pr, 64*1024)
+		for {
+			frame, err := readJPEGFrame(reader)
+			if err != nil {
+				if !headerSent {
+					http.Error(w, fmt.Sprintf("ffmpeg error: %v", err), http.StatusBadGateway)
+				}
+				return
+			}
+			if !headerSent {
+				w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+				w.Header().Set("Cache-Control", "no-cache")
+				headerSent = true
+			}
+
+			header := fmt.Sprintf("--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", mjpegBoundary, len(frame))
+			if _, err := w.Write([]byte(header)); err != nil {
+				return
+			}
+			if _, err := w.Write(frame); err != nil {
+				return
+			}
+			if _, err := w.Write([]byte("\r\n")); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// readJPEGFrame reads the next complete JPEG image (SOI … EOI) from r.
+func readJPEGFrame(r *bufio.Reader) ([]byte, error) {
+	// Skip until the start-of-image marker 0xFFD8.
+	var prev byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if prev == 0xFF && b == 0xD8 {
+			break
+		}
+		prev = b
+	}
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+
+	// Copy until the end-of-image marker 0xFFD9.
+	prev = 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(b)
+		if prev == 0xFF && b == 0xD9 {
+			return buf.Bytes(), nil
+		}
+		prev = b
+	}
+}
@@ -0,0 +1,197 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// CaptureProfile overrides the capture settings of RtspConfig while it is
+// active, e.g. a "night" profile probing longer and saving grayscale
+// infrared frames. Zero fields keep the RtspConfig value or ffmpeg's
+// default. Like Warmup, profiles apply to single-frame captures only.
+type CaptureProfile struct {
+	Timeout time.Duration // 0 → RtspConfig.Timeout
+	Warmup  time.Duration // 0 → RtspConfig.Warmup
+
+	// ProbeSize and AnalyzeDuration set how much of the stream ffmpeg reads
+	// before decoding (-probesize in bytes, -analyzeduration); low-light
+	// streams with long GOPs may need more. 0 → ffmpeg's default.
+	ProbeSize       int64
+	AnalyzeDuration time.Duration
+
+	// Quality is the JPEG -q:v, from 2 (best) to 31. 0 → 2.
+	Quality int
+
+	// Grayscale drops the colour of the frame, which only adds noise to
+	// infrared images.
+	Grayscale bool
+}
+
+// ProfileSelector names the profile of RtspConfig.Profiles to use for a
+// capture at t. A name missing from Profiles, or "", means the base
+// RtspConfig settings.
+type ProfileSelector interface {
+	Profile(t time.Time) string
+}
+
+// ProfileSelectorFunc adapts a function to ProfileSelector.
+type ProfileSelectorFunc func(t time.Time) string
+
+func (f ProfileSelectorFunc) Profile(t time.Time) string { return f(t) }
+
+// ActiveProfile returns the name of the profile captures use now, "" when
+// no profile applies.
+func (s *SnapshotService) ActiveProfile() string {
+	name, _ := s.profile()
+	return name
+}
+
+// profile returns the active profile and its name, "" and a zero profile
+// without one.
+func (s *SnapshotService) profile() (string, CaptureProfile) {
+	sel := s.RtspCamera.ProfileSelector
+	if sel == nil {
+		return "", CaptureProfile{}
+	}
+	name := sel.Profile(time.Now())
+	p, ok := s.RtspCamera.Profiles[name]
+	if !ok {
+		return "", CaptureProfile{}
+	}
+	return name, p
+}
+
+// inputArgs returns the input options of p, placed before -i.
+func (p CaptureProfile) inputArgs() []string {
+	var args []string
+	if p.ProbeSize > 0 {
+		args = append(args, "-probesize", strconv.FormatInt(p.ProbeSize, 10))
+	}
+	if p.AnalyzeDuration > 0 {
+		args = append(args, "-analyzeduration", strconv.FormatInt(p.AnalyzeDuration.Microseconds(), 10))
+	}
+	return args
+}
+
+// quality returns the -q:v value of p.
+func (p CaptureProfile) quality() string {
+	if p.Quality <= 0 {
+		return "2"
+	}
+	return strconv.Itoa(p.Quality)
+}
+
+// filter appends the grayscale filter of p to vf when set. hue keeps the
+// pixel format, so -pix_fmt options still apply.
+func (p CaptureProfile) filter(vf string) string {
+	if !p.Grayscale {
+		return vf
+	}
+	if vf == "" {
+		return "hue=s=0"
+	}
+	return vf + ",hue=s=0"
+}
+
+// =============================
+// Time windows
+// =============================
+
+// TimeWindow is a daily period of local time, From included, To excluded,
+// both offsets from midnight. From after To wraps past midnight, e.g.
+// 19h → 7h.
+type TimeWindow struct {
+	From, To time.Duration
+	Profile  string
+}
+
+// TimeWindows selects the profile of the first window containing the time
+// of day, Default outside all of them:
+//
+//	astrortsp.TimeWindows{
+//	    Windows: []astrortsp.TimeWindow{{From: 19 * time.Hour, To: 7 * time.Hour, Profile: "night"}},
+//	    Default: "day",
+//	}
+type TimeWindows struct {
+	Windows []TimeWindow
+	Default string
+}
+
+func (w TimeWindows) Profile(t time.Time) string {
+	y, m, d := t.Date()
+	since := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	for _, win := range w.Windows {
+		in := since >= win.From && since < win.To
+		if win.From > win.To {
+			in = since >= win.From || since < win.To
+		}
+		if in {
+			return win.Profile
+		}
+	}
+	return w.Default
+}
+
+// =============================
+// Sunrise / sunset
+// =============================
+
+// SunSelector selects Day between local sunrise and sunset at the camera's
+// position, Night otherwise. Latitude is north positive, Longitude east
+// positive, in degrees. Times are accurate to a few minutes; Margin moves
+// both switches later, e.g. to keep the night profile until the light is
+// usable.
+type SunSelector struct {
+	Latitude, Longitude float64
+	Day, Night          string
+	Margin              time.Duration
+}
+
+func (s SunSelector) Profile(t time.Time) string {
+	rise, set := sunTimes(t, s.Latitude, s.Longitude)
+	if !t.Before(rise.Add(s.Margin)) && t.Before(set.Add(s.Margin)) {
+		return s.Day
+	}
+	return s.Night
+}
+
+// sunTimes returns sunrise and sunset on the calendar day of t at the given
+// position, in t's location, using the sunrise equation. During polar day
+// they are the start of this day and of the next one; during polar night
+// both are noon, an empty interval.
+func sunTimes(t time.Time, lat, lon float64) (rise, set time.Time) {
+	const (
+		rad   = math.Pi / 180
+		j2000 = 2451545.0
+		unix0 = 2440587.5 // Julian date of the Unix epoch
+	)
+	y, m, d := t.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	n := math.Round(float64(noon.Unix())/86400 + unix0 - j2000 + 0.0008)
+
+	meanNoon := n - lon/360
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	center := 1.9148*math.Sin(anomaly*rad) + 0.0200*math.Sin(2*anomaly*rad) + 0.0003*math.Sin(3*anomaly*rad)
+	ecliptic := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := j2000 + meanNoon + 0.0053*math.Sin(anomaly*rad) - 0.0069*math.Sin(2*ecliptic*rad)
+	sinDecl := math.Sin(ecliptic*rad) * math.Sin(23.4397*rad)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHour := (math.Sin(-0.833*rad) - math.Sin(lat*rad)*sinDecl) / (math.Cos(lat*rad) * cosDecl)
+
+	fromJulian := func(j float64) time.Time {
+		sec := (j - unix0) * 86400
+		return time.Unix(int64(sec), int64((sec-math.Floor(sec))*1e9)).In(t.Location())
+	}
+	switch {
+	case cosHour < -1:
+		start := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 0, 1)
+	case cosHour > 1:
+		mid := time.Date(y, m, d, 12, 0, 0, 0, t.Location())
+		return mid, mid
+	}
+	hour := math.Acos(cosHour) / rad
+	return fromJulian(transit - hour/360), fromJulian(transit + hour/360)
+}
@@ -0,0 +1,137 @@
+package astrortsp_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp"
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp/astrortsptest"
+)
+
+// fakeClock is the time a test selector sees, moved by the test.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func TestCaptureProfileFlipsAtBoundary(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+	s.RtspCamera.Sidecar = true
+	s.RtspCamera.Profiles = map[string]astrortsp.CaptureProfile{
+		"day":   {Quality: 3},
+		"night": {ProbeSize: 5_000_000, Quality: 5, Grayscale: true},
+	}
+	windows := astrortsp.TimeWindows{
+		Windows: []astrortsp.TimeWindow{{From: 19 * time.Hour, To: 7 * time.Hour, Profile: "night"}},
+		Default: "day",
+	}
+	clock := &fakeClock{}
+	s.RtspCamera.ProfileSelector = astrortsp.ProfileSelectorFunc(func(time.Time) string {
+		return windows.Profile(clock.Now())
+	})
+
+	evening := time.Date(2024, 3, 1, 19, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		at      time.Time
+		profile string
+		want    []string
+		notWant []string
+	}{
+		{evening.Add(-time.Nanosecond), "day", []string{"-q:v 3"}, []string{"-probesize", "hue=s=0"}},
+		{evening, "night", []string{"-probesize 5000000", "-vf hue=s=0", "-q:v 5"}, nil},
+		{evening.Add(12*time.Hour - time.Nanosecond), "night", []string{"-q:v 5"}, nil},
+		{evening.Add(12 * time.Hour), "day", []string{"-q:v 3"}, []string{"-probesize", "hue=s=0"}},
+	} {
+		clock.Set(c.at)
+		if got := s.ActiveProfile(); got != c.profile {
+			t.Errorf("%s: ActiveProfile = %q, want %q", c.at, got, c.profile)
+		}
+		path, err := s.CaptureImg()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		calls := runner.Calls()
+		args := strings.Join(calls[len(calls)-1], " ")
+		for _, w := range c.want {
+			if !strings.Contains(args, w) {
+				t.Errorf("%s: args %q lack %q", c.at, args, w)
+			}
+		}
+		for _, w := range c.notWant {
+			if strings.Contains(args, w) {
+				t.Errorf("%s: args %q carry %q", c.at, args, w)
+			}
+		}
+
+		meta, err := astrortsp.ReadSnapshotMeta(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.Profile != c.profile {
+			t.Errorf("%s: sidecar profile = %q, want %q", c.at, meta.Profile, c.profile)
+		}
+	}
+}
+
+func TestSidecarOmitsProfileWithoutSelector(t *testing.T) {
+	s, _ := astrortsptest.NewTestService(t)
+	s.RtspCamera.Sidecar = true
+
+	path, err := s.CaptureImg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := astrortsp.ReadSnapshotMeta(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Profile != "" {
+		t.Errorf("sidecar profile = %q, want none", meta.Profile)
+	}
+}
+
+func TestSunSelectorMarginMovesBoundary(t *testing.T) {
+	paris := time.FixedZone("CET", 3600)
+	sel := astrortsp.SunSelector{Latitude: 48.85, Longitude: 2.35, Day: "day", Night: "night"}
+
+	// firstDay returns the first minute of the morning sel selects "day".
+	firstDay := func(sel astrortsp.SunSelector) time.Time {
+		t.Helper()
+		for m := time.Date(2024, 3, 1, 0, 0, 0, 0, paris); m.Day() == 1; m = m.Add(time.Minute) {
+			if sel.Profile(m) == "day" {
+				return m
+			}
+		}
+		t.Fatal("no day on 2024-03-01")
+		return time.Time{}
+	}
+
+	// Sunrise in Paris on 1 March is about 07:40 CET.
+	rise := firstDay(sel)
+	if rise.Hour() != 7 || rise.Minute() < 30 || rise.Minute() > 50 {
+		t.Errorf("day starts at %s, want about 07:40", rise.Format("15:04"))
+	}
+	if got := sel.Profile(rise.Add(-time.Minute)); got != "night" {
+		t.Errorf("a minute before: %q, want night", got)
+	}
+
+	sel.Margin = 30 * time.Minute
+	if got := firstDay(sel); got.Sub(rise) != sel.Margin {
+		t.Errorf("with a 30m margin day starts at %s, want %s", got.Format("15:04"), rise.Add(sel.Margin).Format("15:04"))
+	}
+}
@@ -0,0 +1,93 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Corner selects where CaptureImgWithQR places the QR code.
+type Corner string
+
+const (
+	CornerTopLeft     Corner = "top-left"
+	CornerTopRight    Corner = "top-right"
+	CornerBottomLeft  Corner = "bottom-left"
+	CornerBottomRight Corner = "bottom-right"
+)
+
+// qrMargin is the gap (px) between the QR code and the frame edges.
+const qrMargin = 10
+
+// qrScale is the size (px) of one QR module in the overlay.
+const qrScale = 3
+
+// overlayPosition maps a corner to ffmpeg overlay x:y expressions.
+var overlayPosition = map[Corner]string{
+	CornerTopLeft:     fmt.Sprintf("%d:%d", qrMargin, qrMargin),
+	CornerTopRight:    fmt.Sprintf("W-w-%d:%d", qrMargin, qrMargin),
+	CornerBottomLeft:  fmt.Sprintf("%d:H-h-%d", qrMargin, qrMargin),
+	CornerBottomRight: fmt.Sprintf("W-w-%d:H-h-%d", qrMargin, qrMargin),
+}
+
+// CaptureImgWithQR captures a single image with a QR code in the given corner
+// and saves it to a file. The QR code encodes "cam=<ID>;ts=<RFC 3339 time>"
+// so the frame's source and time can be checked even if the file is renamed.
+func (s *SnapshotService) CaptureImgWithQR(corner Corner) (string, error) {
+	position, ok := overlayPosition[corner]
+	if !ok {
+		return "", fmt.Errorf("unsupported QR corner %q", corner)
+	}
+
+	now := time.Now()
+	payload := fmt.Sprintf("cam=%s;ts=%s", s.RtspCamera.ID, now.Format(time.RFC3339))
+	qr, err := qrcode.New(payload, qrcode.Medium)
+	if err != nil {
+		return "", err
+	}
+	qrPNG, err := qr.PNG(-qrScale)
+	if err != nil {
+		return "", err
+	}
+
+	qrFile, err := os.CreateTemp("", "astrortsp_qr_*.png")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(qrFile.Name())
+	if _, err := qrFile.Write(qrPNG); err != nil {
+		qrFile.Close()
+		return "", err
+	}
+	if err := qrFile.Close(); err != nil {
+		return "", err
+	}
+
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_qr_%s.jpg", s.RtspCamera.ID, now.Format("2006-01-02_15-04-05")))
+
+	filter := "overlay=" + position
+	err = s.captureToFile(s.baseContext(), outFile, filter, func() []string {
+		_, p := s.profile()
+		args := append([]string{"-rtsp_transport", "tcp"}, p.inputArgs()...)
+		args = append(args,
+			"-i", s.RtspCamera.RTSPUrl,
+			"-i", qrFile.Name(),
+			"-filter_complex", p.filter("[0:v][1:v]"+filter),
+		)
+		args = append(args, s.warmupArgs()...)
+		return append(args,
+			"-frames:v", "1",
+			"-q:v", p.quality(),
+			outFile,
+		)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return outFile, nil
+}
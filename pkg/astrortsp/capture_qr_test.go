@@ -0,0 +1,78 @@
+package astrortsp_test
+
+import (
+	"context"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp"
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp/astrortsptest"
+)
+
+// qrCheckRunner decodes the QR overlay input while ffmpeg would run, as the
+// temporary file is removed once the capture returns.
+type qrCheckRunner struct {
+	*astrortsptest.FakeRunner
+	width, height int
+	err           error
+}
+
+func (r *qrCheckRunner) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	for i, a := range args {
+		if a == "-i" && strings.HasSuffix(args[i+1], ".png") {
+			f, err := os.Open(args[i+1])
+			if err != nil {
+				r.err = err
+				break
+			}
+			img, err := png.Decode(f)
+			f.Close()
+			if err != nil {
+				r.err = err
+				break
+			}
+			r.width, r.height = img.Bounds().Dx(), img.Bounds().Dy()
+		}
+	}
+	return r.FakeRunner.Run(ctx, args, stdout, stderr)
+}
+
+func TestCaptureImgWithQR(t *testing.T) {
+	s, fake := astrortsptest.NewTestService(t)
+	runner := &qrCheckRunner{FakeRunner: fake}
+	s.Runner = runner
+
+	path, err := s.CaptureImgWithQR(astrortsp.CornerBottomRight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if runner.err != nil {
+		t.Fatalf("QR overlay input: %v", runner.err)
+	}
+	// A version 2+ code with its quiet zone, 3 px per module.
+	if runner.width < 3*(25+8) || runner.width != runner.height {
+		t.Errorf("QR overlay is %dx%d", runner.width, runner.height)
+	}
+
+	args := strings.Join(fake.Calls()[0], " ")
+	if !strings.Contains(args, "overlay=W-w-10:H-h-10") {
+		t.Errorf("args %q lack the bottom-right overlay", args)
+	}
+}
+
+func TestCaptureImgWithQRUnknownCorner(t *testing.T) {
+	s, fake := astrortsptest.NewTestService(t)
+
+	if _, err := s.CaptureImgWithQR("middle"); err == nil {
+		t.Fatal("unknown corner accepted")
+	}
+	if len(fake.Calls()) != 0 {
+		t.Error("ffmpeg ran for an unknown corner")
+	}
+}
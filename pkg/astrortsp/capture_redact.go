@@ -0,0 +1,80 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CaptureRedacted captures an image with every region blurred before it is
+// written, e.g. faces or plates that must not be stored. Regions come
+// straight from detection output:
+//
+//	box := astrortsp.ExtractBoundingBox(p1, p2, p3, p4)
+//	path, err := svc.CaptureRedacted([]astrortsp.Rectangle{box})
+//
+// Regions are clamped to the frame like CaptureCropUnion does; those left
+// empty are skipped. With no region the image is saved as is. The blur is
+// a box blur strong enough that text and faces can't be recovered.
+func (s *SnapshotService) CaptureRedacted(regions []Rectangle) (string, error) {
+	filter := redactFilter(s.clampRegions(regions))
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_redacted_%s.jpg", s.RtspCamera.ID, time.Now().Format("2006-01-02_15-04-05")))
+	if err := s.captureAndSaveWithFilter(s.baseContext(), filter, outFile); err != nil {
+		return "", err
+	}
+	return outFile, nil
+}
+
+// clampRegions clips regions to the frame, at 0 always and to the stream
+// resolution once ProbeStream has run, dropping the empty ones.
+func (s *SnapshotService) clampRegions(regions []Rectangle) []Rectangle {
+	info := s.cachedStreamInfo()
+	var out []Rectangle
+	for _, r := range regions {
+		x0, y0 := max(r.X, 0), max(r.Y, 0)
+		x1, y1 := r.X+r.Width, r.Y+r.Height
+		if info != nil && info.Width > 0 && info.Height > 0 {
+			x1, y1 = min(x1, info.Width), min(y1, info.Height)
+		}
+		if x1 > x0 && y1 > y0 {
+			out = append(out, Rectangle{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0})
+		}
+	}
+	return out
+}
+
+// blurRadius is the boxblur radius expression of a plane of size a×b: a
+// quarter of the smaller side, within the half boxblur allows, and never 0
+// (no blur at all) unless that side is a single pixel.
+func blurRadius(a, b string) string {
+	side := "min(" + a + "," + b + ")"
+	return "'max(" + side + "/4,gt(" + side + ",1))'"
+}
+
+// redactFilter builds a -vf filtergraph that, for each region, splits the
+// frame, blurs a crop of the region and overlays it back in place:
+//
+//	split[m0][c0];[c0]crop=w=…:h=…:x=…:y=…,boxblur=…[b0];[m0][b0]overlay=x:y[v1];[v1]split…
+//
+// The crop is clamped to the frame by ffmpeg itself, so a region past the
+// edge of a stream never probed still blurs what is inside it. The blur
+// radius follows the clamped size, see blurRadius.
+// Returns "" without regions.
+func redactFilter(regions []Rectangle) string {
+	var b strings.Builder
+	for i, r := range regions {
+		if i > 0 {
+			fmt.Fprintf(&b, ";[v%d]", i)
+		}
+		fmt.Fprintf(&b, "split[m%d][c%d];[c%d]crop=w='clip(iw-%d,1,%d)':h='clip(ih-%d,1,%d)':x='min(%d,iw-1)':y='min(%d,ih-1)'",
+			i, i, i, r.X, r.Width, r.Y, r.Height, r.X, r.Y)
+		fmt.Fprintf(&b, ",boxblur=luma_radius=%s:chroma_radius=%s:luma_power=3[b%d];[m%d][b%d]overlay=%d:%d",
+			blurRadius("w", "h"), blurRadius("cw", "ch"), i, i, i, r.X, r.Y)
+		if i < len(regions)-1 {
+			fmt.Fprintf(&b, "[v%d]", i+1)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package astrortsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactFilterClampsInFilter(t *testing.T) {
+	got := redactFilter([]Rectangle{{X: 600, Y: 440, Width: 80, Height: 60}})
+	for _, want := range []string{
+		"crop=w='clip(iw-600,1,80)':h='clip(ih-440,1,60)':x='min(600,iw-1)':y='min(440,ih-1)'",
+		"overlay=600:440",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("filter %q lacks %q", got, want)
+		}
+	}
+}
+
+func TestRedactFilterMinimumRadius(t *testing.T) {
+	// A 3×3 region used to get radius 3/4 = 0, i.e. no blur at all.
+	got := redactFilter([]Rectangle{{X: 10, Y: 10, Width: 3, Height: 3}})
+	for _, want := range []string{
+		"luma_radius='max(min(w,h)/4,gt(min(w,h),1))'",
+		"chroma_radius='max(min(cw,ch)/4,gt(min(cw,ch),1))'",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("filter %q lacks %q", got, want)
+		}
+	}
+	if strings.Contains(got, "luma_radius=0") {
+		t.Errorf("filter %q has a zero radius", got)
+	}
+}
+
+func TestRedactFilterChainsRegions(t *testing.T) {
+	got := redactFilter([]Rectangle{{Width: 8, Height: 8}, {X: 16, Width: 8, Height: 8}})
+	if !strings.HasPrefix(got, "split[m0][c0];") || !strings.Contains(got, "[v1];[v1]split[m1][c1]") {
+		t.Errorf("filter %q does not chain the regions", got)
+	}
+	if strings.HasSuffix(got, "]") {
+		t.Errorf("filter %q labels its last output", got)
+	}
+	if redactFilter(nil) != "" {
+		t.Error("no regions: want an empty filter")
+	}
+}
+
+func TestClampRegionsToProbedFrame(t *testing.T) {
+	s := &SnapshotService{streamInfo: &StreamInfo{Width: 640, Height: 480}}
+	got := s.clampRegions([]Rectangle{
+		{X: -10, Y: -10, Width: 20, Height: 20},
+		{X: 600, Y: 440, Width: 80, Height: 60},
+		{X: 700, Y: 10, Width: 10, Height: 10}, // outside: dropped
+	})
+	want := []Rectangle{
+		{X: 0, Y: 0, Width: 10, Height: 10},
+		{X: 600, Y: 440, Width: 40, Height: 40},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("clampRegions = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("region %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
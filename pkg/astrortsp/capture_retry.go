@@ -0,0 +1,124 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+)
+
+const defaultRetryBackoff = 500 * time.Millisecond
+
+// RetryPolicy retries single-frame captures that failed for a transient
+// reason, e.g. a camera dropping the connection, with exponential backoff.
+// Failures ffmpeg would repeat, such as a bad filter or rejected
+// credentials, are returned at once.
+type RetryPolicy struct {
+	MaxAttempts int           // runs per capture, the first included; 0 → 1, no retry
+	Backoff     time.Duration // delay before the first retry, doubled each time up to 30s; 0 → 500ms
+	Jitter      bool          // randomize each delay between half and all of it
+
+	// MaxElapsed caps the time of a capture across all its attempts: no
+	// retry starts once it would end past it. The capture context's deadline
+	// applies as well. 0 → only the context deadline.
+	MaxElapsed time.Duration
+}
+
+// Transient reports whether the run may succeed if started again: it timed
+// out, or the connection to the camera failed or broke.
+func (e *FFmpegError) Transient() bool {
+	if e.Timeout || e.Unreachable() {
+		return true
+	}
+	s := strings.ToLower(e.Stderr)
+	return strings.Contains(s, "connection reset") ||
+		strings.Contains(s, "broken pipe") ||
+		strings.Contains(s, "timed out") ||
+		strings.Contains(s, "resource temporarily unavailable")
+}
+
+// finalError stops retryCapture from retrying the error it wraps, e.g. once
+// part of a streamed image has already been sent.
+type finalError struct{ error }
+
+func (e finalError) Unwrap() error { return e.error }
+
+// retryCapture calls capture until it succeeds, fails for a non-transient
+// reason or the policy is exhausted, removing filename, if any, between
+// attempts so a partial image never survives. After more than one attempt
+// the error says how many were made and still wraps the last *FFmpegError.
+func (s *SnapshotService) retryCapture(ctx context.Context, filename string, capture func(context.Context) error) error {
+	policy := s.RtspCamera.Retry
+	if policy.MaxAttempts <= 1 {
+		return capture(ctx)
+	}
+	if policy.MaxElapsed > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.MaxElapsed)
+		defer cancel()
+	}
+	ctx = astrolog.EnsureCorrelationID(ctx)
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	attempt := 1
+	for ; ; attempt++ {
+		err := capture(ctx)
+		if err == nil {
+			return nil
+		}
+		var final finalError
+		if errors.As(err, &final) {
+			return attemptsError(attempt, final.error)
+		}
+		var ffErr *FFmpegError
+		if !errors.As(err, &ffErr) || !ffErr.Transient() || attempt == policy.MaxAttempts {
+			return attemptsError(attempt, err)
+		}
+		if filename != "" {
+			if rmErr := os.Remove(filename); rmErr != nil && !os.IsNotExist(rmErr) {
+				return attemptsError(attempt, errors.Join(err, rmErr))
+			}
+		}
+
+		// expBackoff caps the delay, so it neither overflows nor reaches 0
+		// and rand.N always gets a positive bound.
+		delay := expBackoff(backoff, attempt-1)
+		if policy.Jitter {
+			delay = delay/2 + rand.N(delay/2+1)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return attemptsError(attempt, err)
+		}
+		logger := astrolog.FromContext(ctx)
+		logger.Info().
+			Str("camera_id", s.RtspCamera.ID).
+			Int("attempt", attempt).
+			Dur("backoff", delay).
+			Msg("ffmpeg capture retrying")
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return attemptsError(attempt, err)
+		case <-t.C:
+		}
+	}
+}
+
+// attemptsError adds the attempt count to err when there was more than one.
+func attemptsError(attempts int, err error) error {
+	if attempts == 1 {
+		return err
+	}
+	return fmt.Errorf("capture failed after %d attempts: %w", attempts, err)
+}
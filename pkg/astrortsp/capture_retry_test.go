@@ -0,0 +1,131 @@
+package astrortsp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp"
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp/astrortsptest"
+)
+
+// bufferUploader keeps what Put reads.
+type bufferUploader struct {
+	data bytes.Buffer
+}
+
+func (u *bufferUploader) Put(_ context.Context, _ string, r io.Reader, _ string) error {
+	_, err := io.Copy(&u.data, r)
+	return err
+}
+
+// newFlakyService returns a test service whose first n runs fail as
+// unreachable, retried up to three times.
+func newFlakyService(t *testing.T, n int64) (*astrortsp.SnapshotService, *flakyRunner) {
+	t.Helper()
+	s, runner := astrortsptest.NewTestService(t)
+	flaky := &flakyRunner{n: n, next: runner}
+	s.Runner = flaky
+	s.RtspCamera.Sidecar = true
+	s.RtspCamera.Retry = astrortsp.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Jitter: true}
+	return s, flaky
+}
+
+func TestRetryFileCaptures(t *testing.T) {
+	for name, capture := range map[string]func(*astrortsp.SnapshotService) (string, error){
+		"chroma": func(s *astrortsp.SnapshotService) (string, error) {
+			return s.CaptureImgWithChroma(astrortsp.Chroma444)
+		},
+		"qr": func(s *astrortsp.SnapshotService) (string, error) {
+			return s.CaptureImgWithQR(astrortsp.CornerTopLeft)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, flaky := newFlakyService(t, 2)
+
+			path, err := capture(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runs := flaky.runs.Load(); runs != 3 {
+				t.Errorf("ffmpeg ran %d times, want 3", runs)
+			}
+			meta, err := astrortsp.ReadSnapshotMeta(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Attempts != 3 {
+				t.Errorf("sidecar attempts = %d, want 3", meta.Attempts)
+			}
+		})
+	}
+}
+
+func TestRetryCaptureImgBytes(t *testing.T) {
+	s, flaky := newFlakyService(t, 2)
+
+	data, err := s.CaptureImgBytes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, astrortsptest.GoldenJPEG) {
+		t.Error("bytes are not the golden JPEG")
+	}
+	if runs := flaky.runs.Load(); runs != 3 {
+		t.Errorf("ffmpeg ran %d times, want 3", runs)
+	}
+}
+
+func TestRetryCaptureImgBytesGivesUp(t *testing.T) {
+	s, flaky := newFlakyService(t, 5)
+
+	_, err := s.CaptureImgBytes(context.Background())
+	var ffErr *astrortsp.FFmpegError
+	if !errors.As(err, &ffErr) || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("err = %v, want an *FFmpegError after 3 attempts", err)
+	}
+	if runs := flaky.runs.Load(); runs != 3 {
+		t.Errorf("ffmpeg ran %d times, want 3", runs)
+	}
+}
+
+func TestRetryCaptureAndUpload(t *testing.T) {
+	s, flaky := newFlakyService(t, 2)
+	up := &bufferUploader{}
+	s.Uploader = up
+
+	if err := s.CaptureAndUpload("cam/1.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(up.data.Bytes(), astrortsptest.GoldenJPEG) {
+		t.Errorf("uploaded %d bytes, want the golden JPEG alone", up.data.Len())
+	}
+	if runs := flaky.runs.Load(); runs != 3 {
+		t.Errorf("ffmpeg ran %d times, want 3", runs)
+	}
+}
+
+func TestNoUploadRetryAfterOutput(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+	runner.Default = astrortsptest.Response{
+		Output: astrortsptest.GoldenJPEG[:10],
+		Stderr: "Connection reset by peer\n",
+		Err:    astrortsptest.ErrExit,
+	}
+	s.RtspCamera.Retry = astrortsp.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	s.Uploader = &bufferUploader{}
+
+	err := s.CaptureAndUpload("cam/1.jpg")
+	var ffErr *astrortsp.FFmpegError
+	if !errors.As(err, &ffErr) || !ffErr.Transient() {
+		t.Fatalf("err = %v, want a transient *FFmpegError", err)
+	}
+	// A retry would append a second image to the half sent.
+	if n := len(runner.Calls()); n != 1 {
+		t.Errorf("ffmpeg ran %d times, want 1", n)
+	}
+}
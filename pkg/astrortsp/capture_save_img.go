@@ -2,30 +2,92 @@
 package astrortsp
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
 )
 
 type SnapshotService struct {
 	RtspCamera RtspConfig
+	Runner     Runner   // nil → ExecRunner
+	Prober     Runner   // runs ffprobe; nil → ExecRunner{Binary: "ffprobe"}
+	Uploader   Uploader // CaptureAndUpload destination
+
+	// Notifier receives capture lifecycle events; nil → none are sent.
+	Notifier Notifier
+	// NotifyAfterFailures is how many consecutive failed ffmpeg runs raise
+	// EventCaptureFailing. 0 → 3.
+	NotifyAfterFailures int
+	failures            atomic.Int64
+
+	usage usageMeter // per-day data and CPU, see UsageStats
+
+	probeMu    sync.Mutex
+	streamInfo *StreamInfo // cached by ProbeStream
 }
 
-func NewSnapshotService(cfg RtspConfig) *SnapshotService {
+// Option configures a SnapshotService built by NewSnapshotService.
+type Option func(*SnapshotService)
+
+// WithRunner runs every capture through r instead of ExecRunner, e.g. an
+// astrortsptest.FakeRunner, so no ffmpeg or live camera is needed.
+func WithRunner(r Runner) Option {
+	return func(s *SnapshotService) { s.Runner = r }
+}
+
+// WithProber runs ProbeStream through r instead of ffprobe.
+func WithProber(r Runner) Option {
+	return func(s *SnapshotService) { s.Prober = r }
+}
+
+// NewSnapshotService returns a service for cfg and creates its OutputDir.
+// Without options, captures run ffmpeg and ProbeStream ffprobe from PATH.
+func NewSnapshotService(cfg RtspConfig, opts ...Option) *SnapshotService {
 	_ = os.MkdirAll(cfg.OutputDir, 0755)
-	return &SnapshotService{RtspCamera: cfg}
+	s := &SnapshotService{RtspCamera: cfg}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// baseContext returns the parent context of captures started without one.
+func (s *SnapshotService) baseContext() context.Context {
+	if s.RtspCamera.Context != nil {
+		return s.RtspCamera.Context
+	}
+	return context.Background()
 }
 
 // CaptureImg captures a single image from the RTSP stream and saves it to a file
 func (s *SnapshotService) CaptureImg() (string, error) {
+	return s.captureImg(s.baseContext())
+}
+
+// captureImg is CaptureImg under the given context.
+func (s *SnapshotService) captureImg(ctx context.Context) (string, error) {
+	return s.captureImgAs(ctx, s.RtspCamera.ID)
+}
+
+// captureImgAs is captureImg with name in place of the camera ID in the
+// file name.
+func (s *SnapshotService) captureImgAs(ctx context.Context, name string) (string, error) {
 	ts := time.Now().Format("2006-01-02_15-04-05")
-	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_%s.jpg", s.RtspCamera.ID, ts))
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_%s.jpg", name, ts))
 
 	// Only pass -vf if filter is needed; empty string = no filter
-	err := s.captureAndSaveWithFilter(s.RtspCamera.Context, "", outFile)
+	err := s.captureAndSaveWithFilter(ctx, "", outFile)
 	if err != nil {
 		return "", err
 	}
@@ -33,37 +95,136 @@ func (s *SnapshotService) CaptureImg() (string, error) {
 	return outFile, nil
 }
 
-// captureAndSaveWithFilter captures an image applying the given video filter (vf) and saves it to filename
+// captureAndSaveWithFilter captures an image applying the given video filter (vf) and saves it to filename.
+// A failed ffmpeg run is returned as *FFmpegError. See captureToFile.
 func (s *SnapshotService) captureAndSaveWithFilter(ctx context.Context, vf string, filename string) error {
-	ctx, cancel := context.WithTimeout(s.RtspCamera.Context, s.RtspCamera.Timeout)
-	defer cancel()
+	return s.captureToFile(ctx, filename, vf, func() []string {
+		return s.frameArgs(vf, filename)
+	})
+}
+
+// captureToFile runs ffmpeg with the arguments built by args, writing to
+// filename, and then the sidecar recording filter. args is called again
+// for every attempt so each one follows the active profile. Transient
+// failures are retried following RtspConfig.Retry. All attempts and the
+// sidecar share the correlation ID of ctx, generated when it has none.
+func (s *SnapshotService) captureToFile(ctx context.Context, filename, filter string, args func() []string) error {
+	ctx = astrolog.EnsureCorrelationID(ctx)
+	attempts, profile := 0, ""
+	err := s.retryCapture(ctx, filename, func(ctx context.Context) error {
+		attempts++
+		profile = s.ActiveProfile()
+		return s.runFFmpeg(ctx, args(), nil)
+	})
+	if err != nil {
+		return err
+	}
+	return s.writeSidecar(ctx, filename, filter, profile, attempts)
+}
+
+// frameArgs builds the ffmpeg arguments to grab one frame into output.
+// Only pass -vf if filter is needed; empty string = no filter.
+// The active CaptureProfile, if any, adjusts the options.
+func (s *SnapshotService) frameArgs(vf string, output ...string) []string {
+	_, p := s.profile()
+	args := append([]string{"-rtsp_transport", "tcp"}, p.inputArgs()...)
+	args = append(args, "-i", s.RtspCamera.RTSPUrl)
+	args = append(args, s.warmupArgs()...)
+	args = append(args, "-frames:v", "1")
+	if vf = p.filter(vf); vf != "" {
+		args = append(args, "-vf", vf)
+	}
+	args = append(args, "-q:v", p.quality())
+	return append(args, output...)
+}
+
+// warmupArgs returns the output options that drop the frames decoded
+// during the warmup, nil without one.
+func (s *SnapshotService) warmupArgs() []string {
+	warmup := s.warmup()
+	if warmup <= 0 {
+		return nil
+	}
+	return []string{"-ss", strconv.FormatFloat(warmup.Seconds(), 'f', 3, 64)}
+}
+
+// warmup returns the Warmup of the active profile, else RtspConfig.Warmup.
+func (s *SnapshotService) warmup() time.Duration {
+	if _, p := s.profile(); p.Warmup > 0 {
+		return p.Warmup
+	}
+	return s.RtspCamera.Warmup
+}
 
-	args := []string{
-		"-rtsp_transport", "tcp",
-		"-i", s.RtspCamera.RTSPUrl,
-		"-frames:v", "1",
-		"-q:v", "2",
-		filename,
+// runFFmpeg runs ffmpeg with args under the camera timeout, extended by the
+// warmup, sending its stdout to stdout (discarded when nil). Each run is
+// logged with the correlation ID of ctx; one is generated when ctx has none.
+// The timeout and warmup are those of the active profile, if set.
+func (s *SnapshotService) runFFmpeg(ctx context.Context, args []string, stdout io.Writer) error {
+	timeout := s.RtspCamera.Timeout
+	if _, p := s.profile(); p.Timeout > 0 {
+		timeout = p.Timeout
 	}
+	return s.runWith(ctx, s.runner(), "ffmpeg", timeout+max(s.warmup(), 0), args, stdout)
+}
+
+// runWith is runFFmpeg for any Runner and timeout; name tags the log messages.
+func (s *SnapshotService) runWith(ctx context.Context, r Runner, name string, timeout time.Duration, args []string, stdout io.Writer) error {
+	ctx = astrolog.EnsureCorrelationID(ctx)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	if vf != "" {
-		// insert filter before output file
-		args = []string{
-			"-rtsp_transport", "tcp",
-			"-i", s.RtspCamera.RTSPUrl,
-			"-frames:v", "1",
-			"-vf", vf,
-			"-q:v", "2",
-			filename,
+	metered := strings.HasPrefix(name, "ffmpeg")
+	var written *countingWriter
+	if metered {
+		if s.RtspCamera.MeterUsage {
+			args = append(append([]string(nil), usageMeterArgs...), args...)
+		}
+		if stdout != nil {
+			written = &countingWriter{w: stdout}
+			stdout = written
 		}
 	}
 
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	out, err := cmd.CombinedOutput()
+	logCtx := astrolog.FromContext(ctx).With().Str("camera_id", s.RtspCamera.ID)
+	if profile := s.ActiveProfile(); profile != "" && metered {
+		logCtx = logCtx.Str("profile", profile)
+	}
+	logger := logCtx.Logger()
+	started := logger.Debug()
+	if s.RtspCamera.Verbose {
+		started = started.Strs("args", redactArgs(args))
+	}
+	started.Msg(name + " capture started")
+	start := time.Now()
+
+	var stderr bytes.Buffer
+	err := r.Run(ctx, args, stdout, &stderr)
+	if metered {
+		s.usage.record(measureRun(args, stderr.String(), written), err != nil)
+	}
 	if err != nil {
-		return fmt.Errorf("ffmpeg error: %w | %s", err, string(out))
+		if errors.Is(err, ErrFFmpegNotFound) || errors.Is(err, ErrFFprobeNotFound) {
+			logger.Error().Err(err).Msg(name + " capture failed")
+			return err
+		}
+		ffErr := newFFmpegError(ctx, args, err, stderr.Bytes())
+		if name == "ffmpeg" {
+			s.recordRun(ctx, ffErr)
+		}
+		logger.Warn().
+			Int("exit_code", ffErr.ExitCode).
+			Bool("timeout", ffErr.Timeout).
+			Dur("duration", time.Since(start)).
+			Err(err).
+			Msg(name + " capture failed")
+		return ffErr
 	}
 
+	logger.Debug().Dur("duration", time.Since(start)).Msg(name + " capture done")
+	if name == "ffmpeg" {
+		s.recordRun(ctx, nil)
+	}
 	return nil
 }
 
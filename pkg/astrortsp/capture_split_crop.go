@@ -2,9 +2,12 @@
 package astrortsp
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
 )
 
 // captureSplit captures an image and splits it vertically/horizontally
@@ -37,22 +40,59 @@ func (s *SnapshotService) CaptureSplit(vertical bool, splitPos int) (string, str
 	out1 := imgPath("1")
 	out2 := imgPath("2")
 
-	if err := s.captureAndSaveWithFilter(s.RtspCamera.Context, filter1, out1); err != nil {
+	// Both halves are one logical capture and share a correlation ID.
+	ctx := astrolog.EnsureCorrelationID(s.baseContext())
+	if err := s.captureAndSaveWithFilter(ctx, filter1, out1); err != nil {
 		return "", "", fmt.Errorf("error saving first split: %w", err)
 	}
-	if err := s.captureAndSaveWithFilter(s.RtspCamera.Context, filter2, out2); err != nil {
+	if err := s.captureAndSaveWithFilter(ctx, filter2, out2); err != nil {
 		return "", "", fmt.Errorf("error saving second split: %w", err)
 	}
 
 	return out1, out2, nil
 }
 
-// CaptureCrop captures an image and crops it to a specific rectangle
+// ErrCropOutOfBounds is returned by CaptureCrop for rectangles that don't fit
+// the frame.
+var ErrCropOutOfBounds = errors.New("crop rectangle out of frame bounds")
+
+// CaptureCrop captures an image and crops it to a specific rectangle.
+// Once ProbeStream has run, the rectangle is checked against the stream
+// resolution before ffmpeg is started.
 func (s *SnapshotService) CaptureCrop(x, y, w, h int) (string, error) {
+	if x < 0 || y < 0 || w <= 0 || h <= 0 {
+		return "", fmt.Errorf("%w: x=%d y=%d w=%d h=%d must be positive", ErrCropOutOfBounds, x, y, w, h)
+	}
+	if info := s.cachedStreamInfo(); info != nil && info.Width > 0 && info.Height > 0 {
+		if x+w > info.Width || y+h > info.Height {
+			return "", fmt.Errorf("%w: x+w=%d, y+h=%d but stream is %dx%d", ErrCropOutOfBounds, x+w, y+h, info.Width, info.Height)
+		}
+	}
+
 	filter := fmt.Sprintf("crop=%d:%d:%d:%d", w, h, x, y)
 	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_crop_%s.jpg", s.RtspCamera.ID, time.Now().Format("2006-01-02_15-04-05")))
-	if err := s.captureAndSaveWithFilter(s.RtspCamera.Context, filter, outFile); err != nil {
+	if err := s.captureAndSaveWithFilter(s.baseContext(), filter, outFile); err != nil {
 		return "", err
 	}
 	return outFile, nil
 }
+
+// CaptureCropUnion captures an image cropped to the union of boxes grown by
+// pad pixels on every side, e.g. one crop for a cluster of detections
+// instead of one per detection. The padded rectangle is clamped to the
+// frame: at 0 always, and to the stream resolution once ProbeStream has run.
+// A negative pad is treated as 0.
+func (s *SnapshotService) CaptureCropUnion(boxes []Rectangle, pad int) (string, error) {
+	if len(boxes) == 0 {
+		return "", fmt.Errorf("%w: no boxes to crop to", ErrCropOutOfBounds)
+	}
+	pad = max(pad, 0)
+
+	u := BoundingBoxOfMany(boxes)
+	x0, y0 := max(u.X-pad, 0), max(u.Y-pad, 0)
+	x1, y1 := u.X+u.Width+pad, u.Y+u.Height+pad
+	if info := s.cachedStreamInfo(); info != nil && info.Width > 0 && info.Height > 0 {
+		x1, y1 = min(x1, info.Width), min(y1, info.Height)
+	}
+	return s.CaptureCrop(x0, y0, x1-x0, y1-y0)
+}
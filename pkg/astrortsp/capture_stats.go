@@ -0,0 +1,49 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register the JPEG decoder for image.DecodeConfig
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CaptureResult describes one saved capture, for latency and resolution
+// monitoring.
+type CaptureResult struct {
+	Path     string
+	Width    int
+	Height   int
+	Duration time.Duration // wall-clock time of the ffmpeg run
+}
+
+// CaptureImgWithStats is CaptureImg that also reports how long ffmpeg took
+// and the resolution of the saved image.
+func (s *SnapshotService) CaptureImgWithStats(ctx context.Context) (CaptureResult, error) {
+	ts := time.Now().Format("2006-01-02_15-04-05")
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_%s.jpg", s.RtspCamera.ID, ts))
+
+	start := time.Now()
+	err := s.captureAndSaveWithFilter(ctx, "", outFile)
+	result := CaptureResult{Path: outFile, Duration: time.Since(start)}
+	if err != nil {
+		result.Path = ""
+		return result, err
+	}
+
+	f, err := os.Open(outFile)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return result, fmt.Errorf("read dimensions of %s: %w", outFile, err)
+	}
+	result.Width, result.Height = cfg.Width, cfg.Height
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package astrortsp_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp"
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp/astrortsptest"
+)
+
+func TestCaptureImg(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+
+	path, err := s.CaptureImg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != s.RtspCamera.OutputDir || !strings.HasPrefix(filepath.Base(path), "testcam_") {
+		t.Errorf("path = %q, want testcam_* in OutputDir", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, astrortsptest.GoldenJPEG) {
+		t.Error("captured file is not the golden JPEG")
+	}
+
+	calls := runner.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("ffmpeg ran %d times, want 1", len(calls))
+	}
+	if args := strings.Join(calls[0], " "); strings.Contains(args, "-vf") {
+		t.Errorf("args %q carry a filter", args)
+	}
+}
+
+func TestCaptureImgAuthFailure(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+	runner.Default = astrortsptest.AuthFailure()
+
+	_, err := s.CaptureImg()
+	var ffErr *astrortsp.FFmpegError
+	if !errors.As(err, &ffErr) {
+		t.Fatalf("err = %v, want *FFmpegError", err)
+	}
+	if !ffErr.AuthFailed() {
+		t.Errorf("AuthFailed() = false for %q", ffErr.Stderr)
+	}
+	for _, a := range ffErr.Args {
+		if strings.Contains(a, "secret") {
+			t.Errorf("password leaked into error args: %q", a)
+		}
+	}
+}
+
+func TestCaptureImgTimeout(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+	s.RtspCamera.Timeout = 20 * time.Millisecond
+	runner.Default = astrortsptest.Timeout()
+
+	_, err := s.CaptureImg()
+	var ffErr *astrortsp.FFmpegError
+	if !errors.As(err, &ffErr) || !ffErr.Timeout {
+		t.Fatalf("err = %v, want a timed out *FFmpegError", err)
+	}
+}
+
+func TestCaptureCrop(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+
+	path, err := s.CaptureCrop(10, 20, 300, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(filepath.Base(path), "_crop_") {
+		t.Errorf("path = %q, want a _crop_ file", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := runner.Calls()
+	if len(calls) != 1 || !strings.Contains(strings.Join(calls[0], " "), "-vf crop=300:200:10:20") {
+		t.Errorf("calls = %v, want one run with crop=300:200:10:20", calls)
+	}
+}
+
+func TestCaptureCropRejectsInvalidRect(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+
+	if _, err := s.CaptureCrop(-1, 0, 10, 10); !errors.Is(err, astrortsp.ErrCropOutOfBounds) {
+		t.Errorf("err = %v, want ErrCropOutOfBounds", err)
+	}
+	if len(runner.Calls()) != 0 {
+		t.Error("ffmpeg ran for an invalid rectangle")
+	}
+}
+
+func TestCaptureCropOutsideProbedFrame(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+	runner.On(astrortsptest.ArgContains("-show_streams"), astrortsptest.Probe(640, 480, "h264", 25))
+	if _, err := s.ProbeStream(s.RtspCamera.Context); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.CaptureCrop(600, 0, 100, 100); !errors.Is(err, astrortsp.ErrCropOutOfBounds) {
+		t.Errorf("err = %v, want ErrCropOutOfBounds", err)
+	}
+	if _, err := s.CaptureCrop(540, 380, 100, 100); err != nil {
+		t.Errorf("crop flush with the frame edge: %v", err)
+	}
+}
+
+func TestCaptureCropPartialOutput(t *testing.T) {
+	s, runner := astrortsptest.NewTestService(t)
+	runner.On(astrortsptest.ArgContains("crop="), astrortsptest.PartialOutput())
+
+	if _, err := s.CaptureCrop(0, 0, 8, 8); err == nil {
+		t.Fatal("CaptureCrop succeeded on partial output")
+	}
+}
@@ -0,0 +1,89 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Uploader stores an object, e.g. in S3 or GCS. Put must read r to EOF and
+// fail when a Read returns an error, so a failed capture never lands as a
+// complete object.
+type Uploader interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+}
+
+// ErrNoUploader is returned by CaptureAndUpload when SnapshotService.Uploader is nil.
+var ErrNoUploader = errors.New("snapshot service has no uploader")
+
+// CaptureAndUpload captures a single JPEG and streams it from ffmpeg straight
+// to s.Uploader under key, without touching the local disk. A failed upload
+// stops ffmpeg; a failed capture makes the uploader's reader fail.
+// Transient failures are retried following RtspConfig.Retry, as long as
+// ffmpeg has not written anything to the upload yet.
+func (s *SnapshotService) CaptureAndUpload(key string) error {
+	if s.Uploader == nil {
+		return ErrNoUploader
+	}
+
+	ctx, cancel := context.WithCancel(s.baseContext())
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	var (
+		uploaded    = make(chan error, 1)
+		captureDone atomic.Bool
+		aborted     bool // the upload failed first and stopped ffmpeg
+	)
+	go func() {
+		err := s.Uploader.Put(ctx, key, pr, "image/jpeg")
+		if err != nil && !captureDone.Load() {
+			aborted = true
+			cancel() // no one is reading: stop ffmpeg
+		}
+		pr.CloseWithError(err)
+		uploaded <- err
+	}()
+
+	out := &countingWriter{w: pw}
+	captureErr := s.retryCapture(ctx, "", func(ctx context.Context) error {
+		err := s.runFFmpeg(ctx, s.frameArgs("", "-f", "image2", "-c:v", "mjpeg", "pipe:1"), out)
+		if err != nil && (out.n > 0 || ctx.Err() != nil) {
+			return finalError{err} // the upload got part of this run, or was aborted
+		}
+		return err
+	})
+	if captureErr == nil && out.n == 0 {
+		captureErr = ErrEmptyCapture
+	}
+	captureDone.Store(true)
+	if captureErr != nil {
+		pw.CloseWithError(captureErr)
+	} else {
+		pw.Close()
+	}
+
+	uploadErr := <-uploaded
+	if captureErr != nil && !aborted {
+		return captureErr
+	}
+	if uploadErr != nil {
+		return fmt.Errorf("upload %s: %w", key, uploadErr)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,172 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNoROI is returned when no region passes the ROIOptions thresholds.
+var ErrNoROI = errors.New("no region of interest found")
+
+// roiMaxSide is the longest side (px) of the working copy DetectROI analyses.
+const roiMaxSide = 160
+
+// ROIOptions tunes DetectROI. The zero value looks for the largest bright area.
+type ROIOptions struct {
+	// Dark looks for the largest dark area instead of the largest bright one.
+	Dark bool
+	// Threshold is the luminance (0–255) separating bright from dark.
+	// 0 → 128.
+	Threshold int
+	// MinAreaRatio is the smallest component, as a fraction of the frame,
+	// accepted as a region. 0 → 0.005 (0.5%).
+	MinAreaRatio float64
+	// MarginRatio grows the box on each side by this fraction of its size
+	// before clamping to the frame. 0 → 0.05.
+	MarginRatio float64
+}
+
+func (o ROIOptions) withDefaults() ROIOptions {
+	if o.Threshold <= 0 {
+		o.Threshold = 128
+	}
+	if o.MinAreaRatio <= 0 {
+		o.MinAreaRatio = 0.005
+	}
+	if o.MarginRatio <= 0 {
+		o.MarginRatio = 0.05
+	}
+	return o
+}
+
+// DetectROI finds the bounding box of the largest contiguous bright (or dark)
+// area of a JPEG/PNG image, in the image's pixel coordinates.
+func DetectROI(img []byte, opts ROIOptions) (Rectangle, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return Rectangle{}, fmt.Errorf("decode image: %w", err)
+	}
+	return detectROI(decoded, opts.withDefaults())
+}
+
+func detectROI(img image.Image, opts ROIOptions) (Rectangle, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return Rectangle{}, ErrNoROI
+	}
+
+	// ── Downscale: one cell per step×step block ──────────────────────────────
+	step := max((max(width, height)+roiMaxSide-1)/roiMaxSide, 1)
+	cols := (width + step - 1) / step
+	rows := (height + step - 1) / step
+
+	mask := make([]bool, cols*rows)
+	for cy := 0; cy < rows; cy++ {
+		for cx := 0; cx < cols; cx++ {
+			l := luminance(img, bounds.Min.X+min(cx*step+step/2, width-1), bounds.Min.Y+min(cy*step+step/2, height-1))
+			if opts.Dark {
+				mask[cy*cols+cx] = l < opts.Threshold
+			} else {
+				mask[cy*cols+cx] = l >= opts.Threshold
+			}
+		}
+	}
+
+	// ── Largest 4-connected component ────────────────────────────────────────
+	seen := make([]bool, len(mask))
+	bestArea := 0
+	var best [4]int // minX, minY, maxX, maxY in cells
+	for start := range mask {
+		if !mask[start] || seen[start] {
+			continue
+		}
+		area := 0
+		box := [4]int{cols, rows, -1, -1}
+		stack := []int{start}
+		seen[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%cols, i/cols
+			area++
+			box[0], box[1] = min(box[0], x), min(box[1], y)
+			box[2], box[3] = max(box[2], x), max(box[3], y)
+
+			for _, n := range [][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				nx, ny := n[0], n[1]
+				if nx < 0 || ny < 0 || nx >= cols || ny >= rows {
+					continue
+				}
+				j := ny*cols + nx
+				if mask[j] && !seen[j] {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		if area > bestArea {
+			bestArea, best = area, box
+		}
+	}
+
+	if bestArea == 0 || float64(bestArea) < opts.MinAreaRatio*float64(len(mask)) {
+		return Rectangle{}, ErrNoROI
+	}
+
+	// ── Back to pixels, add margin, clamp ────────────────────────────────────
+	x0, y0 := best[0]*step, best[1]*step
+	x1, y1 := min((best[2]+1)*step, width), min((best[3]+1)*step, height)
+	mx := int(float64(x1-x0) * opts.MarginRatio)
+	my := int(float64(y1-y0) * opts.MarginRatio)
+	x0, y0 = max(x0-mx, 0), max(y0-my, 0)
+	x1, y1 = min(x1+mx, width), min(y1+my, height)
+
+	return Rectangle{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}, nil
+}
+
+// CaptureAutoROI captures a frame, detects its region of interest and saves
+// the frame cropped to it. The crop is done locally on the same frame, so
+// nothing can move between detection and crop.
+func (s *SnapshotService) CaptureAutoROI(ctx context.Context, opts ROIOptions) (string, error) {
+	data, err := s.CaptureImgBytes(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decode capture: %w", err)
+	}
+
+	roi, err := detectROI(img, opts.withDefaults())
+	if err != nil {
+		return "", err
+	}
+
+	cropped, err := cropImage(img, roi)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := encodeImage(cropped, "jpeg")
+	if err != nil {
+		return "", err
+	}
+
+	outFile := filepath.Join(s.RtspCamera.OutputDir, fmt.Sprintf("%s_roi_%s.jpg", s.RtspCamera.ID, time.Now().Format("2006-01-02_15-04-05")))
+	if err := os.MkdirAll(s.RtspCamera.OutputDir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(outFile, encoded, 0644); err != nil {
+		return "", err
+	}
+
+	return outFile, nil
+}
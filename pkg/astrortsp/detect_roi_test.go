@@ -0,0 +1,81 @@
+package astrortsp
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// roiFrame returns a w×h PNG filled with bg and the given blobs in fg.
+func roiFrame(t *testing.T, w, h int, bg, fg color.Gray, blobs ...image.Rectangle) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	for _, b := range blobs {
+		draw.Draw(img, b, image.NewUniform(fg), image.Point{}, draw.Src)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var (
+	roiDark   = color.Gray{Y: 0}
+	roiBright = color.Gray{Y: 255}
+	// roiBlob is 80×80 px; at 320 px wide DetectROI works on 2 px cells, so
+	// the box is exact, then grown by the default 5% margin (4 px) a side.
+	roiBlob = image.Rect(100, 60, 180, 140)
+	roiBox  = Rectangle{X: 96, Y: 56, Width: 88, Height: 88}
+)
+
+func TestDetectROIBrightBlob(t *testing.T) {
+	small := image.Rect(10, 10, 30, 30)
+	roi, err := DetectROI(roiFrame(t, 320, 240, roiDark, roiBright, small, roiBlob), ROIOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roi != roiBox {
+		t.Errorf("roi = %+v, want the largest roiBlob %+v", roi, roiBox)
+	}
+}
+
+func TestDetectROIDarkBlob(t *testing.T) {
+	frame := roiFrame(t, 320, 240, roiBright, roiDark, roiBlob)
+	roi, err := DetectROI(frame, ROIOptions{Dark: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roi != roiBox {
+		t.Errorf("roi = %+v, want %+v", roi, roiBox)
+	}
+
+	// Looking for bright areas, the frame around the roiBlob wins.
+	roi, err = DetectROI(frame, ROIOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full := (Rectangle{Width: 320, Height: 240}); roi != full {
+		t.Errorf("bright roi = %+v, want the whole frame", roi)
+	}
+}
+
+func TestDetectROINoRegion(t *testing.T) {
+	for name, frame := range map[string][]byte{
+		"uniform":      roiFrame(t, 320, 240, color.Gray{Y: 100}, roiDark),
+		"tiny roiBlob": roiFrame(t, 320, 240, roiDark, roiBright, image.Rect(0, 0, 4, 4)),
+	} {
+		if roi, err := DetectROI(frame, ROIOptions{}); !errors.Is(err, ErrNoROI) {
+			t.Errorf("%s: roi %+v, err %v; want ErrNoROI", name, roi, err)
+		}
+	}
+
+	if _, err := DetectROI([]byte("not an image"), ROIOptions{}); err == nil || errors.Is(err, ErrNoROI) {
+		t.Errorf("garbage: err = %v, want a decode error", err)
+	}
+}
@@ -0,0 +1,121 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// diffBlockSize is the side (px) of the blocks compared by DiffRegions.
+const diffBlockSize = 16
+
+// DiffRegions compares two captures of the same size and returns the bounding
+// rectangles of the regions that changed.
+//
+// The images are split into 16×16 blocks; a block has changed when the mean
+// absolute luminance difference (0–255) exceeds threshold. Touching changed
+// blocks are merged into one rectangle.
+func DiffRegions(imgA, imgB string, threshold int) ([]Rectangle, error) {
+	a, err := decodeImageFile(imgA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeImageFile(imgB)
+	if err != nil {
+		return nil, err
+	}
+
+	boundsA, boundsB := a.Bounds(), b.Bounds()
+	if boundsA.Dx() != boundsB.Dx() || boundsA.Dy() != boundsB.Dy() {
+		return nil, fmt.Errorf("image size mismatch: %dx%d vs %dx%d",
+			boundsA.Dx(), boundsA.Dy(), boundsB.Dx(), boundsB.Dy())
+	}
+
+	width, height := boundsA.Dx(), boundsA.Dy()
+	cols := (width + diffBlockSize - 1) / diffBlockSize
+	rows := (height + diffBlockSize - 1) / diffBlockSize
+
+	// ── Mark changed blocks ──────────────────────────────────────────────────
+	changed := make([]bool, cols*rows)
+	for by := 0; by < rows; by++ {
+		for bx := 0; bx < cols; bx++ {
+			var sum, count int
+			for y := by * diffBlockSize; y < min((by+1)*diffBlockSize, height); y++ {
+				for x := bx * diffBlockSize; x < min((bx+1)*diffBlockSize, width); x++ {
+					la := luminance(a, boundsA.Min.X+x, boundsA.Min.Y+y)
+					lb := luminance(b, boundsB.Min.X+x, boundsB.Min.Y+y)
+					if la > lb {
+						sum += la - lb
+					} else {
+						sum += lb - la
+					}
+					count++
+				}
+			}
+			changed[by*cols+bx] = count > 0 && sum/count > threshold
+		}
+	}
+
+	// ── Group touching blocks and box each group ─────────────────────────────
+	var regions []Rectangle
+	seen := make([]bool, cols*rows)
+	for start := range changed {
+		if !changed[start] || seen[start] {
+			continue
+		}
+
+		var corners []Point
+		stack := []int{start}
+		seen[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			bx, by := i%cols, i/cols
+
+			corners = append(corners,
+				Point{X: bx * diffBlockSize, Y: by * diffBlockSize},
+				Point{X: min((bx+1)*diffBlockSize, width), Y: min((by+1)*diffBlockSize, height)},
+			)
+
+			for _, n := range [][2]int{{bx - 1, by}, {bx + 1, by}, {bx, by - 1}, {bx, by + 1}} {
+				nx, ny := n[0], n[1]
+				if nx < 0 || ny < 0 || nx >= cols || ny >= rows {
+					continue
+				}
+				j := ny*cols + nx
+				if changed[j] && !seen[j] {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+
+		regions = append(regions, ExtractBoundingBoxN(corners...))
+	}
+
+	return regions, nil
+}
+
+// decodeImageFile opens and decodes a JPEG or PNG file.
+func decodeImageFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// luminance returns the 0–255 luma (BT.601) of the pixel at (x, y).
+func luminance(img image.Image, x, y int) int {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return int((299*r + 587*g + 114*b) / 1000 >> 8)
+}
@@ -9,15 +9,21 @@ type Rectangle struct {
 	X, Y, Width, Height int
 }
 
+// ExtractBoundingBox returns the smallest rectangle containing the four
+// points; see ExtractBoundingBoxN.
 func ExtractBoundingBox(p1, p2, p3, p4 Point) Rectangle {
-	// Collect all points
-	points := []Point{p1, p2, p3, p4}
+	return ExtractBoundingBoxN(p1, p2, p3, p4)
+}
+
+// ExtractBoundingBoxN returns the smallest rectangle containing all points.
+// It returns an empty Rectangle when no point is given.
+func ExtractBoundingBoxN(points ...Point) Rectangle {
+	if len(points) == 0 {
+		return Rectangle{}
+	}
 
-	// Find min and max for x and y
-	minX := points[0].X
-	maxX := points[0].X
-	minY := points[0].Y
-	maxY := points[0].Y
+	minX, maxX := points[0].X, points[0].X
+	minY, maxY := points[0].Y, points[0].Y
 
 	for _, p := range points[1:] {
 		if p.X < minX {
@@ -41,3 +47,16 @@ func ExtractBoundingBox(p1, p2, p3, p4 Point) Rectangle {
 		Height: maxY - minY,
 	}
 }
+
+// BoundingBoxOfMany returns the union of boxes: the smallest rectangle
+// containing all of them. It returns an empty Rectangle when no box is given.
+func BoundingBoxOfMany(boxes []Rectangle) Rectangle {
+	corners := make([]Point, 0, 2*len(boxes))
+	for _, b := range boxes {
+		corners = append(corners,
+			Point{X: b.X, Y: b.Y},
+			Point{X: b.X + b.Width, Y: b.Y + b.Height},
+		)
+	}
+	return ExtractBoundingBoxN(corners...)
+}
@@ -0,0 +1,28 @@
+package astrortsp
+
+import "testing"
+
+func TestExtractBoundingBox(t *testing.T) {
+	got := ExtractBoundingBox(Point{10, 40}, Point{30, 5}, Point{-2, 20}, Point{15, 15})
+	want := Rectangle{X: -2, Y: 5, Width: 32, Height: 35}
+	if got != want {
+		t.Errorf("ExtractBoundingBox = %+v, want %+v", got, want)
+	}
+}
+
+func TestExtractBoundingBoxN(t *testing.T) {
+	if got := ExtractBoundingBoxN(); got != (Rectangle{}) {
+		t.Errorf("no points: %+v, want empty", got)
+	}
+	if got := ExtractBoundingBoxN(Point{3, 4}); got != (Rectangle{X: 3, Y: 4}) {
+		t.Errorf("one point: %+v", got)
+	}
+}
+
+func TestBoundingBoxOfMany(t *testing.T) {
+	got := BoundingBoxOfMany([]Rectangle{{X: 0, Y: 0, Width: 8, Height: 8}, {X: 16, Y: 8, Width: 8, Height: 8}})
+	want := Rectangle{X: 0, Y: 0, Width: 24, Height: 16}
+	if got != want {
+		t.Errorf("BoundingBoxOfMany = %+v, want %+v", got, want)
+	}
+}
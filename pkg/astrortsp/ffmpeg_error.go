@@ -0,0 +1,107 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+)
+
+// ffmpegStderrLimit caps how much of ffmpeg's stderr is kept in an error.
+// Only the tail is kept, which is where ffmpeg reports the failure.
+const ffmpegStderrLimit = 4 * 1024
+
+// FFmpegError describes a failed ffmpeg run.
+type FFmpegError struct {
+	ExitCode int      // process exit code, -1 if it did not exit normally
+	Stderr   string   // last ffmpegStderrLimit bytes of stderr, URL credentials redacted
+	Args     []string // ffmpeg arguments, with RTSP credentials redacted
+	Timeout  bool     // the run was killed because the capture timeout expired
+	Err      error    // underlying exec error
+
+	CorrelationID string // ID shared by every log entry of this capture
+}
+
+func (e *FFmpegError) Error() string {
+	if e.Timeout {
+		return fmt.Sprintf("ffmpeg error: timed out: %v | %s", e.Err, e.Stderr)
+	}
+	return fmt.Sprintf("ffmpeg error: %v | %s", e.Err, e.Stderr)
+}
+
+func (e *FFmpegError) Unwrap() error {
+	return e.Err
+}
+
+// Unreachable reports whether ffmpeg could not connect to the camera.
+func (e *FFmpegError) Unreachable() bool {
+	s := strings.ToLower(e.Stderr)
+	return strings.Contains(s, "connection refused") ||
+		strings.Contains(s, "no route to host") ||
+		strings.Contains(s, "network is unreachable") ||
+		strings.Contains(s, "connection timed out")
+}
+
+// AuthFailed reports whether the camera rejected the credentials.
+func (e *FFmpegError) AuthFailed() bool {
+	return strings.Contains(strings.ToLower(e.Stderr), "401 unauthorized")
+}
+
+// newFFmpegError builds an FFmpegError from a failed run under ctx.
+func newFFmpegError(ctx context.Context, args []string, err error, stderr []byte) *FFmpegError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	if len(stderr) > ffmpegStderrLimit {
+		stderr = stderr[len(stderr)-ffmpegStderrLimit:]
+	}
+
+	return &FFmpegError{
+		ExitCode: exitCode,
+		Stderr:   redactText(string(stderr)),
+		Args:     redactArgs(args),
+		Timeout:  errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Err:      err,
+
+		CorrelationID: astrolog.CorrelationID(ctx),
+	}
+}
+
+// redactArgs returns a copy of args with the userinfo of URLs replaced.
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = redactURL(arg)
+	}
+	return out
+}
+
+// urlUserinfo matches the "user:pass@" part of the URLs in a text, e.g.
+// the input URL ffmpeg repeats in its errors.
+var urlUserinfo = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)[^\s/@]+@`)
+
+// redactText hides the userinfo of every URL in s, since ffmpeg's stderr
+// ends up in error messages and logs.
+func redactText(s string) string {
+	return urlUserinfo.ReplaceAllString(s, "${1}xxxxx@")
+}
+
+// redactURL hides the whole userinfo of a URL, a username alone being a
+// token for some cameras; other strings are returned as-is.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = url.User("xxxxx")
+	return u.String()
+}
@@ -0,0 +1,356 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local image operations mirroring the ffmpeg crop, scale and drawtext
+// filters, for frames that are already on disk or in memory. Everything is
+// pure Go; no ffmpeg process is started.
+//
+// Byte-slice variants keep the input format (JPEG or PNG). File variants pick
+// the output format from dst's extension: ".png" writes PNG, anything else
+// JPEG. JPEGs are written at jpegQuality, close to the "-q:v 2" used by the
+// capture functions.
+
+// jpegQuality is the quality of every JPEG encoded in this package.
+const jpegQuality = 95
+
+// ErrInvalidScale is returned by ScaleImage when neither dimension is set.
+var ErrInvalidScale = errors.New("invalid scale dimensions")
+
+// =============================
+// Crop
+// =============================
+
+// CropImage crops an encoded image to r.
+func CropImage(data []byte, r Rectangle) ([]byte, error) {
+	img, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	cropped, err := cropImage(img, r)
+	if err != nil {
+		return nil, err
+	}
+	return encodeImage(cropped, format)
+}
+
+// CropImageFile crops the image in src to r and writes it to dst.
+func CropImageFile(src, dst string, r Rectangle) error {
+	return transformFile(src, dst, func(img image.Image) (image.Image, error) {
+		return cropImage(img, r)
+	})
+}
+
+// cropImage returns the part of img inside r, relative to img's origin.
+func cropImage(img image.Image, r Rectangle) (image.Image, error) {
+	b := img.Bounds()
+	if r.X < 0 || r.Y < 0 || r.Width <= 0 || r.Height <= 0 || r.X+r.Width > b.Dx() || r.Y+r.Height > b.Dy() {
+		return nil, fmt.Errorf("%w: x=%d y=%d w=%d h=%d but image is %dx%d", ErrCropOutOfBounds, r.X, r.Y, r.Width, r.Height, b.Dx(), b.Dy())
+	}
+	rect := image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height).Add(b.Min)
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect), nil
+	}
+	out := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	draw.Draw(out, out.Bounds(), img, rect.Min, draw.Src)
+	return out, nil
+}
+
+// =============================
+// Scale
+// =============================
+
+// ScaleImage resizes an encoded image to width x height with bilinear
+// filtering. Like ffmpeg's scale filter, a dimension <= 0 is derived from the
+// other one so the aspect ratio is kept.
+func ScaleImage(data []byte, width, height int) ([]byte, error) {
+	img, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	scaled, err := scaleImage(img, width, height)
+	if err != nil {
+		return nil, err
+	}
+	return encodeImage(scaled, format)
+}
+
+// ScaleImageFile resizes the image in src and writes it to dst.
+// See ScaleImage for how width and height are interpreted.
+func ScaleImageFile(src, dst string, width, height int) error {
+	return transformFile(src, dst, func(img image.Image) (image.Image, error) {
+		return scaleImage(img, width, height)
+	})
+}
+
+func scaleImage(img image.Image, width, height int) (image.Image, error) {
+	b := img.Bounds()
+	switch {
+	case width <= 0 && height <= 0:
+		return nil, fmt.Errorf("%w: %dx%d", ErrInvalidScale, width, height)
+	case width <= 0:
+		width = max(1, (b.Dx()*height+b.Dy()/2)/b.Dy())
+	case height <= 0:
+		height = max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+	}
+
+	src := toRGBA(img)
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	xRatio := float64(sw) / float64(width)
+	yRatio := float64(sh) / float64(height)
+
+	for y := 0; y < height; y++ {
+		fy := max(0, (float64(y)+0.5)*yRatio-0.5)
+		y0 := min(int(fy), sh-1)
+		y1 := min(y0+1, sh-1)
+		wy := fy - float64(y0)
+		for x := 0; x < width; x++ {
+			fx := max(0, (float64(x)+0.5)*xRatio-0.5)
+			x0 := min(int(fx), sw-1)
+			x1 := min(x0+1, sw-1)
+			wx := fx - float64(x0)
+
+			p00 := src.Pix[y0*src.Stride+x0*4:]
+			p01 := src.Pix[y0*src.Stride+x1*4:]
+			p10 := src.Pix[y1*src.Stride+x0*4:]
+			p11 := src.Pix[y1*src.Stride+x1*4:]
+			d := out.Pix[y*out.Stride+x*4:]
+			for c := 0; c < 4; c++ {
+				top := float64(p00[c])*(1-wx) + float64(p01[c])*wx
+				bottom := float64(p10[c])*(1-wx) + float64(p11[c])*wx
+				d[c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+	return out, nil
+}
+
+// =============================
+// Text overlay
+// =============================
+
+// TextOptions configures OverlayText.
+type TextOptions struct {
+	X, Y  int         // top-left corner of the text, in pixels
+	Scale int         // pixels per font dot, 0 → 2
+	Color color.Color // text colour, nil → white
+	Box   bool        // draw a translucent black box behind the text
+}
+
+func (o TextOptions) withDefaults() TextOptions {
+	if o.Scale <= 0 {
+		o.Scale = 2
+	}
+	if o.Color == nil {
+		o.Color = color.White
+	}
+	return o
+}
+
+// OverlayText draws text on an encoded image with the package's built-in
+// 5x7 bitmap font. Lowercase letters are drawn as uppercase and characters
+// the font lacks as '?'. Text running past the image edge is clipped.
+func OverlayText(data []byte, text string, opts TextOptions) ([]byte, error) {
+	img, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	return encodeImage(overlayText(img, text, opts.withDefaults()), format)
+}
+
+// OverlayTextOnFile draws text on the image in src and writes it to dst.
+func OverlayTextOnFile(src, dst, text string, opts TextOptions) error {
+	return transformFile(src, dst, func(img image.Image) (image.Image, error) {
+		return overlayText(img, text, opts.withDefaults()), nil
+	})
+}
+
+func overlayText(img image.Image, text string, opts TextOptions) image.Image {
+	out := toRGBA(img)
+	lines := strings.Split(text, "\n")
+	dot := opts.Scale
+	advance := (glyphWidth + 1) * dot
+	lineHeight := (glyphHeight + 2) * dot
+
+	if opts.Box {
+		longest := 0
+		for _, line := range lines {
+			longest = max(longest, len([]rune(line)))
+		}
+		box := image.Rect(opts.X-dot, opts.Y-dot, opts.X+longest*advance, opts.Y+len(lines)*lineHeight-dot).Intersect(out.Rect)
+		draw.Draw(out, box, image.NewUniform(color.NRGBA{A: 0x99}), image.Point{}, draw.Over)
+	}
+
+	ink := image.NewUniform(opts.Color)
+	for row, line := range lines {
+		x := opts.X
+		y := opts.Y + row*lineHeight
+		for _, r := range line {
+			glyph := lookupGlyph(r)
+			for gy, bits := range glyph {
+				for gx := 0; gx < glyphWidth; gx++ {
+					if bits&(1<<(glyphWidth-1-gx)) == 0 {
+						continue
+					}
+					px := image.Rect(x+gx*dot, y+gy*dot, x+(gx+1)*dot, y+(gy+1)*dot).Intersect(out.Rect)
+					draw.Draw(out, px, ink, image.Point{}, draw.Over)
+				}
+			}
+			x += advance
+		}
+	}
+	return out
+}
+
+// =============================
+// Helpers
+// =============================
+
+func decodeImage(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	return img, format, nil
+}
+
+// encodeImage encodes img as PNG when format is "png" and as JPEG otherwise.
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// transformFile decodes src, applies fn and writes the result to dst in the
+// format given by dst's extension.
+func transformFile(src, dst string, fn func(image.Image) (image.Image, error)) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	img, _, err := decodeImage(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	out, err := fn(img)
+	if err != nil {
+		return err
+	}
+
+	format := "jpeg"
+	if strings.EqualFold(filepath.Ext(dst), ".png") {
+		format = "png"
+	}
+	encoded, err := encodeImage(out, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, encoded, 0644)
+}
+
+// toRGBA returns a copy of img as *image.RGBA with its origin at (0, 0).
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	return out
+}
+
+// =============================
+// 5x7 bitmap font
+// =============================
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// glyphs holds one byte per row, the low five bits left to right.
+var glyphs = map[rune][glyphHeight]uint8{
+	' ': {},
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	':': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	';': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x04, 0x08},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',': {0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'+': {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'=': {0x00, 0x00, 0x1F, 0x00, 0x1F, 0x00, 0x00},
+	'_': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	'/': {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'#': {0x0A, 0x0A, 0x1F, 0x0A, 0x1F, 0x0A, 0x0A},
+	'%': {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+}
+
+func lookupGlyph(r rune) [glyphHeight]uint8 {
+	if r >= 'a' && r <= 'z' {
+		r -= 'a' - 'A'
+	}
+	if g, ok := glyphs[r]; ok {
+		return g
+	}
+	return glyphs['?']
+}
@@ -0,0 +1,253 @@
+package astrortsp
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	quadRed   = color.RGBA{R: 255, A: 255}
+	quadGreen = color.RGBA{G: 255, A: 255}
+	quadBlue  = color.RGBA{B: 255, A: 255}
+)
+
+// quadrants returns a 64×48 PNG, quadRed, quadGreen, quadBlue and white clockwise from
+// the top-left quarter.
+func quadrants(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for _, q := range []struct {
+		r image.Rectangle
+		c color.Color
+	}{
+		{image.Rect(0, 0, 32, 24), quadRed},
+		{image.Rect(32, 0, 64, 24), quadGreen},
+		{image.Rect(0, 24, 32, 48), quadBlue},
+		{image.Rect(32, 24, 64, 48), color.White},
+	} {
+		draw.Draw(img, q.r, image.NewUniform(q.c), image.Point{}, draw.Src)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodeAs decodes data and checks its format.
+func decodeAs(t *testing.T, data []byte, wantFormat string) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != wantFormat {
+		t.Errorf("format = %s, want %s", format, wantFormat)
+	}
+	return img
+}
+
+// wantColor checks the pixel at (x, y), relative to img's origin.
+func wantColor(t *testing.T, img image.Image, x, y int, want color.Color) {
+	t.Helper()
+	b := img.Bounds()
+	got := color.RGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+	if w := color.RGBAModel.Convert(want).(color.RGBA); got != w {
+		t.Errorf("pixel (%d,%d) = %v, want %v", x, y, got, w)
+	}
+}
+
+func TestCropImage(t *testing.T) {
+	out, err := CropImage(quadrants(t), Rectangle{X: 24, Y: 16, Width: 16, Height: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := decodeAs(t, out, "png")
+	if b := img.Bounds(); b.Dx() != 16 || b.Dy() != 16 {
+		t.Fatalf("size = %dx%d, want 16x16", b.Dx(), b.Dy())
+	}
+	wantColor(t, img, 0, 0, quadRed)
+	wantColor(t, img, 15, 0, quadGreen)
+	wantColor(t, img, 0, 15, quadBlue)
+	wantColor(t, img, 15, 15, color.White)
+
+	// Coordinates are relative to the origin of an already cropped image.
+	src := decodeAs(t, quadrants(t), "png")
+	sub, err := cropImage(src, Rectangle{X: 32, Y: 24, Width: 32, Height: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := cropImage(sub, Rectangle{Width: 8, Height: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantColor(t, again, 0, 0, color.White)
+}
+
+func TestCropImageOutOfBounds(t *testing.T) {
+	data := quadrants(t)
+	for _, r := range []Rectangle{
+		{X: -1, Y: 0, Width: 10, Height: 10},
+		{X: 0, Y: -1, Width: 10, Height: 10},
+		{X: 0, Y: 0, Width: 0, Height: 10},
+		{X: 0, Y: 0, Width: 10, Height: -5},
+		{X: 60, Y: 0, Width: 5, Height: 10},
+		{X: 0, Y: 40, Width: 10, Height: 9},
+		{X: 0, Y: 0, Width: 65, Height: 48},
+	} {
+		if _, err := CropImage(data, r); !errors.Is(err, ErrCropOutOfBounds) {
+			t.Errorf("crop %+v: err = %v, want ErrCropOutOfBounds", r, err)
+		}
+	}
+	if _, err := CropImage(data, Rectangle{Width: 64, Height: 48}); err != nil {
+		t.Errorf("whole image: %v", err)
+	}
+}
+
+func TestScaleImage(t *testing.T) {
+	data := quadrants(t)
+	for _, tc := range []struct {
+		w, h, wantW, wantH int
+	}{
+		{32, 24, 32, 24},
+		{32, 0, 32, 24},  // height from the aspect ratio
+		{0, 96, 128, 96}, // width from the aspect ratio
+		{100, 10, 100, 10},
+	} {
+		out, err := ScaleImage(data, tc.w, tc.h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := decodeAs(t, out, "png")
+		if b := img.Bounds(); b.Dx() != tc.wantW || b.Dy() != tc.wantH {
+			t.Errorf("scale %dx%d: got %dx%d, want %dx%d", tc.w, tc.h, b.Dx(), b.Dy(), tc.wantW, tc.wantH)
+			continue
+		}
+		// The middle of each quarter keeps its colour.
+		wantColor(t, img, tc.wantW/4, tc.wantH/4, quadRed)
+		wantColor(t, img, 3*tc.wantW/4, tc.wantH/4, quadGreen)
+		wantColor(t, img, tc.wantW/4, 3*tc.wantH/4, quadBlue)
+		wantColor(t, img, 3*tc.wantW/4, 3*tc.wantH/4, color.White)
+	}
+
+	if _, err := ScaleImage(data, 0, -1); !errors.Is(err, ErrInvalidScale) {
+		t.Errorf("0x-1: err = %v, want ErrInvalidScale", err)
+	}
+}
+
+// blankPNG returns a w×h PNG filled with c.
+func blankPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOverlayText(t *testing.T) {
+	data := blankPNG(t, 40, 20, color.Black)
+
+	// '1' at scale 1: top row 0x04 is a single dot in the middle column.
+	out, err := OverlayText(data, "1", TextOptions{X: 3, Y: 2, Scale: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := decodeAs(t, out, "png")
+	wantColor(t, img, 3+2, 2, color.White)
+	wantColor(t, img, 3, 2, color.Black)
+	wantColor(t, img, 3+2, 2+6, color.White) // bottom row 0x0E
+	wantColor(t, img, 3+1, 2+6, color.White)
+
+	// Scale 2 makes each dot 2×2, in the given colour.
+	out, err = OverlayText(data, "1", TextOptions{Scale: 2, Color: quadRed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img = decodeAs(t, out, "png")
+	for _, p := range [][2]int{{4, 0}, {5, 0}, {4, 1}, {5, 1}} {
+		wantColor(t, img, p[0], p[1], quadRed)
+	}
+	wantColor(t, img, 6, 0, color.Black)
+
+	// Lowercase is drawn as uppercase, unknown characters as '?'.
+	for _, pair := range [][2]string{{"cam", "CAM"}, {"é~", "??"}} {
+		a, _ := OverlayText(data, pair[0], TextOptions{})
+		b, _ := OverlayText(data, pair[1], TextOptions{})
+		if !bytes.Equal(a, b) {
+			t.Errorf("%q and %q draw differently", pair[0], pair[1])
+		}
+	}
+}
+
+func TestOverlayTextClipsAndBox(t *testing.T) {
+	data := blankPNG(t, 40, 20, color.White)
+
+	// Running past every edge is clipped, not a panic.
+	out, err := OverlayText(data, "CLIPPED\nTEXT", TextOptions{X: 30, Y: 15, Scale: 3, Box: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := decodeAs(t, out, "png").Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+		t.Errorf("size = %dx%d, want 40x20", b.Dx(), b.Dy())
+	}
+	if _, err := OverlayText(data, "X", TextOptions{X: -50, Y: -50}); err != nil {
+		t.Errorf("text before the origin: %v", err)
+	}
+
+	// The box darkens the background around the glyphs only.
+	out, err = OverlayText(data, " ", TextOptions{X: 10, Y: 5, Scale: 1, Box: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := decodeAs(t, out, "png")
+	if c := color.RGBAModel.Convert(img.At(11, 6)).(color.RGBA); c.R >= 255 || c.R == 0 {
+		t.Errorf("inside the box: %v, want a darkened white", c)
+	}
+	wantColor(t, img, 0, 0, color.White)
+	wantColor(t, img, 30, 15, color.White)
+}
+
+func TestImageFileVariantsPickFormat(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "frame.png")
+	if err := os.WriteFile(src, quadrants(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		dst, format string
+		op          func(dst string) error
+	}{
+		{"crop.png", "png", func(dst string) error { return CropImageFile(src, dst, Rectangle{Width: 32, Height: 24}) }},
+		{"crop.jpg", "jpeg", func(dst string) error { return CropImageFile(src, dst, Rectangle{Width: 32, Height: 24}) }},
+		{"scale.PNG", "png", func(dst string) error { return ScaleImageFile(src, dst, 16, 0) }},
+		{"text.jpeg", "jpeg", func(dst string) error { return OverlayTextOnFile(src, dst, "CAM 1", TextOptions{}) }},
+	} {
+		dst := filepath.Join(dir, tc.dst)
+		if err := tc.op(dst); err != nil {
+			t.Fatalf("%s: %v", tc.dst, err)
+		}
+		data, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decodeAs(t, data, tc.format)
+	}
+
+	if err := CropImageFile(src, filepath.Join(dir, "bad.png"), Rectangle{X: 50, Width: 32, Height: 24}); !errors.Is(err, ErrCropOutOfBounds) {
+		t.Errorf("out of bounds: err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.png")); !os.IsNotExist(err) {
+		t.Error("a failed crop wrote its output")
+	}
+}
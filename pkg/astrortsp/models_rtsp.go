@@ -12,5 +12,45 @@ type RtspConfig struct {
 	RTSPUrl   string
 	OutputDir string
 	Timeout   time.Duration
-	Context   context.Context
+
+	// Warmup decodes and discards the frames of this period before the one
+	// saved, so auto-exposure settles after connecting. Single-frame
+	// captures then take Timeout+Warmup at most. Clips and the MJPEG stream
+	// are not affected.
+	// 0 → the first frame is saved.
+	Warmup time.Duration
+
+	// Verbose logs every ffmpeg/ffprobe command line at debug level, with
+	// the credentials of the RTSP URL redacted, to diagnose captures.
+	Verbose bool
+
+	// MeterUsage runs ffmpeg with "-v verbose -benchmark" so UsageStats
+	// also gets the bytes read from the camera and the CPU time of each
+	// capture, e.g. to bill cellular data per camera. Output sizes are
+	// always counted.
+	MeterUsage bool
+
+	// Sidecar writes a SnapshotMeta JSON file next to every saved image
+	// ("<image>.json"), carrying the correlation ID of its log entries.
+	Sidecar bool
+
+	// Profiles are named capture settings, e.g. "day" and "night", that
+	// ProfileSelector switches between; see CaptureProfile.
+	Profiles map[string]CaptureProfile
+
+	// ProfileSelector picks the profile of each capture, e.g. a SunSelector
+	// or TimeWindows. nil → the settings above always apply.
+	ProfileSelector ProfileSelector
+
+	// Retry retries single-frame captures that failed transiently, e.g. on
+	// flaky Wi-Fi cameras; see RetryPolicy. Zero → no retry.
+	Retry RetryPolicy
+
+	// Context is the long-lived parent of every capture of this camera,
+	// e.g. the service's shutdown context; cancelling it stops them all.
+	// Do not hand in a per-capture context: each capture derives its own
+	// child with Timeout, so cancelling one never affects another camera
+	// or a concurrent capture sharing the parent.
+	// nil → context.Background().
+	Context context.Context
 }
@@ -0,0 +1,300 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+)
+
+// EventType names a capture lifecycle event. Only EventCaptureFailing and
+// EventCaptureRecovered are emitted: this package has no frozen-frame or
+// tamper detection to raise other events from.
+type EventType string
+
+const (
+	// EventCaptureFailing is sent once when NotifyAfterFailures consecutive
+	// ffmpeg runs have failed.
+	EventCaptureFailing EventType = "capture_failing"
+	// EventCaptureRecovered is sent on the first success after
+	// EventCaptureFailing.
+	EventCaptureRecovered EventType = "capture_recovered"
+)
+
+const (
+	defaultNotifyAfterFailures = 3
+	notifyTimeout              = 30 * time.Second
+)
+
+// CaptureEvent is what a Notifier receives, and the JSON body WebhookNotifier
+// POSTs.
+type CaptureEvent struct {
+	Type          EventType `json:"type"`
+	CameraID      string    `json:"camera_id"`
+	Time          time.Time `json:"time"`
+	Failures      int       `json:"failures"`        // consecutive failed runs
+	Error         string    `json:"error,omitempty"` // category of the last error, see ErrorCategory
+	CorrelationID string    `json:"correlation_id,omitempty"`
+}
+
+// Notifier delivers capture events, e.g. to an alerting system. Errors are
+// logged by the SnapshotService and never change a capture's result.
+type Notifier interface {
+	Notify(ctx context.Context, event CaptureEvent) error
+}
+
+// =============================
+// SnapshotService hooks
+// =============================
+
+// recordRun updates the consecutive failure count after an ffmpeg run and
+// sends EventCaptureFailing / EventCaptureRecovered when the streak crosses
+// NotifyAfterFailures.
+func (s *SnapshotService) recordRun(ctx context.Context, runErr error) {
+	threshold := int64(s.NotifyAfterFailures)
+	if threshold <= 0 {
+		threshold = defaultNotifyAfterFailures
+	}
+
+	if runErr == nil {
+		if s.failures.Swap(0) >= threshold {
+			s.notify(ctx, CaptureEvent{Type: EventCaptureRecovered})
+		}
+		return
+	}
+	if n := s.failures.Add(1); n == threshold {
+		s.notify(ctx, CaptureEvent{Type: EventCaptureFailing, Failures: int(n), Error: ErrorCategory(runErr)})
+	}
+}
+
+// Error categories of CaptureEvent.Error.
+const (
+	ErrorUnreachable = "unreachable"
+	ErrorAuthFailed  = "auth_failed"
+	ErrorTimeout     = "timeout"
+	ErrorFFmpeg      = "ffmpeg" // any other failed run
+)
+
+// ErrorCategory classifies a capture error for events leaving the host:
+// unlike the error text, it never carries ffmpeg's output, which may name
+// the camera or its credentials.
+func ErrorCategory(err error) string {
+	var ffErr *FFmpegError
+	if !errors.As(err, &ffErr) {
+		return ErrorFFmpeg
+	}
+	switch {
+	case ffErr.Timeout:
+		return ErrorTimeout
+	case ffErr.AuthFailed():
+		return ErrorAuthFailed
+	case ffErr.Unreachable():
+		return ErrorUnreachable
+	}
+	return ErrorFFmpeg
+}
+
+// notify sends event on its own goroutine so a slow or failing Notifier
+// never delays the capture.
+func (s *SnapshotService) notify(ctx context.Context, event CaptureEvent) {
+	if s.Notifier == nil {
+		return
+	}
+	event.CameraID = s.RtspCamera.ID
+	event.Time = time.Now()
+	event.CorrelationID = astrolog.CorrelationID(ctx)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+		if err := s.Notifier.Notify(ctx, event); err != nil {
+			logger := astrolog.FromContext(ctx)
+			logger.Warn().
+				Str("camera_id", event.CameraID).
+				Str("event", string(event.Type)).
+				Err(err).
+				Msg("capture notification failed")
+		}
+	}()
+}
+
+// =============================
+// Webhook Notifier
+// =============================
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body, "sha256=<hex>",
+// when WebhookNotifier.Secret is set.
+const SignatureHeader = "X-Astro-Signature"
+
+const (
+	defaultNotifyRetries     = 3
+	defaultNotifyBackoff     = 500 * time.Millisecond
+	defaultNotifyMinInterval = time.Minute
+)
+
+// maxRetryBackoff caps the exponential delay between two retries, unless
+// the first delay is already longer.
+const maxRetryBackoff = 30 * time.Second
+
+// expBackoff returns base doubled attempt times, capped at maxRetryBackoff
+// (or base when larger).
+func expBackoff(base time.Duration, attempt int) time.Duration {
+	limit := max(base, maxRetryBackoff)
+	d := base
+	for i := 0; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// WebhookNotifier POSTs each CaptureEvent as JSON to URL. Failed deliveries
+// (network errors, 429 and 5xx) are retried with exponential backoff. Events
+// of a type already sent less than MinInterval ago are suppressed.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Secret  []byte      // HMAC key for SignatureHeader; nil → unsigned
+	Events  []EventType // types to send; empty → all
+
+	MaxRetries  int           // 0 → 3
+	Backoff     time.Duration // first retry delay, doubled each time up to 30s; 0 → 500ms
+	MinInterval time.Duration // per event type; 0 → 1 minute, < 0 → no limit
+	Client      *http.Client  // nil → http.DefaultClient
+
+	mu         sync.Mutex
+	lastSent   map[EventType]time.Time
+	sent       atomic.Uint64
+	failed     atomic.Uint64
+	suppressed atomic.Uint64
+}
+
+// NotifierStats counts WebhookNotifier outcomes.
+type NotifierStats struct {
+	Sent       uint64
+	Failed     uint64 // gave up after every retry
+	Suppressed uint64 // filtered out by Events or MinInterval
+}
+
+// Stats returns the delivery counters.
+func (n *WebhookNotifier) Stats() NotifierStats {
+	return NotifierStats{
+		Sent:       n.sent.Load(),
+		Failed:     n.failed.Load(),
+		Suppressed: n.suppressed.Load(),
+	}
+}
+
+// Notify sends event unless it is filtered out or rate limited, in which
+// case it returns nil without sending.
+func (n *WebhookNotifier) Notify(ctx context.Context, event CaptureEvent) error {
+	if (len(n.Events) > 0 && !slices.Contains(n.Events, event.Type)) || !n.allow(event.Type) {
+		n.suppressed.Add(1)
+		return nil
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	retries := n.MaxRetries
+	if retries <= 0 {
+		retries = defaultNotifyRetries
+	}
+	backoff := n.Backoff
+	if backoff <= 0 {
+		backoff = defaultNotifyBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = n.post(ctx, body)
+		if err == nil {
+			n.sent.Add(1)
+			return nil
+		}
+		if attempt == retries || errors.Is(err, errNotRetryable) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			n.failed.Add(1)
+			return ctx.Err()
+		case <-time.After(expBackoff(backoff, attempt)):
+		}
+	}
+	n.failed.Add(1)
+	return err
+}
+
+// allow reports whether an event of type t may be sent now, and records it.
+func (n *WebhookNotifier) allow(t EventType) bool {
+	interval := n.MinInterval
+	if interval == 0 {
+		interval = defaultNotifyMinInterval
+	}
+	if interval < 0 {
+		return true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	if last, ok := n.lastSent[t]; ok && now.Sub(last) < interval {
+		return false
+	}
+	if n.lastSent == nil {
+		n.lastSent = make(map[EventType]time.Time)
+	}
+	n.lastSent[t] = now
+	return true
+}
+
+// errNotRetryable marks responses that will not succeed on retry (4xx).
+var errNotRetryable = errors.New("not retryable")
+
+func (n *WebhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNotRetryable, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	if n.Secret != nil {
+		mac := hmac.New(sha256.New, n.Secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("notification webhook returned %s", resp.Status)
+	default:
+		return fmt.Errorf("%w: notification webhook returned %s", errNotRetryable, resp.Status)
+	}
+}
@@ -0,0 +1,136 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNoVideoStream is returned when ffprobe finds no video stream.
+var ErrNoVideoStream = errors.New("no video stream found")
+
+// StreamInfo describes the first video stream of the camera.
+type StreamInfo struct {
+	Width  int
+	Height int
+	Codec  string  // e.g. "h264", "hevc"
+	FPS    float64 // average frame rate, 0 when unknown
+	PixFmt string  // e.g. "yuvj420p"
+}
+
+// ProbeStream returns the stream metadata, running ffprobe under the camera
+// timeout on the first call only. Probing takes about a second, so the result
+// is cached on the service; use RefreshStreamInfo after the camera settings
+// change.
+func (s *SnapshotService) ProbeStream(ctx context.Context) (*StreamInfo, error) {
+	s.probeMu.Lock()
+	defer s.probeMu.Unlock()
+
+	if s.streamInfo != nil {
+		info := *s.streamInfo
+		return &info, nil
+	}
+	return s.probeLocked(ctx)
+}
+
+// RefreshStreamInfo probes the stream again and replaces the cached result.
+func (s *SnapshotService) RefreshStreamInfo(ctx context.Context) (*StreamInfo, error) {
+	s.probeMu.Lock()
+	defer s.probeMu.Unlock()
+
+	return s.probeLocked(ctx)
+}
+
+// cachedStreamInfo returns the cached StreamInfo without probing, or nil.
+func (s *SnapshotService) cachedStreamInfo() *StreamInfo {
+	s.probeMu.Lock()
+	defer s.probeMu.Unlock()
+
+	if s.streamInfo == nil {
+		return nil
+	}
+	info := *s.streamInfo
+	return &info
+}
+
+func (s *SnapshotService) probeLocked(ctx context.Context) (*StreamInfo, error) {
+	args := []string{
+		"-v", "error",
+		"-rtsp_transport", "tcp",
+		"-select_streams", "v:0",
+		"-show_streams",
+		"-print_format", "json",
+		s.RtspCamera.RTSPUrl,
+	}
+
+	var out bytes.Buffer
+	if err := s.runWith(ctx, s.prober(), "ffprobe", s.RtspCamera.Timeout, args, &out); err != nil {
+		return nil, err
+	}
+
+	info, err := parseProbeOutput(out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	s.streamInfo = info
+
+	copied := *info
+	return &copied, nil
+}
+
+// parseProbeOutput reads the first video stream of `ffprobe -print_format json`.
+func parseProbeOutput(data []byte) (*StreamInfo, error) {
+	var probe struct {
+		Streams []struct {
+			CodecType    string `json:"codec_type"`
+			CodecName    string `json:"codec_name"`
+			Width        int    `json:"width"`
+			Height       int    `json:"height"`
+			PixFmt       string `json:"pix_fmt"`
+			AvgFrameRate string `json:"avg_frame_rate"`
+			RFrameRate   string `json:"r_frame_rate"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+
+	for _, st := range probe.Streams {
+		if st.CodecType != "" && st.CodecType != "video" {
+			continue
+		}
+		fps := parseFrameRate(st.AvgFrameRate)
+		if fps == 0 {
+			fps = parseFrameRate(st.RFrameRate)
+		}
+		return &StreamInfo{
+			Width:  st.Width,
+			Height: st.Height,
+			Codec:  st.CodecName,
+			FPS:    fps,
+			PixFmt: st.PixFmt,
+		}, nil
+	}
+	return nil, ErrNoVideoStream
+}
+
+// parseFrameRate turns ffprobe's "num/den" into frames per second, 0 when
+// unknown ("0/0").
+func parseFrameRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		f, _ := strconv.ParseFloat(rate, 64)
+		return f
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
@@ -0,0 +1,64 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Runner executes ffmpeg with the given arguments. SnapshotService uses
+// ExecRunner unless its Runner field is set, which lets tests swap in a fake
+// (see the astrortsptest package).
+type Runner interface {
+	Run(ctx context.Context, args []string, stdout, stderr io.Writer) error
+}
+
+// ErrFFmpegNotFound is returned when the ffmpeg binary is not in PATH.
+var ErrFFmpegNotFound = errors.New("ffmpeg binary not found in PATH: install ffmpeg (e.g. `apt install ffmpeg` or `brew install ffmpeg`)")
+
+// ErrFFprobeNotFound is returned when the ffprobe binary is not in PATH.
+// ffprobe ships with ffmpeg.
+var ErrFFprobeNotFound = errors.New("ffprobe binary not found in PATH: install ffmpeg, which provides it")
+
+// ExecRunner runs a binary found in PATH.
+type ExecRunner struct {
+	Binary string // "" → "ffmpeg"
+}
+
+func (e ExecRunner) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	binary := e.Binary
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		notFound := ErrFFmpegNotFound
+		if binary == "ffprobe" {
+			notFound = ErrFFprobeNotFound
+		}
+		return fmt.Errorf("%w: %v", notFound, err)
+	}
+	return err
+}
+
+// runner returns the configured Runner, defaulting to ExecRunner.
+func (s *SnapshotService) runner() Runner {
+	if s.Runner != nil {
+		return s.Runner
+	}
+	return ExecRunner{}
+}
+
+// prober returns the configured Prober, defaulting to ffprobe.
+func (s *SnapshotService) prober() Runner {
+	if s.Prober != nil {
+		return s.Prober
+	}
+	return ExecRunner{Binary: "ffprobe"}
+}
@@ -0,0 +1,75 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrolog"
+)
+
+// SnapshotMeta describes one saved capture. With RtspConfig.Sidecar set it
+// is written next to the image, see SidecarPath.
+type SnapshotMeta struct {
+	CameraID   string    `json:"camera_id"`
+	Image      string    `json:"image"` // base name of the image file
+	CapturedAt time.Time `json:"captured_at"`
+	Filter     string    `json:"filter,omitempty"` // -vf / -filter_complex of the capture
+	Attempts   int       `json:"attempts"`         // ffmpeg runs, retries included
+
+	// Profile is the CaptureProfile the image was captured with, "" for
+	// the base RtspConfig settings.
+	Profile string `json:"profile,omitempty"`
+
+	// CorrelationID is carried by every log entry of the capture, its
+	// retries included, so the image can be traced back to them.
+	CorrelationID string `json:"correlation_id"`
+}
+
+// SidecarPath returns where the SnapshotMeta of image is written: the image
+// path with ".json" appended.
+func SidecarPath(image string) string {
+	return image + ".json"
+}
+
+// ReadSnapshotMeta loads the sidecar of image.
+func ReadSnapshotMeta(image string) (*SnapshotMeta, error) {
+	data, err := os.ReadFile(SidecarPath(image))
+	if err != nil {
+		return nil, err
+	}
+	var meta SnapshotMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("sidecar %s: %w", SidecarPath(image), err)
+	}
+	return &meta, nil
+}
+
+// writeSidecar writes the SnapshotMeta of image, captured under ctx with
+// the named profile, when RtspConfig.Sidecar is set.
+func (s *SnapshotService) writeSidecar(ctx context.Context, image, filter, profile string, attempts int) error {
+	if !s.RtspCamera.Sidecar {
+		return nil
+	}
+	meta := SnapshotMeta{
+		CameraID:      s.RtspCamera.ID,
+		Image:         filepath.Base(image),
+		CapturedAt:    time.Now(),
+		Filter:        filter,
+		Attempts:      attempts,
+		Profile:       profile,
+		CorrelationID: astrolog.CorrelationID(ctx),
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(SidecarPath(image), data, 0644); err != nil {
+		return fmt.Errorf("writing sidecar: %w", err)
+	}
+	return nil
+}
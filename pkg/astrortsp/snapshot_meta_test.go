@@ -0,0 +1,128 @@
+package astrortsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp"
+	"github.com/Asteroidea-tn/asterogo/pkg/astrortsp/astrortsptest"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// flakyRunner fails the first n runs as unreachable, then defers to next.
+type flakyRunner struct {
+	n    int64
+	runs atomic.Int64
+	next astrortsp.Runner
+}
+
+func (f *flakyRunner) Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if f.runs.Add(1) <= f.n {
+		return (&astrortsptest.FakeRunner{Default: astrortsptest.Unreachable()}).Run(ctx, args, stdout, stderr)
+	}
+	return f.next.Run(ctx, args, stdout, stderr)
+}
+
+// captureLogs sends the global logger to a buffer for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev, prevLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&buf)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		log.Logger = prev
+		zerolog.SetGlobalLevel(prevLevel)
+	})
+	return &buf
+}
+
+func TestCaptureCorrelationIDAcrossRetries(t *testing.T) {
+	logs := captureLogs(t)
+	s, runner := astrortsptest.NewTestService(t)
+	flaky := &flakyRunner{n: 2, next: runner}
+	s.Runner = flaky
+	s.RtspCamera.Sidecar = true
+	s.RtspCamera.Retry = astrortsp.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	path, err := s.CaptureImg()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := astrortsp.ReadSnapshotMeta(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CorrelationID == "" {
+		t.Fatal("sidecar has no correlation_id")
+	}
+	if meta.Attempts != 3 || meta.CameraID != "testcam" || meta.Image != filepath.Base(path) {
+		t.Errorf("meta = %+v, want 3 attempts of testcam for %s", meta, filepath.Base(path))
+	}
+
+	entries := 0
+	retries := 0
+	sc := bufio.NewScanner(logs)
+	for sc.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			t.Fatalf("log line %q: %v", sc.Text(), err)
+		}
+		entries++
+		if entry["message"] == "ffmpeg capture retrying" {
+			retries++
+		}
+		if id := entry["correlation_id"]; id != meta.CorrelationID {
+			t.Errorf("entry %q has correlation_id %v, want %s", entry["message"], id, meta.CorrelationID)
+		}
+	}
+	if retries != 2 || entries < 3*2 {
+		t.Errorf("got %d entries with %d retries, want every run and both retries logged", entries, retries)
+	}
+}
+
+func TestCaptureSplitSharesCorrelationID(t *testing.T) {
+	s, _ := astrortsptest.NewTestService(t)
+	s.RtspCamera.Sidecar = true
+
+	left, right, err := s.CaptureSplit(true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := astrortsp.ReadSnapshotMeta(left)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := astrortsp.ReadSnapshotMeta(right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both halves are one logical capture.
+	if a.CorrelationID == "" || a.CorrelationID != b.CorrelationID {
+		t.Errorf("correlation IDs %q and %q, want one shared ID", a.CorrelationID, b.CorrelationID)
+	}
+	if a.Filter != "crop=iw/2:ih:0:0" || b.Filter != "crop=iw/2:ih:iw/2:0" {
+		t.Errorf("filters %q and %q", a.Filter, b.Filter)
+	}
+}
+
+func TestNoSidecarByDefault(t *testing.T) {
+	s, _ := astrortsptest.NewTestService(t)
+
+	path, err := s.CaptureImg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := astrortsp.ReadSnapshotMeta(path); err == nil {
+		t.Error("sidecar written without RtspConfig.Sidecar")
+	}
+}
@@ -0,0 +1,219 @@
+// ================ Version : V1.1.0 ===========
+package astrortsp
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usageRetentionDays is how many daily buckets a SnapshotService keeps.
+const usageRetentionDays = 62
+
+// usageMeterArgs are prepended to ffmpeg runs when RtspConfig.MeterUsage is
+// set: verbose logging prints the bytes demuxed from the camera and
+// -benchmark the CPU time.
+var usageMeterArgs = []string{"-v", "verbose", "-benchmark"}
+
+// UsageDay is what one camera consumed during one local calendar day.
+type UsageDay struct {
+	Date     time.Time // local midnight starting the day
+	Runs     int64     // ffmpeg runs, failed ones included
+	Failures int64
+	// InputBytes is what ffmpeg reported reading from the camera, summed
+	// over the InputRuns runs that reported it (see RtspConfig.MeterUsage).
+	InputBytes int64
+	InputRuns  int64
+	// OutputBytes is the size of the files or streams written.
+	OutputBytes int64
+	// CPUTime is ffmpeg's user+system time, over the runs that reported it.
+	CPUTime time.Duration
+}
+
+// add accumulates one run into d.
+func (d *UsageDay) add(u runUsage, failed bool) {
+	d.Runs++
+	if failed {
+		d.Failures++
+	}
+	if u.inputKnown {
+		d.InputBytes += u.input
+		d.InputRuns++
+	}
+	d.OutputBytes += u.output
+	d.CPUTime += u.cpu
+}
+
+// UsageStats is returned by SnapshotService.UsageStats.
+type UsageStats struct {
+	CameraID string
+	Days     []UsageDay // oldest first; the last one is today once a run happened today
+	Total    UsageDay   // sum of Days, Date unset
+}
+
+// usageMeter holds the daily buckets of one SnapshotService.
+type usageMeter struct {
+	mu   sync.Mutex
+	days []UsageDay
+	now  func() time.Time // nil → time.Now
+}
+
+// record adds a run to the bucket of the current day, opening a new one
+// after midnight and dropping those older than usageRetentionDays.
+func (m *usageMeter) record(u runUsage, failed bool) {
+	now := time.Now
+	if m.now != nil {
+		now = m.now
+	}
+	t := now()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n := len(m.days); n == 0 || !m.days[n-1].Date.Equal(day) {
+		m.days = append(m.days, UsageDay{Date: day})
+		if len(m.days) > usageRetentionDays {
+			m.days = append([]UsageDay(nil), m.days[len(m.days)-usageRetentionDays:]...)
+		}
+	}
+	m.days[len(m.days)-1].add(u, failed)
+}
+
+func (m *usageMeter) snapshot() []UsageDay {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]UsageDay(nil), m.days...)
+}
+
+// UsageStats returns the data and CPU this camera's ffmpeg runs consumed,
+// per local day, for the last 62 days.
+func (s *SnapshotService) UsageStats() UsageStats {
+	stats := UsageStats{CameraID: s.RtspCamera.ID, Days: s.usage.snapshot()}
+	for _, d := range stats.Days {
+		stats.Total.Runs += d.Runs
+		stats.Total.Failures += d.Failures
+		stats.Total.InputBytes += d.InputBytes
+		stats.Total.InputRuns += d.InputRuns
+		stats.Total.OutputBytes += d.OutputBytes
+		stats.Total.CPUTime += d.CPUTime
+	}
+	return stats
+}
+
+// =============================
+// CSV report
+// =============================
+
+var usageReportHeader = []string{"date", "camera_id", "runs", "failures", "input_bytes", "input_runs", "output_bytes", "cpu_seconds"}
+
+// WriteUsageReport writes this camera's daily usage as CSV, one row per day
+// from the day containing since onwards.
+func (s *SnapshotService) WriteUsageReport(w io.Writer, since time.Time) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(usageReportHeader)
+	writeUsageRows(cw, s.UsageStats(), since)
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteUsageReport is SnapshotService.WriteUsageReport for every camera,
+// under a single header.
+func (m *MultiSnapshotService) WriteUsageReport(w io.Writer, since time.Time) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(usageReportHeader)
+	for _, s := range m.Services {
+		writeUsageRows(cw, s.UsageStats(), since)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeUsageRows(cw *csv.Writer, stats UsageStats, since time.Time) {
+	for _, d := range stats.Days {
+		if !d.Date.AddDate(0, 0, 1).After(since) {
+			continue
+		}
+		_ = cw.Write([]string{
+			d.Date.Format("2006-01-02"),
+			stats.CameraID,
+			strconv.FormatInt(d.Runs, 10),
+			strconv.FormatInt(d.Failures, 10),
+			strconv.FormatInt(d.InputBytes, 10),
+			strconv.FormatInt(d.InputRuns, 10),
+			strconv.FormatInt(d.OutputBytes, 10),
+			strconv.FormatFloat(d.CPUTime.Seconds(), 'f', 3, 64),
+		})
+	}
+}
+
+// =============================
+// ffmpeg output parsing
+// =============================
+
+// runUsage is what one ffmpeg run consumed.
+type runUsage struct {
+	input      int64
+	inputKnown bool
+	output     int64
+	cpu        time.Duration
+}
+
+var (
+	// "Total: 25 packets (123456 bytes) demuxed", printed per input with -v verbose.
+	demuxedRe = regexp.MustCompile(`Total:\s*\d+\s+packets\s+\((\d+)\s+bytes\)\s+demuxed`)
+	// "video:123kB audio:0kB …" (ffmpeg < 6.1 says kB, later KiB; both are 1024 bytes).
+	muxedRe = regexp.MustCompile(`\bvideo:\s*(\d+(?:\.\d+)?)\s*(KiB|kB)`)
+	// "bench: utime=0.012s stime=0.004s rtime=0.050s", printed with -benchmark.
+	benchRe = regexp.MustCompile(`bench:\s+utime=(\d+(?:\.\d+)?)s\s+stime=(\d+(?:\.\d+)?)s`)
+)
+
+// parseFFmpegUsage reads the input bytes, muxed video size and CPU time from
+// ffmpeg's stderr. Lines missing from this ffmpeg version or log level are
+// left at zero.
+func parseFFmpegUsage(stderr string) (usage runUsage, videoBytes int64) {
+	for _, m := range demuxedRe.FindAllStringSubmatch(stderr, -1) {
+		if n, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			usage.input += n
+			usage.inputKnown = true
+		}
+	}
+
+	if m := muxedRe.FindAllStringSubmatch(stderr, -1); len(m) > 0 {
+		last := m[len(m)-1]
+		size, _ := strconv.ParseFloat(last[1], 64)
+		videoBytes = int64(size * (1 << 10))
+	}
+
+	if m := benchRe.FindStringSubmatch(stderr); m != nil {
+		utime, _ := strconv.ParseFloat(m[1], 64)
+		stime, _ := strconv.ParseFloat(m[2], 64)
+		usage.cpu = time.Duration((utime + stime) * float64(time.Second))
+	}
+	return usage, videoBytes
+}
+
+// measureRun returns the usage of a finished ffmpeg run: its stderr, plus
+// the bytes written to stdout (when written is not nil) or the size of the
+// output file, the last argument. The muxed size from stderr is the
+// fallback when neither is known.
+func measureRun(args []string, stderr string, written *countingWriter) runUsage {
+	usage, videoBytes := parseFFmpegUsage(stderr)
+	switch {
+	case written != nil:
+		usage.output = written.n
+	case len(args) > 0 && !strings.HasPrefix(args[len(args)-1], "pipe:") && args[len(args)-1] != "-":
+		if fi, err := os.Stat(args[len(args)-1]); err == nil && fi.Mode().IsRegular() {
+			usage.output = fi.Size()
+		} else {
+			usage.output = videoBytes
+		}
+	default:
+		usage.output = videoBytes
+	}
+	return usage
+}
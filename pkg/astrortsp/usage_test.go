@@ -0,0 +1,155 @@
+package astrortsp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFFmpegUsage(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		stderr     string
+		want       runUsage
+		videoBytes int64
+	}{
+		{
+			name: "ffmpeg 4 verbose",
+			stderr: "Input #0, rtsp, from 'rtsp://cam.local/stream1':\n" +
+				"frame=    1 fps=0.0 q=2.0 Lsize=N/A time=00:00:00.04 bitrate=N/A speed=0.1x\n" +
+				"video:401kB audio:0kB subtitle:0kB other streams:0kB global headers:0kB muxing overhead: unknown\n" +
+				"Input file #0 (rtsp://cam.local/stream1):\n" +
+				"  Input stream #0:0 (video): 25 packets read (123456 bytes); 1 frames decoded;\n" +
+				"  Total: 25 packets (123456 bytes) demuxed\n" +
+				"bench: utime=0.120s stime=0.030s rtime=1.000s\n",
+			want:       runUsage{input: 123456, inputKnown: true, cpu: 150 * time.Millisecond},
+			videoBytes: 401 << 10,
+		},
+		{
+			name: "ffmpeg 6.1 verbose",
+			stderr: "[in#0/rtsp @ 0x5581] Input file #0 (rtsp://cam.local/stream1):\n" +
+				"[in#0/rtsp @ 0x5581]   Total: 48 packets (250000 bytes) demuxed\n" +
+				"[out#0/image2 @ 0x5582] video:12.5KiB audio:0KiB subtitle:0KiB other streams:0KiB global headers:0KiB muxing overhead: unknown\n" +
+				"bench: utime=1.5s stime=0.5s rtime=3.0s\n",
+			want:       runUsage{input: 250000, inputKnown: true, cpu: 2 * time.Second},
+			videoBytes: 12800,
+		},
+		{
+			name: "two inputs",
+			stderr: "  Total: 10 packets (1000 bytes) demuxed\n" +
+				"  Total: 5 packets (500 bytes) demuxed\n",
+			want: runUsage{input: 1500, inputKnown: true},
+		},
+		{
+			name: "default log level",
+			stderr: "frame=    1 fps=0.0 q=2.0 size=401kB time=00:00:00.04\n" +
+				"video:30kB audio:0kB subtitle:0kB other streams:0kB global headers:0kB muxing overhead: unknown\n",
+			videoBytes: 30 << 10,
+		},
+		{
+			name: "no summary",
+			stderr: "[rtsp @ 0x5581] method DESCRIBE failed: 404 Not Found\n" +
+				"rtsp://cam.local/stream1: Server returned 404 Not Found\n",
+		},
+		{name: "empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, videoBytes := parseFFmpegUsage(tc.stderr)
+			if got != tc.want || videoBytes != tc.videoBytes {
+				t.Errorf("got %+v, video %d; want %+v, video %d", got, videoBytes, tc.want, tc.videoBytes)
+			}
+		})
+	}
+}
+
+func TestMeasureRunOutput(t *testing.T) {
+	stderr := "video:4kB audio:0kB subtitle:0kB other streams:0kB global headers:0kB muxing overhead: unknown\n"
+
+	out := filepath.Join(t.TempDir(), "snap.jpg")
+	if err := os.WriteFile(out, make([]byte, 1234), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if u := measureRun([]string{"-i", "rtsp://cam", out}, stderr, nil); u.output != 1234 {
+		t.Errorf("file output = %d, want its size", u.output)
+	}
+
+	w := &countingWriter{w: &bytes.Buffer{}}
+	w.Write(make([]byte, 77))
+	if u := measureRun([]string{"-i", "rtsp://cam", "pipe:1"}, stderr, w); u.output != 77 {
+		t.Errorf("stream output = %d, want the bytes written", u.output)
+	}
+
+	// Neither known: the muxed size from stderr.
+	for _, last := range []string{"pipe:1", "-", filepath.Join(t.TempDir(), "missing.jpg")} {
+		if u := measureRun([]string{"-i", "rtsp://cam", last}, stderr, nil); u.output != 4<<10 {
+			t.Errorf("%s: output = %d, want the muxed size", last, u.output)
+		}
+	}
+}
+
+// fakeClock is a settable usageMeter clock.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestUsageMeterRollsOverAtMidnight(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	clock := &fakeClock{t: time.Date(2024, 3, 9, 23, 59, 58, 0, loc)}
+	s := &SnapshotService{RtspCamera: RtspConfig{ID: "gate"}}
+	s.usage.now = clock.now
+
+	run := runUsage{input: 1000, inputKnown: true, output: 100, cpu: time.Second}
+	s.usage.record(run, false)
+	s.usage.record(runUsage{output: 50}, true) // no input reported
+	clock.t = clock.t.Add(2 * time.Second)     // 00:00:00 the next day
+	s.usage.record(run, false)
+
+	stats := s.UsageStats()
+	if len(stats.Days) != 2 {
+		t.Fatalf("days = %+v, want 2", stats.Days)
+	}
+	first, second := stats.Days[0], stats.Days[1]
+	if !first.Date.Equal(time.Date(2024, 3, 9, 0, 0, 0, 0, loc)) || !second.Date.Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, loc)) {
+		t.Errorf("dates = %v, %v; want local midnights", first.Date, second.Date)
+	}
+	want := UsageDay{Date: first.Date, Runs: 2, Failures: 1, InputBytes: 1000, InputRuns: 1, OutputBytes: 150, CPUTime: time.Second}
+	if first != want {
+		t.Errorf("day 1 = %+v, want %+v", first, want)
+	}
+	want = UsageDay{Date: second.Date, Runs: 1, InputBytes: 1000, InputRuns: 1, OutputBytes: 100, CPUTime: time.Second}
+	if second != want {
+		t.Errorf("day 2 = %+v, want %+v", second, want)
+	}
+	if stats.CameraID != "gate" || stats.Total.Runs != 3 || stats.Total.InputBytes != 2000 || stats.Total.OutputBytes != 250 {
+		t.Errorf("total = %+v", stats.Total)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteUsageReport(&buf, time.Date(2024, 3, 10, 12, 0, 0, 0, loc)); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := strings.Join(usageReportHeader, ",") + "\n" +
+		"2024-03-10,gate,1,0,1000,1,100,1.000\n"
+	if buf.String() != wantCSV {
+		t.Errorf("report since the 10th:\n%s\nwant:\n%s", buf.String(), wantCSV)
+	}
+}
+
+func TestUsageMeterRetention(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	m := &usageMeter{now: clock.now}
+	for i := 0; i < usageRetentionDays+8; i++ {
+		m.record(runUsage{}, false)
+		clock.t = clock.t.AddDate(0, 0, 1)
+	}
+	days := m.snapshot()
+	if len(days) != usageRetentionDays {
+		t.Fatalf("%d days kept, want %d", len(days), usageRetentionDays)
+	}
+	if want := time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC); !days[0].Date.Equal(want) {
+		t.Errorf("oldest day = %v, want %v", days[0].Date, want)
+	}
+}